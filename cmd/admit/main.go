@@ -2,8 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/user"
 	"path/filepath"
 	"strings"
 	"time"
@@ -11,13 +14,16 @@ import (
 	"admit/internal/artifact"
 	"admit/internal/baseline"
 	"admit/internal/cli"
+	"admit/internal/codes"
 	"admit/internal/contract"
 	"admit/internal/drift"
 	"admit/internal/execid"
+	"admit/internal/fixture"
 	"admit/internal/identity"
 	"admit/internal/injector"
 	"admit/internal/invariant"
 	"admit/internal/launcher"
+	"admit/internal/probe"
 	"admit/internal/resolver"
 	"admit/internal/schema"
 	"admit/internal/snapshot"
@@ -54,29 +60,111 @@ func run(args []string, environ []string, defaultSchemaDir string) int {
 		return runBaseline(cmd, environ)
 	}
 
+	// Handle v8 encrypt subcommand
+	if cmd.Subcommand == cli.SubcommandEncrypt {
+		return runEncrypt(cmd, environ)
+	}
+
+	// Handle v9 explain-code subcommand
+	if cmd.Subcommand == cli.SubcommandExplainCode {
+		return runExplainCode(cmd)
+	}
+
+	// Load --env-file entries and merge them into the environment (v8 feature)
+	// so env files can select the schema and environment (ADMIT_SCHEMA, ADMIT_ENV)
+	processEnviron := environ
+	envFileEntries, err := loadEnvFiles(cmd.EnvFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot load env file: %v\n", err)
+		return 1
+	}
+	environ = resolver.MergeEnviron(environ, envFileEntries, cmd.EnvFileOverride)
+
 	// Resolve schema path
 	schemaPath := resolveSchemaPath(cmd.SchemaPath, environ, defaultSchemaDir)
 
+	// check --json also reports failures on stdout, with their error codes (v9 feature)
+	checkJSON := cmd.Subcommand == cli.SubcommandCheck && cmd.JSONOutput
+
 	// Load schema
 	s, err := schema.LoadSchemaFromPath(schemaPath)
 	if err != nil {
+		message := fmt.Sprintf("failed to parse schema: %v", err)
 		if os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "schema file not found: %s\n", schemaPath)
-			return 3
+			message = fmt.Sprintf("schema file not found: %s", schemaPath)
+		}
+		fmt.Fprintln(os.Stderr, message)
+		if checkJSON {
+			fmt.Println(formatCheckReport(checkReport{
+				schemaError: &checkSchemaError{Code: schema.ErrorCode(err), Message: message},
+				schemaPath:  schemaPath,
+			}))
 		}
-		fmt.Fprintf(os.Stderr, "failed to parse schema: %v\n", err)
 		return 3
 	}
 
-	// Resolve config from environment
-	resolved := resolver.Resolve(s, environ)
+	// With --warnings-as-errors, "warn" rules block like errors (v9 feature)
+	if cmd.WarningsAsErrors {
+		s = s.WarningsAsErrors()
+	}
+
+	// Handle v9 invariants subcommand (analysis needs only the schema)
+	if cmd.Subcommand == cli.SubcommandInvariants {
+		return runInvariants(cmd, s)
+	}
+
+	// Handle v9 test subcommand (each fixture supplies its own environment)
+	if cmd.Subcommand == cli.SubcommandTest {
+		return runTest(cmd, s, schemaPath, processEnviron)
+	}
 
-	// Validate config
-	result := validator.Validate(s, resolved)
+	// Load config sources from admit.yaml and flags (v8 feature)
+	// The merged environment is passed to the child process; the resolver
+	// consults the process environment and the sources separately to track provenance
+	resolveOpts, err := sourceOptions(cmd, s, schemaPath, environ, envFileEntries)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	environ = resolver.MergeEnviron(processEnviron, resolveOpts.EnvFile, resolveOpts.EnvFileWins())
+
+	// Handle v8 explain subcommand
+	if cmd.Subcommand == cli.SubcommandExplain {
+		return runExplain(cmd, s, processEnviron, resolveOpts)
+	}
+
+	// Resolve, validate and evaluate invariants and the environment contract;
+	// each stage runs only if the one before it passed
+	ev := evaluate(cmd, s, schemaPath, processEnviron, environ, resolveOpts, currentHost(environ))
+	if ev.resolveErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot resolve config: %v\n", ev.resolveErr)
+		if checkJSON {
+			fmt.Println(formatCheckReport(checkReport{
+				resolveErrors: checkResolveErrors(ev.resolveErr),
+				schemaPath:    schemaPath,
+			}))
+		}
+		return ev.exitCode
+	}
+	environ = ev.environ
+	resolved := ev.resolved
+	sensitive := ev.sensitive
+	result := ev.validation
 
 	// Check CI mode
 	ciMode := cmd.CIMode || getEnvBool(environ, "ADMIT_CI") || getEnvBool(environ, "CI")
 
+	// Failures of "warn" rules are printed and collected for check --json (v9 feature)
+	var warnings []checkWarning
+	for _, verr := range result.Warnings {
+		if ciMode {
+			fmt.Fprintln(os.Stderr, formatCIAnnotation(verr))
+		} else {
+			fmt.Fprintln(os.Stderr, "Warning: "+validator.FormatError(verr))
+		}
+		warnings = append(warnings, checkWarning{Type: "validation", Code: verr.Code, Key: verr.Key, Message: validator.FormatError(verr)})
+	}
+
 	// If invalid: print errors to stderr, exit non-zero
 	// No artifacts are produced when validation fails
 	if !result.Valid {
@@ -90,30 +178,21 @@ func run(args []string, environ []string, defaultSchemaDir string) int {
 				fmt.Fprintln(os.Stderr, validator.FormatError(verr))
 			}
 		}
+		if checkJSON {
+			fmt.Println(formatCheckReport(checkReport{validationErrors: result.Errors, warnings: warnings, resolved: resolved, schemaPath: schemaPath}))
+		}
 		return 1
 	}
 
-	// Evaluate invariants (v2 feature)
+	// Report invariants (v2 feature)
 	// Skip if no invariants defined (backward compatibility)
-	var invResults []invariant.InvariantResult
+	invResults := ev.invResults
 	if len(s.Invariants) > 0 {
-		// Build config values map from resolved values
-		configValues := make(map[string]string)
-		for _, rv := range resolved {
-			if rv.Present {
-				configValues[rv.Key] = rv.Value
-			}
+		// Handle --explain flag (v9): evaluation trees go in the JSON output if requested
+		if cmd.ExplainInvariants && !cmd.InvariantsJSON && !checkJSON {
+			fmt.Fprint(os.Stderr, invariant.FormatTraces(invResults))
 		}
 
-		// Build evaluation context
-		evalCtx := invariant.EvalContext{
-			ConfigValues: configValues,
-			ExecutionEnv: getAdmitEnv(environ),
-		}
-
-		// Evaluate all invariants
-		invResults = invariant.EvaluateAll(s.Invariants, evalCtx)
-
 		// Handle --invariants-json flag
 		if cmd.InvariantsJSON {
 			jsonOutput, err := invariant.FormatJSON(invResults)
@@ -124,53 +203,56 @@ func run(args []string, environ []string, defaultSchemaDir string) int {
 			fmt.Println(jsonOutput)
 		}
 
+		// Report warnings (unless JSON mode already printed them)
+		invWarnings := invariant.GetWarnings(invResults)
+		if !cmd.InvariantsJSON {
+			if ciMode {
+				for _, inv := range invWarnings {
+					fmt.Fprintf(os.Stderr, "::warning file=admit.yaml::INVARIANT WARNING: '%s' - %s\n", inv.Name, inv.Message)
+				}
+			} else {
+				fmt.Fprint(os.Stderr, invariant.FormatWarnings(invResults))
+			}
+		}
+		for _, inv := range invWarnings {
+			warnings = append(warnings, checkWarning{Type: "invariant", Code: inv.Code, Name: inv.Name, Message: inv.Message})
+		}
+
 		// Check for violations
 		if invariant.HasViolations(invResults) {
 			// Report all violations to stderr (unless JSON mode already printed)
 			if !cmd.InvariantsJSON {
 				if ciMode {
-					for _, inv := range invResults {
-						if !inv.Passed {
-							fmt.Fprintf(os.Stderr, "::error file=admit.yaml::INVARIANT VIOLATION: '%s' - %s\n", inv.Name, inv.Message)
-						}
-					}
 					violations := invariant.GetViolations(invResults)
+					for _, inv := range violations {
+						fmt.Fprintf(os.Stderr, "::error file=admit.yaml::INVARIANT VIOLATION: '%s' - %s\n", inv.Name, inv.Message)
+					}
 					fmt.Fprintf(os.Stderr, "\n❌ Invariant check failed: %d violation(s)\n", len(violations))
 				} else {
 					fmt.Fprint(os.Stderr, invariant.FormatViolations(invResults))
 				}
 			}
+			if checkJSON {
+				fmt.Println(formatCheckReport(checkReport{invariantResults: invResults, warnings: warnings, resolved: resolved, schemaPath: schemaPath}))
+			}
 			// Exit with code 2 for invariant violations
 			return 2
 		}
 	}
 
-	// Evaluate environment contracts (v7 feature)
-	// Skip if no environment specified (--env flag or ADMIT_ENV)
-	// Skip if no environments section in schema
-	envName := resolveEnvironment(cmd.Env, environ)
-	if envName != "" && len(s.Environments) > 0 {
-		// Check if the specified environment exists
-		envContract, exists := s.Environments[envName]
-		if !exists {
-			fmt.Fprintf(os.Stderr, "Error: unknown environment '%s'\n", envName)
-			return 1
-		}
-
-		// Build config values map from resolved values
-		configValues := make(map[string]string)
-		for _, rv := range resolved {
-			if rv.Present {
-				configValues[rv.Key] = rv.Value
-			}
-		}
-
-		// Evaluate contract
-		contractResult := contract.Evaluate(envContract, configValues)
+	// Report environment contracts (v7 feature)
+	// Skipped if no environment is specified (--env flag or ADMIT_ENV)
+	// or the schema has no environments section
+	if ev.contractErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", ev.contractErr)
+		return 1
+	}
+	if contractResult := ev.contract; contractResult != nil {
+		envName := ev.envName
 
 		// Handle --contract-json flag
 		if cmd.ContractJSON {
-			jsonOutput, err := contract.FormatJSON(contractResult)
+			jsonOutput, err := contract.FormatJSON(*contractResult)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: cannot format contract results: %v\n", err)
 				return 1
@@ -178,27 +260,112 @@ func run(args []string, environ []string, defaultSchemaDir string) int {
 			fmt.Println(jsonOutput)
 		}
 
+		// Report warnings (unless JSON mode already printed them)
+		if !cmd.ContractJSON {
+			if ciMode {
+				fmt.Fprint(os.Stderr, contract.FormatCIWarnings(*contractResult))
+			} else {
+				fmt.Fprint(os.Stderr, contract.FormatCLIWarnings(*contractResult))
+			}
+		}
+		for _, v := range contractResult.Warnings {
+			warnings = append(warnings, checkWarning{Type: "contract", Code: v.Code, Key: v.Key, Environment: envName, Message: contract.FormatMessage(v)})
+		}
+
 		// Check for violations
 		if !contractResult.Passed {
 			// Report all violations to stderr (unless JSON mode already printed)
 			if !cmd.ContractJSON {
 				if ciMode {
-					fmt.Fprint(os.Stderr, contract.FormatCI(contractResult))
+					fmt.Fprint(os.Stderr, contract.FormatCI(*contractResult))
 				} else {
-					fmt.Fprint(os.Stderr, contract.FormatCLI(contractResult))
+					fmt.Fprint(os.Stderr, contract.FormatCLI(*contractResult))
+				}
+			}
+			if checkJSON {
+				violations := make([]checkContractViolation, 0, len(contractResult.Violations))
+				for _, v := range contractResult.Violations {
+					violations = append(violations, checkContractViolation{
+						Code:        v.Code,
+						Key:         v.Key,
+						Environment: envName,
+						Rule:        v.RuleType,
+						Value:       v.ActualValue,
+						Message:     contract.FormatMessage(v),
+					})
 				}
+				fmt.Println(formatCheckReport(checkReport{invariantResults: invResults, contractViolations: violations, warnings: warnings, resolved: resolved, schemaPath: schemaPath}))
 			}
 			// Exit with code 5 for contract violations - do NOT execute command
 			return 5
 		}
 	}
 
+	// Wait for --wait-for and --wait-for-key targets (v9 feature)
+	// Waiting comes before probes, so probes see dependencies that came up meanwhile
+	var waitResults []probe.WaitResult
+	if len(cmd.WaitFor) > 0 || len(cmd.WaitForKeys) > 0 {
+		targets, err := waitTargets(cmd, resolved)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		waitTimeout := cmd.WaitTimeout
+		if waitTimeout <= 0 {
+			waitTimeout = probe.DefaultWaitTimeout
+		}
+		waitResults = probe.Wait(targets, probe.WaitOptions{Timeout: waitTimeout})
+		if !probe.WaitReady(waitResults) {
+			if ciMode {
+				fmt.Fprint(os.Stderr, probe.FormatWaitCI(waitResults, waitTimeout))
+			} else {
+				fmt.Fprint(os.Stderr, probe.FormatWaitTimeout(waitResults, waitTimeout))
+			}
+			if checkJSON {
+				fmt.Println(formatCheckReport(checkReport{invariantResults: invResults, waitResults: waitResults, warnings: warnings, resolved: resolved, schemaPath: schemaPath}))
+			}
+			// Exit with code 8 when a wait target is not ready in time - do NOT execute command
+			return 8
+		}
+	}
+
+	// Probe the dependencies config values point at before the command runs (v9 feature)
+	// Probes run last, so they never run for config that is already rejected;
+	// check and --dry-run only dial them with --probe
+	var probeResults []probe.Result
+	if cmd.Probe || (cmd.Subcommand != cli.SubcommandCheck && !cmd.DryRun) {
+		probeResults = probe.Run(s, resolved, probe.Options{})
+	}
+	if len(probeResults) > 0 {
+		if ciMode {
+			fmt.Fprint(os.Stderr, probe.FormatCI(probeResults))
+		} else {
+			fmt.Fprint(os.Stderr, probe.FormatWarnings(probeResults))
+		}
+		for _, r := range probe.GetWarnings(probeResults) {
+			warnings = append(warnings, checkWarning{Type: "probe", Code: r.Code, Key: r.Key, Message: probe.FormatMessage(r)})
+		}
+
+		if probe.HasFailures(probeResults) {
+			if ciMode {
+				fmt.Fprintf(os.Stderr, "\n❌ Probe check failed: %d unreachable dependency(ies)\n", len(probe.GetFailures(probeResults)))
+			} else {
+				fmt.Fprint(os.Stderr, probe.FormatFailures(probeResults))
+			}
+			if checkJSON {
+				fmt.Println(formatCheckReport(checkReport{invariantResults: invResults, waitResults: waitResults, probeResults: probeResults, warnings: warnings, resolved: resolved, schemaPath: schemaPath}))
+			}
+			// Exit with code 7 for unreachable dependencies - do NOT execute command
+			return 7
+		}
+	}
+
 	// Handle check subcommand - validation only, no execution
 	if cmd.Subcommand == cli.SubcommandCheck {
 		// Compute execution ID for check mode (uses placeholder command hash)
 		schemaKeys := getSchemaKeys(s)
 		art := artifact.GenerateArtifact(resolved)
-		execID := execid.ComputeExecutionID(art.ConfigVersion, "", []string{}, environ, schemaKeys)
+		execID := execid.ComputeExecutionIDWithSources(art.ConfigVersion, "", []string{}, environ, schemaKeys, resolveOpts.IdentitySources())
 
 		// Handle execution ID flags in check mode
 		if cmd.ExecutionID {
@@ -206,7 +373,16 @@ func run(args []string, environ []string, defaultSchemaDir string) int {
 		}
 
 		if cmd.JSONOutput {
-			fmt.Println(formatCheckJSONWithExecID(true, result.Errors, invResults, schemaPath, execID.Short()))
+			fmt.Println(formatCheckReport(checkReport{
+				valid:            true,
+				invariantResults: invResults,
+				waitResults:      waitResults,
+				probeResults:     probeResults,
+				warnings:         warnings,
+				resolved:         resolved,
+				schemaPath:       schemaPath,
+				executionID:      execID.Short(),
+			}))
 		} else if !cmd.ExecutionID {
 			fmt.Println("✓ Config valid")
 		}
@@ -218,7 +394,7 @@ func run(args []string, environ []string, defaultSchemaDir string) int {
 		// Compute execution ID for dry-run mode
 		schemaKeys := getSchemaKeys(s)
 		art := artifact.GenerateArtifact(resolved)
-		execID := execid.ComputeExecutionID(art.ConfigVersion, cmd.Target, cmd.Args, environ, schemaKeys)
+		execID := execid.ComputeExecutionIDWithSources(art.ConfigVersion, cmd.Target, cmd.Args, environ, schemaKeys, resolveOpts.IdentitySources())
 
 		// Handle execution ID flags in dry-run mode
 		if cmd.ExecutionID {
@@ -236,16 +412,28 @@ func run(args []string, environ []string, defaultSchemaDir string) int {
 	// Generate config artifact (always generated after successful validation)
 	art := artifact.GenerateArtifact(resolved)
 
+	// Stored records hold keyed digests of sensitive values, or only their
+	// presence without a key file; configVersion still covers the plaintext
+	var maskKey []byte
+	if len(sensitive) > 0 {
+		if maskKey, err = resolver.MaskKey(environ); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot load mask key: %v\n", err)
+			return 1
+		}
+	}
+
 	// Handle artifact output flags
+	outArt := art
+	outArt.Values = resolver.MaskValues(art.Values, sensitive, maskKey)
 	if cmd.ArtifactFile != "" {
-		if err := art.WriteToFile(cmd.ArtifactFile); err != nil {
+		if err := outArt.WriteToFile(cmd.ArtifactFile); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: cannot write artifact: %s: %v\n", cmd.ArtifactFile, err)
 			return 1
 		}
 	}
 
 	if cmd.ArtifactStdout {
-		jsonBytes, err := art.ToJSON()
+		jsonBytes, err := outArt.ToJSON()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: cannot serialize artifact: %v\n", err)
 			return 1
@@ -290,7 +478,7 @@ func run(args []string, environ []string, defaultSchemaDir string) int {
 		schemaKeys := getSchemaKeys(s)
 
 		// Compute v4 execution identity
-		execID := execid.ComputeExecutionID(art.ConfigVersion, cmd.Target, cmd.Args, environ, schemaKeys)
+		execID := execid.ComputeExecutionIDWithSources(art.ConfigVersion, cmd.Target, cmd.Args, environ, schemaKeys, resolveOpts.IdentitySources())
 
 		if cmd.ExecutionIDFile != "" {
 			if err := execID.WriteToFile(cmd.ExecutionIDFile); err != nil {
@@ -336,15 +524,21 @@ func run(args []string, environ []string, defaultSchemaDir string) int {
 	// Handle v5 snapshot storage
 	if cmd.Snapshot {
 		schemaKeys := getSchemaKeys(s)
-		execID := execid.ComputeExecutionID(art.ConfigVersion, cmd.Target, cmd.Args, environ, schemaKeys)
+		execID := execid.ComputeExecutionIDWithSources(art.ConfigVersion, cmd.Target, cmd.Args, environ, schemaKeys, resolveOpts.IdentitySources())
 
 		// Build environment map from schema-referenced vars
+		// Sensitive values are not stored; only their names are recorded
 		envMap := make(map[string]string)
+		var redacted []string
 		for _, key := range schemaKeys {
 			envVar := strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
 			for _, env := range environ {
 				if strings.HasPrefix(env, envVar+"=") {
-					envMap[envVar] = strings.TrimPrefix(env, envVar+"=")
+					if sensitive[key] {
+						redacted = append(redacted, envVar)
+					} else {
+						envMap[envVar] = strings.TrimPrefix(env, envVar+"=")
+					}
 					break
 				}
 			}
@@ -356,8 +550,11 @@ func run(args []string, environ []string, defaultSchemaDir string) int {
 			Command:       cmd.Target,
 			Args:          cmd.Args,
 			Environment:   envMap,
+			Provenance:    art.Provenance,
 			SchemaPath:    schemaPath,
 			Timestamp:     time.Now().UTC(),
+			Sources:       resolveOpts.IdentitySources(),
+			Redacted:      redacted,
 		}
 
 		store := snapshot.NewStore(snapshot.ResolveDir(environ))
@@ -370,7 +567,7 @@ func run(args []string, environ []string, defaultSchemaDir string) int {
 	// Handle v6 baseline storage
 	if cmd.Baseline != "" {
 		schemaKeys := getSchemaKeys(s)
-		execID := execid.ComputeExecutionID(art.ConfigVersion, cmd.Target, cmd.Args, environ, schemaKeys)
+		execID := execid.ComputeExecutionIDWithSources(art.ConfigVersion, cmd.Target, cmd.Args, environ, schemaKeys, resolveOpts.IdentitySources())
 
 		// Build command string
 		cmdStr := cmd.Target
@@ -382,7 +579,7 @@ func run(args []string, environ []string, defaultSchemaDir string) int {
 			Name:         cmd.Baseline,
 			ExecutionID:  execID.ExecutionID,
 			ConfigHash:   art.ConfigVersion,
-			ConfigValues: art.Values,
+			ConfigValues: resolver.MaskValues(art.Values, sensitive, maskKey),
 			Command:      cmdStr,
 			Timestamp:    time.Now().UTC(),
 		}
@@ -400,7 +597,7 @@ func run(args []string, environ []string, defaultSchemaDir string) int {
 		b, err := store.Load(cmd.DetectDrift)
 		if err == nil {
 			// Baseline exists, perform drift detection
-			report := drift.Detect(b, art.Values, art.ConfigVersion)
+			report := drift.Detect(b, resolver.MaskValues(art.Values, sensitive, maskKey), art.ConfigVersion)
 
 			if report.HasDrift {
 				// Output drift report (warnings only, never blocks)
@@ -466,6 +663,451 @@ func resolveSchemaPath(flagValue string, environ []string, defaultDir string) st
 	return filepath.Join(defaultDir, "admit.yaml")
 }
 
+// loadEnvFiles parses all --env-file paths in order.
+// Later files win over earlier ones when entries are merged.
+func loadEnvFiles(paths []string) ([]resolver.DotenvEntry, error) {
+	var entries []resolver.DotenvEntry
+	for _, path := range paths {
+		fileEntries, err := resolver.LoadDotenvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
+}
+
+// loadConfigDirs lists the key files of each --config-dir in order
+func loadConfigDirs(dirs []string) ([]resolver.ConfigDirEntry, error) {
+	var entries []resolver.ConfigDirEntry
+	for _, dir := range dirs {
+		dirEntries, err := resolver.ListConfigDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, dirEntries...)
+	}
+	return entries, nil
+}
+
+// loadConfigFiles reads and flattens each --config-file in order
+func loadConfigFiles(paths []string) ([]resolver.ConfigFileEntry, error) {
+	var entries []resolver.ConfigFileEntry
+	for _, path := range paths {
+		fileEntries, err := resolver.LoadConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
+}
+
+// sourceOptions builds resolver options from the schema's source chain and CLI flags.
+// The chain order comes from --sources, then admit.yaml "sources:", then the default.
+// Paths from admit.yaml are relative to the schema file and load before flag paths,
+// so flags win within a source. envFileEntries are the already loaded --env-file entries.
+func sourceOptions(cmd cli.Command, s schema.Schema, schemaPath string, environ []string, envFileEntries []resolver.DotenvEntry) (resolver.Options, error) {
+	opts := resolver.Options{
+		EnvFileOverride: cmd.EnvFileOverride,
+		FileSecrets:     cmd.SecretFiles,
+		MaxFileSize:     cmd.SecretFileMax,
+		ConfigDirMax:    cmd.ConfigDirMax,
+		Interpolate:     cmd.Interpolate,
+		Order:           cmd.Sources,
+	}
+
+	var envFiles, configDirs, configFiles []string
+	var vaultSpec schema.SourceSpec
+	for _, spec := range s.Sources {
+		if len(cmd.Sources) == 0 {
+			opts.Order = append(opts.Order, spec.Type)
+		}
+		if err := checkSourceSettings(spec); err != nil {
+			return resolver.Options{}, err
+		}
+		if spec.Type == resolver.SourceNameVault {
+			vaultSpec = spec
+			continue
+		}
+		if spec.Type == resolver.SourceNameHelper {
+			opts.Helper = resolver.HelperConfig{
+				Command: helperPath(spec.Path, schemaPath),
+				Args:    spec.Args,
+				Timeout: spec.Timeout,
+				OnError: spec.OnError,
+			}
+			continue
+		}
+		if spec.Path == "" {
+			continue
+		}
+
+		path := spec.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(schemaPath), path)
+		}
+		switch spec.Type {
+		case resolver.SourceNameEnvFile:
+			envFiles = append(envFiles, path)
+		case resolver.SourceNameConfigDir:
+			configDirs = append(configDirs, path)
+		case resolver.SourceNameConfigFile:
+			configFiles = append(configFiles, path)
+		default:
+			return resolver.Options{}, fmt.Errorf("source '%s' does not take a path", spec.Type)
+		}
+	}
+
+	// --helper replaces the admit.yaml helper, including its arguments
+	if cmd.Helper != "" {
+		opts.Helper.Command = cmd.Helper
+		opts.Helper.Args = nil
+	}
+	if cmd.HelperTimeout != 0 {
+		opts.Helper.Timeout = cmd.HelperTimeout
+	}
+	if cmd.HelperOnError != "" {
+		opts.Helper.OnError = cmd.HelperOnError
+	}
+
+	// Vault is used when the chain lists it, or with the default chain when keys have vault stanzas
+	if containsSource(opts.Order, resolver.SourceNameVault) || (len(opts.Order) == 0 && resolver.UsesVault(s)) {
+		vault, err := vaultOptions(cmd, vaultSpec, schemaPath, environ)
+		if err != nil {
+			return resolver.Options{}, err
+		}
+		opts.Vault = vault
+	}
+
+	schemaEnvFileEntries, err := loadEnvFiles(envFiles)
+	if err != nil {
+		return resolver.Options{}, fmt.Errorf("cannot load env file: %w", err)
+	}
+	opts.EnvFile = append(schemaEnvFileEntries, envFileEntries...)
+
+	// Decrypt "enc:v1:" values so validation and the child see the plaintext
+	opts.EnvFile, err = resolver.DecryptEntries(opts.EnvFile, environ)
+	if err != nil {
+		return resolver.Options{}, fmt.Errorf("cannot decrypt env file: %w", err)
+	}
+
+	opts.ConfigDir, err = loadConfigDirs(append(configDirs, cmd.ConfigDirs...))
+	if err != nil {
+		return resolver.Options{}, fmt.Errorf("cannot load config dir: %w", err)
+	}
+
+	opts.ConfigFile, err = loadConfigFiles(append(configFiles, cmd.ConfigFiles...))
+	if err != nil {
+		return resolver.Options{}, fmt.Errorf("cannot load config file: %w", err)
+	}
+
+	return opts, nil
+}
+
+// sourceSettings lists the admit.yaml settings each source type accepts
+var sourceSettings = map[string][]string{
+	resolver.SourceNameEnvFile:    {"path"},
+	resolver.SourceNameConfigDir:  {"path"},
+	resolver.SourceNameConfigFile: {"path"},
+	resolver.SourceNameVault:      {"address", "token_file", "timeout", "retries"},
+	resolver.SourceNameHelper:     {"path", "args", "timeout", "on_error"},
+}
+
+// checkSourceSettings rejects admit.yaml settings that a source type does not use
+func checkSourceSettings(spec schema.SourceSpec) error {
+	given := []struct {
+		name string
+		set  bool
+	}{
+		{"path", spec.Path != ""},
+		{"args", len(spec.Args) > 0},
+		{"timeout", spec.Timeout != 0},
+		{"on_error", spec.OnError != ""},
+		{"address", spec.Address != ""},
+		{"token_file", spec.TokenFile != ""},
+		{"retries", spec.Retries != nil},
+	}
+	for _, g := range given {
+		if g.set && !containsSource(sourceSettings[spec.Type], g.name) {
+			if g.name == "path" {
+				return fmt.Errorf("source '%s' does not take a path", spec.Type)
+			}
+			return fmt.Errorf("source '%s' does not take '%s'", spec.Type, g.name)
+		}
+	}
+	return nil
+}
+
+// vaultOptions builds the Vault settings from flags, the admit.yaml vault
+// source and the environment. Flags win over admit.yaml, which wins over VAULT_ADDR.
+func vaultOptions(cmd cli.Command, spec schema.SourceSpec, schemaPath string, environ []string) (resolver.VaultConfig, error) {
+	vault := resolver.VaultConfig{
+		Address: spec.Address,
+		Timeout: spec.Timeout,
+		Retries: resolver.DefaultVaultRetries,
+	}
+	if spec.Retries != nil {
+		vault.Retries = *spec.Retries
+	}
+	if spec.TokenFile != "" {
+		vault.TokenFile = spec.TokenFile
+		if !filepath.IsAbs(vault.TokenFile) {
+			vault.TokenFile = filepath.Join(filepath.Dir(schemaPath), vault.TokenFile)
+		}
+	}
+
+	if cmd.VaultAddr != "" {
+		vault.Address = cmd.VaultAddr
+	}
+	if vault.Address == "" {
+		vault.Address, _ = lookupEnviron(environ, "VAULT_ADDR")
+	}
+	if vault.Address == "" {
+		return resolver.VaultConfig{}, fmt.Errorf("vault address not set (use --vault-addr, 'address' in admit.yaml or VAULT_ADDR)")
+	}
+	if cmd.VaultTokenFile != "" {
+		vault.TokenFile = cmd.VaultTokenFile
+	}
+	return vault, nil
+}
+
+// containsSource checks if a name is in a list of source names or settings
+func containsSource(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// waitTargets builds the targets of --wait-for and --wait-for-key. A key's
+// target is the host:port of its resolved value.
+func waitTargets(cmd cli.Command, resolved []resolver.ResolvedValue) ([]probe.WaitTarget, error) {
+	var targets []probe.WaitTarget
+	for _, spec := range cmd.WaitFor {
+		target, err := probe.ParseWaitTarget(spec)
+		if err != nil {
+			return nil, fmt.Errorf("--wait-for: %v", err)
+		}
+		targets = append(targets, target)
+	}
+
+	byKey := make(map[string]resolver.ResolvedValue, len(resolved))
+	for _, rv := range resolved {
+		byKey[rv.Key] = rv
+	}
+	for _, key := range cmd.WaitForKeys {
+		rv, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("--wait-for-key: unknown config key '%s'", key)
+		}
+		if !rv.Present {
+			return nil, fmt.Errorf("--wait-for-key: %s has no value", key)
+		}
+		target, err := probe.KeyWaitTarget(key, rv.Value)
+		if err != nil {
+			return nil, fmt.Errorf("--wait-for-key: %v", err)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// resolveExitCode returns the exit code for a resolution error:
+// 6 when a Vault secret could not be fetched, 1 otherwise
+func resolveExitCode(err error) int {
+	var vaultErr *resolver.VaultError
+	if errors.As(err, &vaultErr) {
+		return 6
+	}
+	return 1
+}
+
+// helperPath resolves a helper executable from admit.yaml relative to the schema file.
+// Bare names (no path separator) are left for PATH lookup.
+func helperPath(path, schemaPath string) string {
+	if path == "" || filepath.IsAbs(path) || !strings.ContainsRune(path, filepath.Separator) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(schemaPath), path)
+}
+
+// lookupEnviron finds a variable in an environ slice
+func lookupEnviron(environ []string, name string) (string, bool) {
+	prefix := name + "="
+	for _, env := range environ {
+		if strings.HasPrefix(env, prefix) {
+			return strings.TrimPrefix(env, prefix), true
+		}
+	}
+	return "", false
+}
+
+// evaluation is the outcome of the checks run performs before executing the
+// command. Stages after the first failing one are left empty.
+type evaluation struct {
+	environ     []string // Child environment, with interpolated and helper values applied
+	resolved    []resolver.ResolvedValue
+	sensitive   map[string]bool
+	resolveErr  error
+	validation  validator.ValidationResult
+	invResults  []invariant.InvariantResult
+	envName     string
+	contract    *contract.EvalResult // nil if no environment contract applies
+	contractErr error                // Unknown environment
+	exitCode    int
+}
+
+// evaluate resolves the config and checks it: validation, invariants and
+// the environment contract, stopping at the first stage that fails. It is
+// shared by run and the test subcommand so fixtures see what run would.
+// environ is the merged child environment before resolution.
+func evaluate(cmd cli.Command, s schema.Schema, schemaPath string, processEnviron, environ []string, opts resolver.Options, host executionHost) evaluation {
+	var ev evaluation
+
+	// Resolve config from environment
+	ev.resolved, ev.resolveErr = resolver.ResolveWithOptions(s, processEnviron, opts)
+	if ev.resolveErr != nil {
+		ev.exitCode = resolveExitCode(ev.resolveErr)
+		return ev
+	}
+
+	// Pass interpolated values to the child process so it sees what was validated
+	if cmd.Interpolate {
+		environ = resolver.ApplyInterpolated(environ, ev.resolved)
+	}
+
+	// Pass helper values to the child process, which cannot fetch them itself
+	ev.environ = resolver.ApplyFetched(environ, ev.resolved)
+
+	// Sensitive values are redacted in reports and masked in stored records
+	ev.sensitive = resolver.SensitiveKeys(ev.resolved)
+
+	// Validate config, including validate_with executables (v9 feature)
+	ev.validation = validator.ValidateWithPlugins(s, ev.resolved, validator.PluginOptions{
+		Dir:     filepath.Dir(schemaPath),
+		Env:     resolveEnvironment(cmd.Env, ev.environ),
+		Environ: ev.environ,
+	})
+	if !ev.validation.Valid {
+		ev.exitCode = 1
+		return ev
+	}
+
+	// Evaluate invariants (v2 feature)
+	if len(s.Invariants) > 0 {
+		evalCtx := invariantContext(cmd, ev.environ, ev.resolved, ev.sensitive, host)
+		evalCtx.Trace = cmd.ExplainInvariants
+		ev.invResults = invariant.EvaluateAll(s.Invariants, evalCtx)
+		if invariant.HasViolations(ev.invResults) {
+			ev.exitCode = 2
+			return ev
+		}
+	}
+
+	// Evaluate environment contracts (v7 feature)
+	// Skip if no environment specified (--env flag or ADMIT_ENV)
+	// Skip if no environments section in schema
+	ev.envName = resolveEnvironment(cmd.Env, ev.environ)
+	if ev.envName == "" || len(s.Environments) == 0 {
+		return ev
+	}
+	envContract, exists := s.Environments[ev.envName]
+	if !exists {
+		ev.contractErr = fmt.Errorf("unknown environment '%s'", ev.envName)
+		ev.exitCode = 1
+		return ev
+	}
+
+	configValues := make(map[string]string)
+	for _, rv := range ev.resolved {
+		if rv.Present {
+			configValues[rv.Key] = rv.Value
+		}
+	}
+	contractResult := contract.Evaluate(envContract, configValues)
+	for i, v := range contractResult.Violations {
+		if ev.sensitive[v.Key] {
+			contractResult.Violations[i].ActualValue = resolver.RedactedValue
+		}
+	}
+	for i, v := range contractResult.Warnings {
+		if ev.sensitive[v.Key] {
+			contractResult.Warnings[i].ActualValue = resolver.RedactedValue
+		}
+	}
+	ev.contract = &contractResult
+	if !contractResult.Passed {
+		ev.exitCode = 5
+	}
+	return ev
+}
+
+// executionHost is the part of the invariant execution context that comes
+// from the machine rather than the command line
+type executionHost struct {
+	User     string
+	Hostname string
+	Cwd      string
+}
+
+// currentHost describes the machine admit runs on; the user falls back to
+// $USER if it cannot be looked up
+func currentHost(environ []string) executionHost {
+	var host executionHost
+	if u, err := user.Current(); err == nil {
+		host.User = u.Username
+	} else {
+		host.User, _ = lookupEnviron(environ, "USER")
+	}
+	host.Hostname, _ = os.Hostname()
+	host.Cwd, _ = os.Getwd()
+	return host
+}
+
+// invariantContext builds the context invariants are evaluated in: the
+// resolved config values and the execution context (v9: command, user, env.NAME, ...)
+func invariantContext(cmd cli.Command, environ []string, resolved []resolver.ResolvedValue, sensitive map[string]bool, host executionHost) invariant.EvalContext {
+	configValues := make(map[string]string)
+	redacted := make(map[string]bool, len(sensitive))
+	for _, rv := range resolved {
+		if rv.Present {
+			configValues[rv.Key] = rv.Value
+		}
+		// A sensitive key's variable stays redacted when read as env.NAME
+		if sensitive[rv.Key] {
+			redacted[rv.Key] = true
+			if rv.EnvVar != "" {
+				redacted["env."+rv.EnvVar] = true
+			}
+		}
+	}
+
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			env[name] = value
+		}
+	}
+
+	ctx := invariant.EvalContext{
+		ConfigValues: configValues,
+		ExecutionEnv: resolveEnvironment(cmd.Env, environ),
+		Sensitive:    redacted,
+		Args:         cmd.Args,
+		Env:          env,
+		User:         host.User,
+		Hostname:     host.Hostname,
+		Cwd:          host.Cwd,
+	}
+	if cmd.Target != "" {
+		ctx.Command = filepath.Base(cmd.Target)
+	}
+	return ctx
+}
+
 // getAdmitEnv extracts the ADMIT_ENV value from the environment slice
 func getAdmitEnv(environ []string) string {
 	for _, env := range environ {
@@ -499,41 +1141,196 @@ func getEnvBool(environ []string, name string) bool {
 	return false
 }
 
-// formatCIAnnotation formats a validation error as GitHub Actions annotation
+// formatCIAnnotation formats a validation error as GitHub Actions annotation.
+// Errors for values read from an env file or config file point at that file and line.
+// Failures of keys with severity "warn" are ::warning annotations.
 func formatCIAnnotation(err validator.ValidationError) string {
-	return fmt.Sprintf("::error file=admit.yaml::%s", validator.FormatError(err))
+	command := "error"
+	if err.Severity.IsWarning() {
+		command = "warning"
+	}
+	if err.File != "" {
+		return fmt.Sprintf("::%s file=%s,line=%d::%s", command, err.File, err.Line, validator.FormatError(err))
+	}
+	return fmt.Sprintf("::%s file=admit.yaml::%s", command, validator.FormatError(err))
+}
+
+// checkWarning is a failure of a "warn" rule, as reported by check --json
+type checkWarning struct {
+	Type        string     `json:"type"`                  // "validation", "invariant", "contract" or "probe"
+	Code        codes.Code `json:"code"`                  // Error code (see admit explain-code)
+	Key         string     `json:"key,omitempty"`         // Config key (validation and contract warnings)
+	Name        string     `json:"name,omitempty"`        // Invariant name
+	Environment string     `json:"environment,omitempty"` // Contract environment
+	Message     string     `json:"message"`
+}
+
+// checkContractViolation is a blocking contract violation, as reported by check --json
+type checkContractViolation struct {
+	Code        codes.Code `json:"code"`
+	Key         string     `json:"key"`
+	Environment string     `json:"environment"`
+	Rule        string     `json:"rule"` // "allow" or "deny"
+	Value       string     `json:"value"`
+	Message     string     `json:"message"`
+}
+
+// checkSchemaError is a schema that could not be loaded, as reported by check --json
+type checkSchemaError struct {
+	Code    codes.Code `json:"code,omitempty"`
+	Message string     `json:"message"`
+}
+
+// checkResolveError is a value the sources could not provide, as reported by check --json
+type checkResolveError struct {
+	Code    codes.Code `json:"code,omitempty"`
+	Message string     `json:"message"`
+}
+
+// checkResolveErrors splits a resolution error into its failures, each with its code
+func checkResolveErrors(err error) []checkResolveError {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	result := make([]checkResolveError, len(errs))
+	for i, e := range errs {
+		result[i] = checkResolveError{Code: resolver.ErrorCode(e), Message: e.Error()}
+	}
+	return result
+}
+
+// checkReport holds everything check --json reports. A failed check reports
+// the failures of the stage that stopped it.
+type checkReport struct {
+	valid              bool
+	validationErrors   []validator.ValidationError
+	invariantResults   []invariant.InvariantResult
+	contractViolations []checkContractViolation
+	waitResults        []probe.WaitResult
+	probeResults       []probe.Result
+	warnings           []checkWarning
+	schemaError        *checkSchemaError
+	resolveErrors      []checkResolveError
+	resolved           []resolver.ResolvedValue
+	schemaPath         string
+	executionID        string
 }
 
 // formatCheckJSON formats check results as JSON
 func formatCheckJSON(valid bool, valErrors []validator.ValidationError, invResults []invariant.InvariantResult, schemaPath string) string {
-	return formatCheckJSONWithExecID(valid, valErrors, invResults, schemaPath, "")
+	return formatCheckJSONWithExecID(valid, valErrors, invResults, nil, schemaPath, "")
 }
 
 // formatCheckJSONWithExecID formats check results as JSON with optional execution ID
-func formatCheckJSONWithExecID(valid bool, valErrors []validator.ValidationError, invResults []invariant.InvariantResult, schemaPath string, executionID string) string {
+// and per-key provenance
+func formatCheckJSONWithExecID(valid bool, valErrors []validator.ValidationError, invResults []invariant.InvariantResult, resolved []resolver.ResolvedValue, schemaPath string, executionID string) string {
+	return formatCheckReport(checkReport{
+		valid:            valid,
+		validationErrors: valErrors,
+		invariantResults: invResults,
+		resolved:         resolved,
+		schemaPath:       schemaPath,
+		executionID:      executionID,
+	})
+}
+
+// formatCheckReport formats a check report as JSON
+func formatCheckReport(r checkReport) string {
 	// Simple JSON formatting without external dependencies
 	var sb strings.Builder
 	sb.WriteString("{")
-	sb.WriteString(fmt.Sprintf(`"valid":%t,`, valid))
+	sb.WriteString(fmt.Sprintf(`"valid":%t,`, r.valid))
+	if r.schemaError != nil {
+		if errJSON, err := json.Marshal(r.schemaError); err == nil {
+			sb.WriteString(`"schemaError":`)
+			sb.Write(errJSON)
+			sb.WriteString(",")
+		}
+	}
+	if len(r.resolveErrors) > 0 {
+		if errJSON, err := json.Marshal(r.resolveErrors); err == nil {
+			sb.WriteString(`"resolveErrors":`)
+			sb.Write(errJSON)
+			sb.WriteString(",")
+		}
+	}
 	sb.WriteString(`"validationErrors":[`)
-	for i, err := range valErrors {
+	for i, err := range r.validationErrors {
 		if i > 0 {
 			sb.WriteString(",")
 		}
-		sb.WriteString(fmt.Sprintf(`{"key":"%s","envVar":"%s","message":"%s"}`, err.Key, err.EnvVar, escapeJSON(err.Message)))
+		sb.WriteString(fmt.Sprintf(`{"code":"%s","key":"%s","envVar":"%s","message":"%s"`, err.Code, err.Key, err.EnvVar, escapeJSON(err.Message)))
+		if err.File != "" {
+			sb.WriteString(fmt.Sprintf(`,"file":"%s","line":%d`, escapeJSON(err.File), err.Line))
+		}
+		if err.Validator != "" {
+			sb.WriteString(fmt.Sprintf(`,"validator":"%s"`, escapeJSON(err.Validator)))
+		}
+		sb.WriteString("}")
 	}
 	sb.WriteString("],")
 	sb.WriteString(`"invariantResults":[`)
-	for i, inv := range invResults {
+	for i, inv := range r.invariantResults {
 		if i > 0 {
 			sb.WriteString(",")
 		}
-		sb.WriteString(fmt.Sprintf(`{"name":"%s","rule":"%s","passed":%t}`, inv.Name, escapeJSON(inv.Rule), inv.Passed))
+		sb.WriteString(fmt.Sprintf(`{"name":"%s","rule":"%s","passed":%t,"severity":"%s"`, inv.Name, escapeJSON(inv.Rule), inv.Passed, inv.Severity))
+		if inv.Code != "" {
+			sb.WriteString(fmt.Sprintf(`,"code":"%s","message":"%s"`, inv.Code, escapeJSON(inv.Message)))
+		}
+		if inv.Skipped {
+			sb.WriteString(fmt.Sprintf(`,"skipped":true,"message":"%s"`, escapeJSON(inv.Message)))
+		}
+		if inv.Trace != nil {
+			if traceJSON, err := json.Marshal(inv.Trace); err == nil {
+				sb.WriteString(`,"trace":`)
+				sb.Write(traceJSON)
+			}
+		}
+		sb.WriteString("}")
 	}
 	sb.WriteString("],")
-	sb.WriteString(fmt.Sprintf(`"schemaPath":"%s"`, escapeJSON(schemaPath)))
-	if executionID != "" {
-		sb.WriteString(fmt.Sprintf(`,"executionId":"%s"`, escapeJSON(executionID)))
+	if len(r.contractViolations) > 0 {
+		if violationJSON, err := json.Marshal(r.contractViolations); err == nil {
+			sb.WriteString(`"contractViolations":`)
+			sb.Write(violationJSON)
+			sb.WriteString(",")
+		}
+	}
+	if len(r.waitResults) > 0 {
+		if waitJSON, err := json.Marshal(r.waitResults); err == nil {
+			sb.WriteString(`"waitResults":`)
+			sb.Write(waitJSON)
+			sb.WriteString(",")
+		}
+	}
+	if len(r.probeResults) > 0 {
+		if probeJSON, err := json.Marshal(r.probeResults); err == nil {
+			sb.WriteString(`"probeResults":`)
+			sb.Write(probeJSON)
+			sb.WriteString(",")
+		}
+	}
+	warnings := r.warnings
+	if warnings == nil {
+		warnings = []checkWarning{}
+	}
+	if warnJSON, err := json.Marshal(warnings); err == nil {
+		sb.WriteString(`"warnings":`)
+		sb.Write(warnJSON)
+		sb.WriteString(",")
+	}
+	sb.WriteString(fmt.Sprintf(`"schemaPath":"%s"`, escapeJSON(r.schemaPath)))
+	if r.executionID != "" {
+		sb.WriteString(fmt.Sprintf(`,"executionId":"%s"`, escapeJSON(r.executionID)))
+	}
+	if provenance := resolver.ProvenanceMap(r.resolved); len(provenance) > 0 {
+		// Map keys are sorted by encoding/json, so output is deterministic
+		if provJSON, err := json.Marshal(provenance); err == nil {
+			sb.WriteString(`,"provenance":`)
+			sb.Write(provJSON)
+		}
 	}
 	sb.WriteString("}")
 	return sb.String()
@@ -662,16 +1459,31 @@ func runReplay(cmd cli.Command, environ []string) int {
 		return 1
 	}
 
+	// Sensitive values were not stored; take them from the current environment
+	restored := make(map[string]string, len(snap.Environment)+len(snap.Redacted))
+	for k, v := range snap.Environment {
+		restored[k] = v
+	}
+	for _, name := range snap.Redacted {
+		if value, ok := lookupEnviron(environ, name); ok {
+			restored[name] = value
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: sensitive value %s was not stored in the snapshot and is not set\n", name)
+		}
+	}
+
 	// Get schema keys for verification (from snapshot environment keys)
 	var schemaKeys []string
-	for k := range snap.Environment {
+	for k := range restored {
 		// Convert env var back to schema key (e.g., DB_URL -> db.url)
 		key := strings.ToLower(strings.ReplaceAll(k, "_", "."))
 		schemaKeys = append(schemaKeys, key)
 	}
 
 	// Verify snapshot integrity
-	verifyResult := snapshot.Verify(snap, schemaKeys)
+	verifySnap := snap
+	verifySnap.Environment = restored
+	verifyResult := snapshot.Verify(verifySnap, schemaKeys)
 	if verifyResult.IDMismatch {
 		fmt.Fprintln(os.Stderr, "Warning: snapshot may be corrupted (execution ID mismatch)")
 	}
@@ -697,11 +1509,14 @@ func runReplay(cmd cli.Command, environ []string) int {
 		for k, v := range snap.Environment {
 			fmt.Printf("  %s=%s\n", k, v)
 		}
+		for _, name := range snap.Redacted {
+			fmt.Printf("  %s=%s\n", name, resolver.RedactedValue)
+		}
 		return 0
 	}
 
 	// Restore environment from snapshot
-	for k, v := range snap.Environment {
+	for k, v := range restored {
 		environ = append(environ, k+"="+v)
 	}
 
@@ -810,3 +1625,275 @@ func runBaseline(cmd cli.Command, environ []string) int {
 
 	return 1
 }
+
+// runExplain handles the explain subcommand.
+// It resolves config exactly like run and reports where a single key's value came from.
+func runExplain(cmd cli.Command, s schema.Schema, processEnviron []string, opts resolver.Options) int {
+	if _, exists := s.Config[cmd.ExplainKey]; !exists {
+		fmt.Fprintf(os.Stderr, "Error: unknown config key: %s\n", cmd.ExplainKey)
+		return 1
+	}
+
+	resolved, err := resolver.ResolveWithOptions(s, processEnviron, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot resolve config: %v\n", err)
+		return resolveExitCode(err)
+	}
+
+	var rv resolver.ResolvedValue
+	for _, r := range resolved {
+		if r.Key == cmd.ExplainKey {
+			rv = r
+			break
+		}
+	}
+
+	if cmd.JSONOutput {
+		out := struct {
+			Key       string               `json:"key"`
+			EnvVar    string               `json:"envVar"`
+			Present   bool                 `json:"present"`
+			Value     *string              `json:"value"`
+			Sensitive bool                 `json:"sensitive,omitempty"`
+			Source    *resolver.Provenance `json:"source"`
+		}{Key: rv.Key, EnvVar: rv.EnvVar, Present: rv.Present, Sensitive: rv.Sensitive}
+		if rv.Present {
+			value := rv.DisplayValue()
+			out.Value = &value
+			out.Source = &rv.Source
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot serialize explanation: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	fmt.Printf("Key:     %s\n", rv.Key)
+	fmt.Printf("EnvVar:  %s\n", rv.EnvVar)
+	if rv.Present {
+		fmt.Printf("Value:   %s\n", rv.DisplayValue())
+	} else {
+		fmt.Println("Value:   (not set)")
+	}
+	fmt.Printf("Source:  %s\n", rv.Source)
+	return 0
+}
+
+// runEncrypt handles the encrypt subcommand.
+// It prints an "enc:v1:" value of the --name variable for an env file, encrypting
+// the value argument or, without one, stdin (minus a trailing newline). With --generate-key it
+// prints a new key for a key file instead.
+func runEncrypt(cmd cli.Command, environ []string) int {
+	if cmd.GenerateKey {
+		key, err := resolver.GenerateKey()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot generate key: %v\n", err)
+			return 1
+		}
+		fmt.Println(key)
+		return 0
+	}
+
+	keyFile := cmd.KeyFile
+	if keyFile == "" {
+		keyFile, _ = lookupEnviron(environ, resolver.KeyFileEnv)
+	}
+	if keyFile == "" {
+		fmt.Fprintf(os.Stderr, "Error: no key file: set %s or use --key-file\n", resolver.KeyFileEnv)
+		return 1
+	}
+	key, err := resolver.LoadKey(keyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	plaintext := cmd.EncryptValue
+	if plaintext == "" {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot read value from stdin: %v\n", err)
+			return 1
+		}
+		plaintext = strings.TrimSuffix(strings.TrimSuffix(string(input), "\n"), "\r")
+	}
+
+	value, err := resolver.EncryptValue(key, cmd.EncryptName, plaintext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot encrypt value: %v\n", err)
+		return 1
+	}
+	fmt.Println(value)
+	return 0
+}
+
+// runExplainCode handles the explain-code subcommand (v9 feature).
+// With a code it documents that code; without one it lists all codes.
+func runExplainCode(cmd cli.Command) int {
+	if cmd.ExplainCode == "" {
+		all := codes.All()
+		if cmd.JSONOutput {
+			data, err := json.MarshalIndent(all, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: cannot format codes: %v\n", err)
+				return 1
+			}
+			fmt.Println(string(data))
+			return 0
+		}
+		for _, info := range all {
+			fmt.Printf("%s  %-10s  %s\n", info.Code, info.Category, info.Title)
+		}
+		return 0
+	}
+
+	info, ok := codes.Lookup(codes.Code(cmd.ExplainCode))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown error code: %s (run 'admit explain-code' to list codes)\n", cmd.ExplainCode)
+		return 1
+	}
+
+	if cmd.JSONOutput {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot format code: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+
+	fmt.Printf("%s: %s\n\n", info.Code, info.Title)
+	fmt.Printf("Category:  %s\n", info.Category)
+	if info.ExitCode != 0 {
+		fmt.Printf("Exit code: %d\n", info.ExitCode)
+	} else {
+		fmt.Println("Exit code: none (not blocking)")
+	}
+	fmt.Printf("\n%s\n\nFix: %s\n", info.Description, info.Fix)
+	return 0
+}
+
+// runInvariants handles the invariants analyze subcommand (v9 feature).
+// Returns 2 if an invariant can never pass or two invariants conflict.
+func runInvariants(cmd cli.Command, s schema.Schema) int {
+	analysis := invariant.Analyze(s.Invariants, s.AnalysisDomain())
+
+	if cmd.JSONOutput {
+		data, err := json.MarshalIndent(analysis, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot format analysis: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(invariant.FormatAnalysis(analysis))
+	}
+
+	if analysis.HasProblems() {
+		return 2
+	}
+	return 0
+}
+
+// runTest handles the test subcommand (v9 feature).
+// It checks each fixture's outcome and prints a report, writing a JUnit XML
+// report too with --junit. Returns 1 if a fixture does not match.
+func runTest(cmd cli.Command, s schema.Schema, schemaPath string, processEnviron []string) int {
+	path := cmd.TestFile
+	if path == "" {
+		path = filepath.Join(filepath.Dir(schemaPath), fixture.DefaultFile)
+	}
+	cases, err := fixture.Load(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot load fixtures %s: %v\n", path, err)
+		return 1
+	}
+
+	results := make([]fixture.Result, 0, len(cases))
+	failed := false
+	for _, c := range cases {
+		start := time.Now()
+		result := fixture.Check(c, testOutcome(cmd, s, schemaPath, c, processEnviron))
+		result.Elapsed = time.Since(start)
+		results = append(results, result)
+		failed = failed || !result.Passed()
+	}
+
+	fmt.Print(fixture.FormatText(results))
+
+	if cmd.JUnitPath != "" {
+		report, err := fixture.FormatJUnit(filepath.Base(path), results)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot format JUnit report: %v\n", err)
+			return 1
+		}
+		if err := os.WriteFile(cmd.JUnitPath, []byte(report), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot write JUnit report: %v\n", err)
+			return 1
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// testOutcome runs a fixture through the stages admit run checks before
+// executing the command (see evaluate): resolve, validate, invariants and the
+// environment contract. --wait-for targets and probes are not checked. Runs
+// are hermetic: the execution context comes from the fixture, and the vault
+// and helper sources are only queried if the fixture sets live_sources.
+func testOutcome(cmd cli.Command, s schema.Schema, schemaPath string, c fixture.Case, processEnviron []string) fixture.Outcome {
+	// The fixture's variables are the whole environment, except for PATH
+	// (so validate_with executables are found)
+	environ := c.Environ()
+	if _, set := c.Env["PATH"]; !set {
+		if path, ok := lookupEnviron(processEnviron, "PATH"); ok {
+			environ = append(environ, "PATH="+path)
+		}
+	}
+	caseEnviron := environ
+
+	var envFiles []string
+	if c.EnvFile != "" {
+		envFiles = []string{c.EnvFile}
+	}
+	envFileEntries, err := loadEnvFiles(envFiles)
+	if err != nil {
+		return fixture.Outcome{ExitCode: 1, Error: fmt.Sprintf("cannot load env file: %v", err)}
+	}
+	environ = resolver.MergeEnviron(environ, envFileEntries, cmd.EnvFileOverride)
+
+	opts, err := sourceOptions(cmd, s, schemaPath, environ, envFileEntries)
+	if err != nil {
+		return fixture.Outcome{ExitCode: 1, Error: err.Error()}
+	}
+	if !c.LiveSources {
+		opts = opts.Offline()
+	}
+	environ = resolver.MergeEnviron(caseEnviron, opts.EnvFile, opts.EnvFileWins())
+
+	cmd.Target = c.Execution.Command
+	cmd.Args = c.Execution.Args
+	host := executionHost{User: c.Execution.User, Hostname: c.Execution.Hostname, Cwd: c.Execution.Cwd}
+
+	ev := evaluate(cmd, s, schemaPath, caseEnviron, environ, opts, host)
+	switch {
+	case ev.resolveErr != nil:
+		return fixture.Outcome{ExitCode: ev.exitCode, Error: fmt.Sprintf("cannot resolve config: %v", ev.resolveErr)}
+	case !ev.validation.Valid:
+		return fixture.Outcome{ExitCode: ev.exitCode, Errors: ev.validation.Errors}
+	case invariant.HasViolations(ev.invResults):
+		return fixture.Outcome{ExitCode: ev.exitCode, Invariants: invariant.GetViolations(ev.invResults)}
+	case ev.contractErr != nil:
+		return fixture.Outcome{ExitCode: ev.exitCode, Error: ev.contractErr.Error()}
+	case ev.contract != nil && !ev.contract.Passed:
+		return fixture.Outcome{ExitCode: ev.exitCode, Contract: ev.contract.Violations}
+	}
+	return fixture.Outcome{}
+}
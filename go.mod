@@ -1,8 +1,9 @@
 module admit
 
-go 1.21
+go 1.21.0
 
-require (
-	github.com/leanovate/gopter v0.2.11 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
-)
+require gopkg.in/yaml.v3 v3.0.1
+
+require github.com/leanovate/gopter v0.2.11
+
+require github.com/pelletier/go-toml/v2 v2.2.4
@@ -11,23 +11,33 @@ import (
 
 // ConfigArtifact represents the immutable config artifact
 type ConfigArtifact struct {
-	ConfigVersion string            `json:"configVersion"` // sha256:hex
-	Values        map[string]string `json:"values"`
+	ConfigVersion string                         `json:"configVersion"` // sha256:hex
+	Values        map[string]string              `json:"values"`
+	Provenance    map[string]resolver.Provenance `json:"provenance,omitempty"` // Where each value came from (not hashed)
 }
 
 // GenerateArtifact creates a config artifact from resolved values.
 // Only includes values that are present (set in environment).
+// Provenance is informational and does not affect configVersion.
 func GenerateArtifact(resolved []resolver.ResolvedValue) ConfigArtifact {
 	values := make(map[string]string)
+	var provenance map[string]resolver.Provenance
 	for _, rv := range resolved {
 		if rv.Present {
 			values[rv.Key] = rv.Value
+			if rv.Source.Kind != "" {
+				if provenance == nil {
+					provenance = make(map[string]resolver.Provenance)
+				}
+				provenance[rv.Key] = rv.Source
+			}
 		}
 	}
 
 	return ConfigArtifact{
 		ConfigVersion: ComputeConfigVersion(values),
 		Values:        values,
+		Provenance:    provenance,
 	}
 }
 
@@ -318,3 +318,51 @@ func TestConfigHashUniqueness_Property(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+// TestGenerateArtifact_Provenance tests that value provenance is recorded
+// in the artifact without changing the config version
+func TestGenerateArtifact_Provenance(t *testing.T) {
+	fromEnv := []resolver.ResolvedValue{
+		{Key: "db.password", EnvVar: "DB_PASSWORD", Value: "s3cret", Present: true,
+			Source: resolver.Provenance{Kind: resolver.SourceEnv, Var: "DB_PASSWORD"}},
+		{Key: "db.user", EnvVar: "DB_USER", Value: "app", Present: true,
+			Source: resolver.Provenance{Kind: resolver.SourceEnv, Var: "DB_USER"}},
+		{Key: "db.host", EnvVar: "DB_HOST"},
+	}
+	fromFile := []resolver.ResolvedValue{
+		{Key: "db.password", EnvVar: "DB_PASSWORD", Value: "s3cret", Present: true,
+			Source: resolver.Provenance{Kind: resolver.SourceFile, Var: "DB_PASSWORD_FILE", Path: "/run/secrets/db"}},
+		{Key: "db.user", EnvVar: "DB_USER", Value: "app", Present: true,
+			Source: resolver.Provenance{Kind: resolver.SourceEnv, Var: "DB_USER"}},
+		{Key: "db.host", EnvVar: "DB_HOST"},
+	}
+
+	envArt := GenerateArtifact(fromEnv)
+	fileArt := GenerateArtifact(fromFile)
+
+	if envArt.ConfigVersion != fileArt.ConfigVersion {
+		t.Errorf("configVersion should not depend on value source: %s != %s", envArt.ConfigVersion, fileArt.ConfigVersion)
+	}
+	if len(fileArt.Provenance) != 2 {
+		t.Errorf("expected provenance for the 2 present keys, got %v", fileArt.Provenance)
+	}
+	if p := fileArt.Provenance["db.password"]; p.Kind != resolver.SourceFile || p.Path != "/run/secrets/db" {
+		t.Errorf("db.password provenance = %+v, want secret file /run/secrets/db", p)
+	}
+
+	// Provenance is omitted when no source information is available
+	bare := GenerateArtifact([]resolver.ResolvedValue{{Key: "a", Value: "1", Present: true}})
+	jsonBytes, err := bare.ToJSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(jsonBytes), "provenance") {
+		t.Errorf("provenance should be omitted when empty: %s", jsonBytes)
+	}
+
+	// Provenance is never part of the canonical (hashed) form
+	canonical, _ := fileArt.ToCanonicalJSON()
+	if strings.Contains(string(canonical), "provenance") {
+		t.Errorf("canonical JSON should not include provenance: %s", canonical)
+	}
+}
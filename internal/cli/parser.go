@@ -3,6 +3,7 @@ package cli
 import (
 	"errors"
 	"strings"
+	"time"
 )
 
 // ErrNoCommand is returned when no command is provided after "run"
@@ -18,11 +19,16 @@ var ErrMissingFlagValue = errors.New("flag requires a value")
 type Subcommand string
 
 const (
-	SubcommandRun       Subcommand = "run"
-	SubcommandCheck     Subcommand = "check"
-	SubcommandReplay    Subcommand = "replay"    // v5: replay an execution
-	SubcommandSnapshots Subcommand = "snapshots" // v5: list/manage snapshots
-	SubcommandBaseline  Subcommand = "baseline"  // v6: manage baselines
+	SubcommandRun         Subcommand = "run"
+	SubcommandCheck       Subcommand = "check"
+	SubcommandReplay      Subcommand = "replay"       // v5: replay an execution
+	SubcommandSnapshots   Subcommand = "snapshots"    // v5: list/manage snapshots
+	SubcommandBaseline    Subcommand = "baseline"     // v6: manage baselines
+	SubcommandExplain     Subcommand = "explain"      // v8: show where a config value came from
+	SubcommandEncrypt     Subcommand = "encrypt"      // v8: encrypt a value for an env file
+	SubcommandExplainCode Subcommand = "explain-code" // v9: document an error code
+	SubcommandInvariants  Subcommand = "invariants"   // v9: analyze invariants
+	SubcommandTest        Subcommand = "test"         // v9: check schema fixtures
 )
 
 // Command represents the parsed CLI input
@@ -46,7 +52,8 @@ type Command struct {
 	IdentityShort bool   // --identity-short
 
 	// Invariant flags
-	InvariantsJSON bool // --invariants-json
+	InvariantsJSON    bool // --invariants-json
+	ExplainInvariants bool // --explain (v9: print each invariant's evaluation tree)
 
 	// v3 flags
 	SchemaPath string // --schema <path>
@@ -76,6 +83,50 @@ type Command struct {
 	// v7 Environment Contract flags
 	Env          string // --env <name> (environment for contract evaluation)
 	ContractJSON bool   // --contract-json (output contract violations as JSON)
+
+	// v8 Config Source flags
+	EnvFiles        []string      // --env-file <path> (repeatable, later files win)
+	EnvFileOverride bool          // --env-file-override (env-file values win over process env)
+	SecretFiles     bool          // --secret-files (read <VAR>_FILE when <VAR> is unset)
+	SecretFileMax   int64         // --secret-file-max-size <bytes> (0 uses the default limit)
+	ConfigDirs      []string      // --config-dir <dir> (repeatable, later dirs win)
+	ConfigDirMax    int64         // --config-dir-max-size <bytes> (0 uses the default limit)
+	ConfigFiles     []string      // --config-file <path> (repeatable, later files win)
+	Interpolate     bool          // --interpolate (expand ${VAR} references in values)
+	Sources         []string      // --sources <name,...> (source chain in precedence order)
+	Helper          string        // --helper <path> (executable that supplies values as JSON)
+	HelperTimeout   time.Duration // --helper-timeout <duration> (0 uses the default)
+	HelperOnError   string        // --helper-on-error <fail|ignore> (non-zero exit policy)
+	VaultAddr       string        // --vault-addr <url> (Vault server, overrides VAULT_ADDR)
+	VaultTokenFile  string        // --vault-token-file <path> (used when VAULT_TOKEN is unset)
+	ExplainKey      string        // config key argument for explain subcommand
+
+	// Encrypt flags (v8)
+	EncryptValue string // value argument for encrypt subcommand (empty reads stdin)
+	EncryptName  string // --name <VAR> (the variable the value is encrypted for)
+	KeyFile      string // --key-file <path> (overrides ADMIT_KEY_FILE)
+	GenerateKey  bool   // --generate-key (print a new key instead of encrypting)
+
+	// v9 Severity flags
+	WarningsAsErrors bool // --warnings-as-errors (failures of "warn" rules block too)
+
+	// v9 Error code flags
+	ExplainCode string // code argument for explain-code subcommand (empty lists all codes)
+
+	// v9 Invariant analysis flags
+	InvariantsAction string // "analyze" for invariants subcommand
+
+	// v9 Fixture flags
+	TestFile  string // fixtures file argument for test subcommand (empty uses admit.test.yaml next to the schema)
+	JUnitPath string // --junit <path> (also write a JUnit XML report)
+
+	// v9 Probe flags
+	Probe bool // --probe (also run dependency probes for check and --dry-run)
+
+	// v9 Readiness flags
+	WaitFor     []string      // --wait-for <tcp://host:port|file:/path> (repeatable)
+	WaitForKeys []string      // --wait-for-key <key> (repeatable, waits for the host:port of the key's value)
+	WaitTimeout time.Duration // --wait-timeout <duration> (overall deadline, 0 uses the default)
 }
 
 // ParseArgs parses CLI arguments into a Command.
@@ -90,7 +141,7 @@ func ParseArgs(args []string) (Command, error) {
 	// First arg must be a valid subcommand
 	subcommand := args[0]
 	switch subcommand {
-	case "run", "check", "replay", "snapshots", "baseline":
+	case "run", "check", "replay", "snapshots", "baseline", "explain", "encrypt", "explain-code", "invariants", "test":
 		// Valid subcommands
 	default:
 		return Command{}, ErrNoRunSubcommand
@@ -115,6 +166,31 @@ func ParseArgs(args []string) (Command, error) {
 		return parseBaselineArgs(args[1:], cmd)
 	}
 
+	// Handle explain subcommand: admit explain <key> [flags]
+	if subcommand == "explain" {
+		return parseExplainArgs(args[1:], cmd)
+	}
+
+	// Handle encrypt subcommand: admit encrypt [--key-file <path>] [value]
+	if subcommand == "encrypt" {
+		return parseEncryptArgs(args[1:], cmd)
+	}
+
+	// Handle explain-code subcommand: admit explain-code [--json] [code]
+	if subcommand == "explain-code" {
+		return parseExplainCodeArgs(args[1:], cmd)
+	}
+
+	// Handle invariants subcommand: admit invariants analyze [--schema <path>] [--json]
+	if subcommand == "invariants" {
+		return parseInvariantsArgs(args[1:], cmd)
+	}
+
+	// Handle test subcommand: admit test [--schema <path>] [--junit <path>] [--interpolate] [fixtures]
+	if subcommand == "test" {
+		return parseTestArgs(args[1:], cmd)
+	}
+
 	// Parse flags and find the command (for run/check)
 	i := 1 // Start after subcommand
 
@@ -161,6 +237,8 @@ func ParseArgs(args []string) (Command, error) {
 				cmd.IdentityShort = true
 			case "invariants-json":
 				cmd.InvariantsJSON = true
+			case "explain":
+				cmd.ExplainInvariants = true
 			case "schema":
 				if i+1 >= len(args) {
 					return Command{}, ErrMissingFlagValue
@@ -217,9 +295,41 @@ func ParseArgs(args []string) (Command, error) {
 				cmd.Env = args[i]
 			case "contract-json":
 				cmd.ContractJSON = true
+			case "warnings-as-errors":
+				cmd.WarningsAsErrors = true
+			case "probe":
+				cmd.Probe = true
+			case "wait-for":
+				if i+1 >= len(args) {
+					return Command{}, ErrMissingFlagValue
+				}
+				i++
+				if !strings.HasPrefix(args[i], "tcp://") && !strings.HasPrefix(args[i], "file:") {
+					return Command{}, errors.New("--wait-for requires tcp://host:port or file:/path")
+				}
+				cmd.WaitFor = append(cmd.WaitFor, args[i])
+			case "wait-for-key":
+				if i+1 >= len(args) {
+					return Command{}, ErrMissingFlagValue
+				}
+				i++
+				cmd.WaitForKeys = append(cmd.WaitForKeys, args[i])
+			case "wait-timeout":
+				if i+1 >= len(args) {
+					return Command{}, ErrMissingFlagValue
+				}
+				i++
+				timeout, err := time.ParseDuration(args[i])
+				if err != nil || timeout <= 0 {
+					return Command{}, errors.New("--wait-timeout requires a positive duration (e.g., 30s)")
+				}
+				cmd.WaitTimeout = timeout
 			default:
+				// v8 config source flags are shared with other subcommands
+				if _, err := parseSourceFlag(flagName, args, &i, &cmd); err != nil {
+					return Command{}, err
+				}
 				// Unknown flag - treat as start of command
-				break
 			}
 			i++
 			continue
@@ -336,7 +446,6 @@ func parseInt(s string) (int, error) {
 	return n, nil
 }
 
-
 // parseBaselineArgs parses arguments for the baseline subcommand.
 func parseBaselineArgs(args []string, cmd Command) (Command, error) {
 	if len(args) == 0 {
@@ -377,3 +486,275 @@ func parseBaselineArgs(args []string, cmd Command) (Command, error) {
 
 	return cmd, nil
 }
+
+// parseSourceFlag parses a v8 config source flag at args[*i].
+// It advances *i past any flag value and reports whether the flag was recognized.
+func parseSourceFlag(flagName string, args []string, i *int, cmd *Command) (bool, error) {
+	switch flagName {
+	case "env-file":
+		if *i+1 >= len(args) {
+			return true, ErrMissingFlagValue
+		}
+		*i++
+		cmd.EnvFiles = append(cmd.EnvFiles, args[*i])
+	case "env-file-override":
+		cmd.EnvFileOverride = true
+	case "secret-files":
+		cmd.SecretFiles = true
+	case "secret-file-max-size":
+		if *i+1 >= len(args) {
+			return true, ErrMissingFlagValue
+		}
+		*i++
+		size, err := parseInt(args[*i])
+		if err != nil || size == 0 {
+			return true, errors.New("--secret-file-max-size requires a positive number of bytes")
+		}
+		cmd.SecretFileMax = int64(size)
+	case "config-dir":
+		if *i+1 >= len(args) {
+			return true, ErrMissingFlagValue
+		}
+		*i++
+		cmd.ConfigDirs = append(cmd.ConfigDirs, args[*i])
+	case "config-dir-max-size":
+		if *i+1 >= len(args) {
+			return true, ErrMissingFlagValue
+		}
+		*i++
+		size, err := parseInt(args[*i])
+		if err != nil || size == 0 {
+			return true, errors.New("--config-dir-max-size requires a positive number of bytes")
+		}
+		cmd.ConfigDirMax = int64(size)
+	case "config-file":
+		if *i+1 >= len(args) {
+			return true, ErrMissingFlagValue
+		}
+		*i++
+		cmd.ConfigFiles = append(cmd.ConfigFiles, args[*i])
+	case "interpolate":
+		cmd.Interpolate = true
+	case "sources":
+		if *i+1 >= len(args) {
+			return true, ErrMissingFlagValue
+		}
+		*i++
+		cmd.Sources = nil
+		for _, name := range strings.Split(args[*i], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cmd.Sources = append(cmd.Sources, name)
+			}
+		}
+		if len(cmd.Sources) == 0 {
+			return true, errors.New("--sources requires a comma-separated list of source names")
+		}
+	case "helper":
+		if *i+1 >= len(args) {
+			return true, ErrMissingFlagValue
+		}
+		*i++
+		cmd.Helper = args[*i]
+	case "helper-timeout":
+		if *i+1 >= len(args) {
+			return true, ErrMissingFlagValue
+		}
+		*i++
+		timeout, err := time.ParseDuration(args[*i])
+		if err != nil || timeout <= 0 {
+			return true, errors.New("--helper-timeout requires a positive duration (e.g., 5s)")
+		}
+		cmd.HelperTimeout = timeout
+	case "helper-on-error":
+		if *i+1 >= len(args) {
+			return true, ErrMissingFlagValue
+		}
+		*i++
+		if args[*i] != "fail" && args[*i] != "ignore" {
+			return true, errors.New("--helper-on-error must be 'fail' or 'ignore'")
+		}
+		cmd.HelperOnError = args[*i]
+	case "vault-addr":
+		if *i+1 >= len(args) {
+			return true, ErrMissingFlagValue
+		}
+		*i++
+		cmd.VaultAddr = args[*i]
+	case "vault-token-file":
+		if *i+1 >= len(args) {
+			return true, ErrMissingFlagValue
+		}
+		*i++
+		cmd.VaultTokenFile = args[*i]
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// parseExplainArgs parses arguments for the explain subcommand.
+func parseExplainArgs(args []string, cmd Command) (Command, error) {
+	i := 0
+
+	for i < len(args) {
+		arg := args[i]
+
+		if strings.HasPrefix(arg, "--") {
+			flagName := strings.TrimPrefix(arg, "--")
+			switch flagName {
+			case "json":
+				cmd.JSONOutput = true
+			case "schema":
+				if i+1 >= len(args) {
+					return Command{}, ErrMissingFlagValue
+				}
+				i++
+				cmd.SchemaPath = args[i]
+			default:
+				if _, err := parseSourceFlag(flagName, args, &i, &cmd); err != nil {
+					return Command{}, err
+				}
+				// Unknown flag
+			}
+			i++
+			continue
+		}
+
+		// Not a flag - this is the config key
+		if cmd.ExplainKey == "" {
+			cmd.ExplainKey = arg
+		}
+		i++
+	}
+
+	// Explain requires a config key
+	if cmd.ExplainKey == "" {
+		return Command{}, errors.New("explain requires a config key: usage: admit explain <key>")
+	}
+
+	return cmd, nil
+}
+
+// parseEncryptArgs parses arguments for the encrypt subcommand.
+func parseEncryptArgs(args []string, cmd Command) (Command, error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch arg {
+		case "--key-file":
+			if i+1 >= len(args) {
+				return Command{}, ErrMissingFlagValue
+			}
+			i++
+			cmd.KeyFile = args[i]
+		case "--name":
+			if i+1 >= len(args) {
+				return Command{}, ErrMissingFlagValue
+			}
+			i++
+			cmd.EncryptName = args[i]
+		case "--generate-key":
+			cmd.GenerateKey = true
+		default:
+			if strings.HasPrefix(arg, "--") {
+				return Command{}, errors.New("unknown encrypt flag: " + arg)
+			}
+			if cmd.EncryptValue != "" {
+				return Command{}, errors.New("encrypt takes a single value: usage: admit encrypt --name <VAR> [--key-file <path>] [value]")
+			}
+			cmd.EncryptValue = arg
+		}
+	}
+
+	if cmd.GenerateKey {
+		if cmd.EncryptValue != "" || cmd.EncryptName != "" {
+			return Command{}, errors.New("--generate-key does not take a value")
+		}
+	} else if cmd.EncryptName == "" {
+		return Command{}, errors.New("encrypt requires the variable name: usage: admit encrypt --name <VAR> [--key-file <path>] [value]")
+	}
+
+	return cmd, nil
+}
+
+// parseExplainCodeArgs parses arguments for the explain-code subcommand.
+func parseExplainCodeArgs(args []string, cmd Command) (Command, error) {
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			cmd.JSONOutput = true
+		default:
+			if strings.HasPrefix(arg, "--") {
+				return Command{}, errors.New("unknown explain-code flag: " + arg)
+			}
+			if cmd.ExplainCode != "" {
+				return Command{}, errors.New("explain-code takes a single code: usage: admit explain-code [--json] [code]")
+			}
+			cmd.ExplainCode = strings.ToUpper(arg)
+		}
+	}
+
+	return cmd, nil
+}
+
+// parseInvariantsArgs parses arguments for the invariants subcommand.
+func parseInvariantsArgs(args []string, cmd Command) (Command, error) {
+	if len(args) == 0 {
+		return Command{}, errors.New("invariants requires an action: usage: admit invariants analyze [--schema <path>] [--json]")
+	}
+	if args[0] != "analyze" {
+		return Command{}, errors.New("unknown invariants action: usage: admit invariants analyze [--schema <path>] [--json]")
+	}
+	cmd.InvariantsAction = args[0]
+
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--json":
+			cmd.JSONOutput = true
+		case "--schema":
+			if i+1 >= len(args) {
+				return Command{}, ErrMissingFlagValue
+			}
+			i++
+			cmd.SchemaPath = args[i]
+		default:
+			return Command{}, errors.New("unknown invariants flag: " + args[i])
+		}
+	}
+
+	return cmd, nil
+}
+
+// parseTestArgs parses arguments for the test subcommand.
+func parseTestArgs(args []string, cmd Command) (Command, error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch arg {
+		case "--schema", "--junit":
+			if i+1 >= len(args) {
+				return Command{}, ErrMissingFlagValue
+			}
+			i++
+			if arg == "--schema" {
+				cmd.SchemaPath = args[i]
+			} else {
+				cmd.JUnitPath = args[i]
+			}
+		case "--warnings-as-errors":
+			cmd.WarningsAsErrors = true
+		case "--interpolate":
+			cmd.Interpolate = true
+		default:
+			if strings.HasPrefix(arg, "--") {
+				return Command{}, errors.New("unknown test flag: " + arg)
+			}
+			if cmd.TestFile != "" {
+				return Command{}, errors.New("test takes a single fixtures file: usage: admit test [--schema <path>] [--junit <path>] [fixtures]")
+			}
+			cmd.TestFile = arg
+		}
+	}
+
+	return cmd, nil
+}
@@ -1,7 +1,9 @@
 package cli
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
@@ -1580,3 +1582,462 @@ func TestParseArgs_V7EnvironmentSelection_Property(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+// TestParseArgs_V8EnvFileFlags tests parsing of --env-file and --env-file-override
+func TestParseArgs_V8EnvFileFlags(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantTarget   string
+		wantEnvFiles []string
+		wantOverride bool
+	}{
+		{
+			name:         "single env file",
+			args:         []string{"run", "--env-file", ".env", "echo"},
+			wantTarget:   "echo",
+			wantEnvFiles: []string{".env"},
+		},
+		{
+			name:         "repeated env files keep order",
+			args:         []string{"run", "--env-file", ".env", "--env-file", ".env.local", "node", "app.js"},
+			wantTarget:   "node",
+			wantEnvFiles: []string{".env", ".env.local"},
+		},
+		{
+			name:         "env file with override",
+			args:         []string{"check", "--env-file", ".env", "--env-file-override"},
+			wantEnvFiles: []string{".env"},
+			wantOverride: true,
+		},
+		{
+			name:       "no env files",
+			args:       []string{"run", "echo"},
+			wantTarget: "echo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := ParseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cmd.Target != tt.wantTarget {
+				t.Errorf("Target = %q, want %q", cmd.Target, tt.wantTarget)
+			}
+			if len(cmd.EnvFiles) != len(tt.wantEnvFiles) {
+				t.Fatalf("EnvFiles = %v, want %v", cmd.EnvFiles, tt.wantEnvFiles)
+			}
+			for i := range cmd.EnvFiles {
+				if cmd.EnvFiles[i] != tt.wantEnvFiles[i] {
+					t.Errorf("EnvFiles[%d] = %q, want %q", i, cmd.EnvFiles[i], tt.wantEnvFiles[i])
+				}
+			}
+			if cmd.EnvFileOverride != tt.wantOverride {
+				t.Errorf("EnvFileOverride = %v, want %v", cmd.EnvFileOverride, tt.wantOverride)
+			}
+		})
+	}
+}
+
+// TestParseArgs_V8SecretFileFlags tests parsing of --secret-files and --secret-file-max-size
+func TestParseArgs_V8SecretFileFlags(t *testing.T) {
+	cmd, err := ParseArgs([]string{"run", "--secret-files", "--secret-file-max-size", "1024", "node", "app.js"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cmd.SecretFiles {
+		t.Error("SecretFiles = false, want true")
+	}
+	if cmd.SecretFileMax != 1024 {
+		t.Errorf("SecretFileMax = %d, want 1024", cmd.SecretFileMax)
+	}
+	if cmd.Target != "node" {
+		t.Errorf("Target = %q, want %q", cmd.Target, "node")
+	}
+
+	cmd, err = ParseArgs([]string{"run", "echo"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.SecretFiles || cmd.SecretFileMax != 0 {
+		t.Errorf("expected secret file flags unset, got %v/%d", cmd.SecretFiles, cmd.SecretFileMax)
+	}
+}
+
+// TestParseArgs_V8ConfigDirFlags tests parsing of --config-dir and --config-dir-max-size
+func TestParseArgs_V8ConfigDirFlags(t *testing.T) {
+	cmd, err := ParseArgs([]string{"run", "--config-dir", "/etc/config", "--config-dir", "/etc/secrets", "--config-dir-max-size", "2048", "node", "app.js"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cmd.ConfigDirs, []string{"/etc/config", "/etc/secrets"}) {
+		t.Errorf("ConfigDirs = %v", cmd.ConfigDirs)
+	}
+	if cmd.ConfigDirMax != 2048 {
+		t.Errorf("ConfigDirMax = %d, want 2048", cmd.ConfigDirMax)
+	}
+	if cmd.Target != "node" {
+		t.Errorf("Target = %q, want %q", cmd.Target, "node")
+	}
+
+	cmd, err = ParseArgs([]string{"explain", "--config-dir", "/etc/config", "db.url"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cmd.ConfigDirs, []string{"/etc/config"}) || cmd.ExplainKey != "db.url" {
+		t.Errorf("explain ConfigDirs = %v, key = %q", cmd.ConfigDirs, cmd.ExplainKey)
+	}
+}
+
+// TestParseArgs_V8ConfigFileFlag tests parsing of --config-file
+func TestParseArgs_V8ConfigFileFlag(t *testing.T) {
+	cmd, err := ParseArgs([]string{"check", "--config-file", "base.yaml", "--config-file", "local.toml", "--json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cmd.ConfigFiles, []string{"base.yaml", "local.toml"}) {
+		t.Errorf("ConfigFiles = %v", cmd.ConfigFiles)
+	}
+	if !cmd.JSONOutput {
+		t.Error("JSONOutput = false, want true")
+	}
+}
+
+// TestParseArgs_V8SourcesFlag tests parsing of --sources
+func TestParseArgs_V8SourcesFlag(t *testing.T) {
+	cmd, err := ParseArgs([]string{"run", "--sources", "config-file, env,env-file", "echo"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cmd.Sources, []string{"config-file", "env", "env-file"}) {
+		t.Errorf("Sources = %v", cmd.Sources)
+	}
+	if cmd.Target != "echo" {
+		t.Errorf("Target = %q, want %q", cmd.Target, "echo")
+	}
+}
+
+// TestParseArgs_V8HelperFlags tests parsing of --helper, --helper-timeout and --helper-on-error
+func TestParseArgs_V8HelperFlags(t *testing.T) {
+	cmd, err := ParseArgs([]string{"run", "--helper", "./fetch-secrets", "--helper-timeout", "3s", "--helper-on-error", "ignore", "echo"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.Helper != "./fetch-secrets" || cmd.HelperTimeout != 3*time.Second || cmd.HelperOnError != "ignore" {
+		t.Errorf("Helper = %q, HelperTimeout = %v, HelperOnError = %q", cmd.Helper, cmd.HelperTimeout, cmd.HelperOnError)
+	}
+
+	cmd, err = ParseArgs([]string{"explain", "--helper", "fetch", "db.password"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.Helper != "fetch" || cmd.ExplainKey != "db.password" {
+		t.Errorf("explain: Helper = %q, ExplainKey = %q", cmd.Helper, cmd.ExplainKey)
+	}
+}
+
+// TestParseArgs_V8VaultFlags tests parsing of --vault-addr and --vault-token-file
+func TestParseArgs_V8VaultFlags(t *testing.T) {
+	cmd, err := ParseArgs([]string{"check", "--vault-addr", "https://vault:8200", "--vault-token-file", "/run/vault/token"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.VaultAddr != "https://vault:8200" || cmd.VaultTokenFile != "/run/vault/token" {
+		t.Errorf("VaultAddr = %q, VaultTokenFile = %q", cmd.VaultAddr, cmd.VaultTokenFile)
+	}
+}
+
+// TestParseArgs_V8InterpolateFlag tests parsing of --interpolate
+func TestParseArgs_V8InterpolateFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{args: []string{"run", "--interpolate", "echo"}, want: true},
+		{args: []string{"check", "--interpolate"}, want: true},
+		{args: []string{"explain", "--interpolate", "db.url"}, want: true},
+		{args: []string{"run", "echo", "--interpolate"}, want: false},
+	}
+
+	for _, tt := range tests {
+		cmd, err := ParseArgs(tt.args)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.args, err)
+		}
+		if cmd.Interpolate != tt.want {
+			t.Errorf("%v: Interpolate = %v, want %v", tt.args, cmd.Interpolate, tt.want)
+		}
+	}
+}
+
+// TestParseArgs_V8FlagErrors tests error cases for v8 flags
+func TestParseArgs_V8FlagErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "env-file without value", args: []string{"run", "--env-file"}},
+		{name: "secret-file-max-size without value", args: []string{"run", "--secret-file-max-size"}},
+		{name: "secret-file-max-size not a number", args: []string{"run", "--secret-file-max-size", "big", "echo"}},
+		{name: "secret-file-max-size zero", args: []string{"run", "--secret-file-max-size", "0", "echo"}},
+		{name: "config-dir without value", args: []string{"run", "--config-dir"}},
+		{name: "config-file without value", args: []string{"run", "--config-file"}},
+		{name: "sources without value", args: []string{"run", "--sources"}},
+		{name: "sources empty list", args: []string{"run", "--sources", " , ", "echo"}},
+		{name: "helper without value", args: []string{"run", "--helper"}},
+		{name: "vault-addr without value", args: []string{"run", "--vault-addr"}},
+		{name: "vault-token-file without value", args: []string{"run", "--vault-token-file"}},
+		{name: "helper-timeout not a duration", args: []string{"run", "--helper-timeout", "soon", "echo"}},
+		{name: "helper-on-error unknown policy", args: []string{"run", "--helper-on-error", "retry", "echo"}},
+		{name: "config-dir-max-size not a number", args: []string{"run", "--config-dir-max-size", "big", "echo"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseArgs(tt.args); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+// TestParseArgs_V8ExplainSubcommand tests parsing of the explain subcommand
+func TestParseArgs_V8ExplainSubcommand(t *testing.T) {
+	cmd, err := ParseArgs([]string{"explain", "db.url", "--json", "--env-file", ".env", "--schema", "custom.yaml", "--secret-files"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.Subcommand != SubcommandExplain {
+		t.Errorf("Subcommand = %q, want %q", cmd.Subcommand, SubcommandExplain)
+	}
+	if cmd.ExplainKey != "db.url" {
+		t.Errorf("ExplainKey = %q, want %q", cmd.ExplainKey, "db.url")
+	}
+	if !cmd.JSONOutput || !cmd.SecretFiles || cmd.SchemaPath != "custom.yaml" {
+		t.Errorf("flags not parsed: %+v", cmd)
+	}
+	if len(cmd.EnvFiles) != 1 || cmd.EnvFiles[0] != ".env" {
+		t.Errorf("EnvFiles = %v, want [.env]", cmd.EnvFiles)
+	}
+
+	// Flags may come before the key
+	cmd, err = ParseArgs([]string{"explain", "--env-file-override", "payments.mode"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.ExplainKey != "payments.mode" || !cmd.EnvFileOverride {
+		t.Errorf("got key %q override %v", cmd.ExplainKey, cmd.EnvFileOverride)
+	}
+
+	// Key is required
+	if _, err := ParseArgs([]string{"explain", "--json"}); err == nil {
+		t.Error("expected error for explain without key")
+	}
+	if _, err := ParseArgs([]string{"explain", "db.url", "--env-file"}); err != ErrMissingFlagValue {
+		t.Errorf("error = %v, want %v", err, ErrMissingFlagValue)
+	}
+}
+
+// TestParseArgs_V8EncryptSubcommand tests parsing of the encrypt subcommand
+func TestParseArgs_V8EncryptSubcommand(t *testing.T) {
+	cmd, err := ParseArgs([]string{"encrypt", "--key-file", "admit.key", "--name", "DB_PASSWORD", "s3cret"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.Subcommand != SubcommandEncrypt || cmd.KeyFile != "admit.key" || cmd.EncryptName != "DB_PASSWORD" || cmd.EncryptValue != "s3cret" {
+		t.Errorf("got %+v", cmd)
+	}
+
+	// Without a value the value is read from stdin
+	cmd, err = ParseArgs([]string{"encrypt", "--name", "DB_PASSWORD"})
+	if err != nil || cmd.EncryptValue != "" || cmd.KeyFile != "" {
+		t.Errorf("got %+v, %v", cmd, err)
+	}
+
+	cmd, err = ParseArgs([]string{"encrypt", "--generate-key"})
+	if err != nil || !cmd.GenerateKey {
+		t.Errorf("got %+v, %v", cmd, err)
+	}
+
+	errorCases := [][]string{
+		{"encrypt", "--key-file"},
+		{"encrypt", "--name"},
+		{"encrypt", "a"},
+		{"encrypt", "--name", "A", "a", "b"},
+		{"encrypt", "--name", "A", "--verbose", "a"},
+		{"encrypt", "--generate-key", "a"},
+		{"encrypt", "--generate-key", "--name", "A"},
+	}
+	for _, args := range errorCases {
+		if _, err := ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%v): expected error, got nil", args)
+		}
+	}
+}
+
+// TestParseArgs_V9WarningsAsErrors tests the --warnings-as-errors flag
+func TestParseArgs_V9WarningsAsErrors(t *testing.T) {
+	cmd, err := ParseArgs([]string{"check", "--warnings-as-errors", "--json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cmd.WarningsAsErrors || !cmd.JSONOutput {
+		t.Errorf("WarningsAsErrors = %v, JSONOutput = %v", cmd.WarningsAsErrors, cmd.JSONOutput)
+	}
+
+	cmd, err = ParseArgs([]string{"run", "--warnings-as-errors", "node", "--warnings-as-errors"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cmd.WarningsAsErrors || cmd.Target != "node" || len(cmd.Args) != 1 {
+		t.Errorf("got %+v, want flag before the command parsed and the one after passed through", cmd)
+	}
+
+	cmd, _ = ParseArgs([]string{"run", "node"})
+	if cmd.WarningsAsErrors {
+		t.Error("WarningsAsErrors should default to false")
+	}
+}
+
+// TestParseArgs_V9Probe tests the --probe flag
+func TestParseArgs_V9Probe(t *testing.T) {
+	cmd, err := ParseArgs([]string{"check", "--probe", "--json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cmd.Probe || !cmd.JSONOutput {
+		t.Errorf("Probe = %v, JSONOutput = %v", cmd.Probe, cmd.JSONOutput)
+	}
+
+	cmd, _ = ParseArgs([]string{"run", "--dry-run", "node"})
+	if cmd.Probe {
+		t.Error("Probe should default to false")
+	}
+}
+
+// TestParseArgs_V9Explain tests the --explain flag
+func TestParseArgs_V9Explain(t *testing.T) {
+	cmd, err := ParseArgs([]string{"check", "--explain", "--invariants-json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cmd.ExplainInvariants || !cmd.InvariantsJSON {
+		t.Errorf("ExplainInvariants = %v, InvariantsJSON = %v", cmd.ExplainInvariants, cmd.InvariantsJSON)
+	}
+
+	cmd, _ = ParseArgs([]string{"run", "--explain", "node", "--explain"})
+	if !cmd.ExplainInvariants || cmd.Target != "node" || len(cmd.Args) != 1 {
+		t.Errorf("got %+v, want flag before the command parsed and the one after passed through", cmd)
+	}
+
+	cmd, _ = ParseArgs([]string{"check"})
+	if cmd.ExplainInvariants {
+		t.Error("ExplainInvariants should default to false")
+	}
+}
+
+// TestParseArgs_V9ExplainCodeSubcommand tests parsing of admit explain-code
+func TestParseArgs_V9ExplainCodeSubcommand(t *testing.T) {
+	cmd, err := ParseArgs([]string{"explain-code", "adm002", "--json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.Subcommand != SubcommandExplainCode || cmd.ExplainCode != "ADM002" || !cmd.JSONOutput {
+		t.Errorf("got %+v", cmd)
+	}
+
+	// Without a code all codes are listed
+	cmd, err = ParseArgs([]string{"explain-code"})
+	if err != nil || cmd.ExplainCode != "" {
+		t.Errorf("got %+v, %v", cmd, err)
+	}
+
+	for _, args := range [][]string{{"explain-code", "ADM001", "ADM002"}, {"explain-code", "--verbose"}} {
+		if _, err := ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%v): expected error, got nil", args)
+		}
+	}
+}
+
+// TestParseArgs_V9InvariantsAnalyze tests parsing of admit invariants analyze
+func TestParseArgs_V9InvariantsAnalyze(t *testing.T) {
+	cmd, err := ParseArgs([]string{"invariants", "analyze", "--schema", "admit.yaml", "--json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.Subcommand != SubcommandInvariants || cmd.InvariantsAction != "analyze" || cmd.SchemaPath != "admit.yaml" || !cmd.JSONOutput {
+		t.Errorf("got %+v", cmd)
+	}
+
+	errorCases := [][]string{
+		{"invariants"},
+		{"invariants", "solve"},
+		{"invariants", "analyze", "--schema"},
+		{"invariants", "analyze", "--verbose"},
+	}
+	for _, args := range errorCases {
+		if _, err := ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%v): expected error, got nil", args)
+		}
+	}
+}
+
+// TestParseArgs_V9Test tests parsing of admit test
+func TestParseArgs_V9Test(t *testing.T) {
+	cmd, err := ParseArgs([]string{"test", "--schema", "admit.yaml", "--junit", "report.xml", "--warnings-as-errors", "--interpolate", "cases.yaml"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.Subcommand != SubcommandTest || cmd.SchemaPath != "admit.yaml" || cmd.JUnitPath != "report.xml" || cmd.TestFile != "cases.yaml" || !cmd.WarningsAsErrors || !cmd.Interpolate {
+		t.Errorf("got %+v", cmd)
+	}
+
+	// Without an argument the default fixtures file is used
+	cmd, err = ParseArgs([]string{"test"})
+	if err != nil || cmd.TestFile != "" {
+		t.Errorf("got %+v, %v", cmd, err)
+	}
+
+	errorCases := [][]string{
+		{"test", "a.yaml", "b.yaml"},
+		{"test", "--junit"},
+		{"test", "--verbose"},
+	}
+	for _, args := range errorCases {
+		if _, err := ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%v): expected error, got nil", args)
+		}
+	}
+}
+
+// TestParseArgs_V9WaitFor tests the readiness flags
+func TestParseArgs_V9WaitFor(t *testing.T) {
+	cmd, err := ParseArgs([]string{"run", "--wait-for", "tcp://db:5432", "--wait-for", "file:/tmp/ready", "--wait-for-key", "cache.url", "--wait-timeout", "45s", "node", "server.js"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cmd.WaitFor, []string{"tcp://db:5432", "file:/tmp/ready"}) {
+		t.Errorf("WaitFor = %v", cmd.WaitFor)
+	}
+	if !reflect.DeepEqual(cmd.WaitForKeys, []string{"cache.url"}) || cmd.WaitTimeout != 45*time.Second {
+		t.Errorf("WaitForKeys = %v, WaitTimeout = %v", cmd.WaitForKeys, cmd.WaitTimeout)
+	}
+	if cmd.Target != "node" || len(cmd.Args) != 1 {
+		t.Errorf("Target = %q, Args = %v", cmd.Target, cmd.Args)
+	}
+
+	errorCases := [][]string{
+		{"run", "--wait-for", "db:5432", "node"},
+		{"run", "--wait-for"},
+		{"run", "--wait-for-key"},
+		{"run", "--wait-timeout", "0s", "node"},
+		{"run", "--wait-timeout", "soon", "node"},
+	}
+	for _, args := range errorCases {
+		if _, err := ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%v): expected error, got nil", args)
+		}
+	}
+}
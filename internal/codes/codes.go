@@ -0,0 +1,225 @@
+// Package codes provides v9 stable error codes for every kind of failure
+// admit reports, and the documentation shown by "admit explain-code".
+package codes
+
+import "sort"
+
+// Code is a stable, machine-readable identifier for a kind of failure.
+// Codes are never reused or renumbered; messages may change, codes do not.
+type Code string
+
+const (
+	RequiredMissing    Code = "ADM001" // Required config key is not set
+	InvalidEnum        Code = "ADM002" // Value is not one of the enum's values
+	InvariantViolated  Code = "ADM003" // Invariant rule evaluated to false
+	ContractNotAllowed Code = "ADM004" // Value is not in a contract's allow list
+	ContractDenied     Code = "ADM005" // Value matches a contract's deny rule
+	DriftAdded         Code = "ADM006" // Key set now but not in the baseline
+	DriftRemoved       Code = "ADM007" // Key in the baseline but not set now
+	DriftChanged       Code = "ADM008" // Value differs from the baseline
+	SchemaNotFound     Code = "ADM009" // Schema file does not exist
+	SchemaInvalidYAML  Code = "ADM010" // Schema is not valid YAML
+	SchemaInvalidKey   Code = "ADM011" // Config key definition is invalid
+	SchemaInvalidRule  Code = "ADM012" // Invariant definition is invalid
+	SchemaInvalidEnv   Code = "ADM013" // Environment contract is invalid
+	SchemaInvalidChain Code = "ADM014" // Source chain entry is invalid
+	VaultFailed        Code = "ADM015" // Vault secret could not be fetched
+	HelperFailed       Code = "ADM016" // Helper source could not be run or gave bad output
+	SecretFileFailed   Code = "ADM017" // Secret file or config directory file could not be read
+	ValueNotScalar     Code = "ADM018" // Config file or Vault value is not a scalar
+	PluginRejected     Code = "ADM019" // External validator rejected the value
+	PluginFailed       Code = "ADM020" // External validator could not be run or gave bad output
+	ProbeFailed        Code = "ADM021" // Dependency named by a value is not reachable
+	WaitTimeout        Code = "ADM022" // A --wait-for target was not ready before the deadline
+	InvalidType        Code = "ADM023" // Value does not have the key's int, float or duration format
+)
+
+// Info documents a code
+type Info struct {
+	Code        Code   `json:"code"`
+	Title       string `json:"title"`
+	Category    string `json:"category"` // "validation", "invariant", "contract", "drift", "schema", "source", "probe" or "wait"
+	ExitCode    int    `json:"exitCode"` // Exit code when the failure blocks (0 if it never does)
+	Description string `json:"description"`
+	Fix         string `json:"fix"`
+}
+
+// catalog documents every code
+var catalog = map[Code]Info{
+	RequiredMissing: {
+		Title:       "Required config key is not set",
+		Category:    "validation",
+		ExitCode:    1,
+		Description: "A key marked 'required: true' was not found in any source of the chain, under its env var or an alias, and has no default.",
+		Fix:         "Set the key's env var (e.g. DB_URL for db.url), add it to an env file or config source, or give the key a 'default'.",
+	},
+	InvalidEnum: {
+		Title:       "Invalid enum value",
+		Category:    "validation",
+		ExitCode:    1,
+		Description: "An enum key's value is not one of the 'values' listed in the schema. Matching is exact and case-sensitive.",
+		Fix:         "Use one of the allowed values, or add the value to the key's 'values' list.",
+	},
+	InvariantViolated: {
+		Title:       "Invariant violated",
+		Category:    "invariant",
+		ExitCode:    2,
+		Description: "An invariant's rule evaluated to false for the resolved config. For an implication (A => B), A was true and B was false.",
+		Fix:         "Change the config so the rule holds; the report shows the values the rule was evaluated with.",
+	},
+	ContractNotAllowed: {
+		Title:       "Value not allowed by environment contract",
+		Category:    "contract",
+		ExitCode:    5,
+		Description: "The environment's contract has an allow rule for the key, and the value is not in its list.",
+		Fix:         "Use an allowed value for this environment, or run with the intended environment (--env or ADMIT_ENV).",
+	},
+	ContractDenied: {
+		Title:       "Value denied by environment contract",
+		Category:    "contract",
+		ExitCode:    5,
+		Description: "The value matches one of the deny rule's values or '*' glob patterns for the environment.",
+		Fix:         "Use a value that no deny pattern matches, or run with the intended environment (--env or ADMIT_ENV).",
+	},
+	DriftAdded: {
+		Title:       "Config key added since baseline",
+		Category:    "drift",
+		Description: "The key has a value now but was not set when the baseline was recorded. Drift never blocks execution.",
+		Fix:         "If the change is intended, record a new baseline with --baseline.",
+	},
+	DriftRemoved: {
+		Title:       "Config key removed since baseline",
+		Category:    "drift",
+		Description: "The key was set when the baseline was recorded but has no value now. Drift never blocks execution.",
+		Fix:         "If the change is intended, record a new baseline with --baseline.",
+	},
+	DriftChanged: {
+		Title:       "Config value changed since baseline",
+		Category:    "drift",
+		Description: "The key's value differs from the one recorded in the baseline. Drift never blocks execution.",
+		Fix:         "If the change is intended, record a new baseline with --baseline.",
+	},
+	SchemaNotFound: {
+		Title:       "Schema file not found",
+		Category:    "schema",
+		ExitCode:    3,
+		Description: "No schema exists at the path from --schema, ADMIT_SCHEMA or ./admit.yaml.",
+		Fix:         "Create admit.yaml or point --schema / ADMIT_SCHEMA at the schema file.",
+	},
+	SchemaInvalidYAML: {
+		Title:       "Schema is not valid YAML",
+		Category:    "schema",
+		ExitCode:    3,
+		Description: "The schema file could not be parsed as YAML, or a section has the wrong shape.",
+		Fix:         "Fix the syntax at the line reported in the message.",
+	},
+	SchemaInvalidKey: {
+		Title:       "Invalid config key definition",
+		Category:    "schema",
+		ExitCode:    3,
+		Description: "A 'config' entry is invalid: an unknown type, an enum without values, a default outside the enum, an empty alias, an incomplete vault stanza, a validate_with without a command or with an invalid timeout, an invalid probe, or an unknown severity.",
+		Fix:         "Correct the entry named in the message.",
+	},
+	SchemaInvalidRule: {
+		Title:       "Invalid invariant definition",
+		Category:    "schema",
+		ExitCode:    3,
+		Description: "An 'invariants' entry is invalid: a missing or duplicate name, a missing rule, a rule that does not parse or references an unknown key, or an unknown severity.",
+		Fix:         "Correct the invariant named in the message.",
+	},
+	SchemaInvalidEnv: {
+		Title:       "Invalid environment contract",
+		Category:    "schema",
+		ExitCode:    3,
+		Description: "An 'environments' entry is invalid: a rule without values or with an unknown severity.",
+		Fix:         "Correct the rule named in the message.",
+	},
+	SchemaInvalidChain: {
+		Title:       "Invalid source chain entry",
+		Category:    "schema",
+		ExitCode:    3,
+		Description: "A 'sources' entry is invalid: a missing or duplicate type, an invalid timeout or negative retries.",
+		Fix:         "Correct the source named in the message.",
+	},
+	VaultFailed: {
+		Title:       "Vault secret could not be fetched",
+		Category:    "source",
+		ExitCode:    6,
+		Description: "Reading a key's secret from Vault failed after any retries: there was no token, the request failed or timed out, the server answered with an error status, or the secret does not exist.",
+		Fix:         "Check VAULT_ADDR or --vault-addr, the token (VAULT_TOKEN or --vault-token-file) and the key's vault path; the message gives the server's answer.",
+	},
+	HelperFailed: {
+		Title:       "Helper source failed",
+		Category:    "source",
+		ExitCode:    1,
+		Description: "The helper executable could not be started, timed out, exited with a non-zero status (with on_error: fail) or did not print a JSON object of key/value strings. No key was read from it.",
+		Fix:         "Run the helper by hand with the JSON list of keys on stdin, fix it, or raise its timeout; the message includes its stderr.",
+	},
+	SecretFileFailed: {
+		Title:       "Secret file could not be read",
+		Category:    "source",
+		ExitCode:    1,
+		Description: "A file named by a <VAR>_FILE variable (--secret-files) or found in a config directory is missing, is not a regular file, is world-writable or exceeds the size limit.",
+		Fix:         "Point the variable at a readable regular file, remove write access for others (chmod o-w), or raise the size limit.",
+	},
+	ValueNotScalar: {
+		Title:       "Source value is not a scalar",
+		Category:    "source",
+		ExitCode:    1,
+		Description: "A config file entry or Vault field for a key holds a list or mapping rather than a string, number or boolean.",
+		Fix:         "Store a single value at the key's path, or point the key at a nested path that holds one.",
+	},
+	PluginRejected: {
+		Title:       "Value rejected by external validator",
+		Category:    "validation",
+		ExitCode:    1,
+		Description: "The key's validate_with executable answered {\"ok\": false}. The message is the one it returned.",
+		Fix:         "Change the value so the validator accepts it; the validator's message says why it did not.",
+	},
+	PluginFailed: {
+		Title:       "External validator failed",
+		Category:    "validation",
+		ExitCode:    1,
+		Description: "The key's validate_with executable could not be started, timed out, exited with a non-zero status or did not print a {\"ok\", \"message\"} JSON object. The value was not checked.",
+		Fix:         "Run the validator by hand with {\"key\", \"value\", \"env\"} on stdin, fix it, or raise its timeout in validate_with.",
+	},
+	ProbeFailed: {
+		Title:       "Dependency not reachable",
+		Category:    "probe",
+		ExitCode:    7,
+		Description: "A key's probe failed on every attempt: the tcp host:port refused or timed out, the unix socket is missing or not accepting connections, or the http URL did not answer with a 2xx or 3xx status.",
+		Fix:         "Check that the value points at the right host, port or socket and that the dependency is up; raise the probe's timeout or retries if it starts slowly.",
+	},
+	WaitTimeout: {
+		Title:       "Wait target not ready",
+		Category:    "wait",
+		ExitCode:    8,
+		Description: "A --wait-for or --wait-for-key target did not become ready before the --wait-timeout deadline: the tcp host:port never accepted a connection or the file never appeared. The command was not executed.",
+		Fix:         "Check that the dependency is starting and that the target is right; raise --wait-timeout if it needs longer to come up.",
+	},
+	InvalidType: {
+		Title:       "Value does not match the key's type",
+		Category:    "validation",
+		ExitCode:    1,
+		Description: "The value of an int, float or duration key cannot be parsed as that type. Ints are base-10 integers, floats are decimal numbers, and durations are Go durations such as 500ms or 1h30m.",
+		Fix:         "Set a value of the key's type (e.g. POOL_MAX=20, TIMEOUT=30s), or change the key's 'type' in the schema.",
+	},
+}
+
+// Lookup returns the documentation for a code
+func Lookup(code Code) (Info, bool) {
+	info, ok := catalog[code]
+	info.Code = code
+	return info, ok
+}
+
+// All returns the documentation for every code, in code order
+func All() []Info {
+	all := make([]Info, 0, len(catalog))
+	for code := range catalog {
+		info, _ := Lookup(code)
+		all = append(all, info)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Code < all[j].Code })
+	return all
+}
@@ -0,0 +1,31 @@
+package codes
+
+import "testing"
+
+func TestCatalog(t *testing.T) {
+	all := All()
+	if len(all) != len(catalog) {
+		t.Fatalf("All() returned %d codes, want %d", len(all), len(catalog))
+	}
+	for i, info := range all {
+		if i > 0 && all[i-1].Code >= info.Code {
+			t.Errorf("All() not in code order at %s", info.Code)
+		}
+		if info.Title == "" || info.Category == "" || info.Description == "" || info.Fix == "" {
+			t.Errorf("%s is not fully documented: %+v", info.Code, info)
+		}
+		if info.Category == "drift" && info.ExitCode != 0 {
+			t.Errorf("%s: drift never blocks, got exit code %d", info.Code, info.ExitCode)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	info, ok := Lookup(InvalidEnum)
+	if !ok || info.Code != "ADM002" || info.Category != "validation" || info.ExitCode != 1 {
+		t.Errorf("Lookup(InvalidEnum) = %+v, %v", info, ok)
+	}
+	if _, ok := Lookup("ADM999"); ok {
+		t.Error("Lookup(ADM999) found an undefined code")
+	}
+}
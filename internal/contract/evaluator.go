@@ -1,16 +1,22 @@
 package contract
 
-import "strings"
+import (
+	"strings"
+
+	"admit/internal/codes"
+)
 
 // Evaluate checks resolved config against an environment contract.
 // Returns EvalResult with all violations (does not short-circuit).
 // Deny rules take precedence over allow rules.
+// Violations of rules with severity "warn" are collected as warnings and do not fail the contract.
 // Keys not mentioned in the contract pass without violation.
 func Evaluate(c Contract, configValues map[string]string) EvalResult {
 	result := EvalResult{
 		Environment: c.Name,
 		Passed:      true,
 		Violations:  []Violation{},
+		Warnings:    []Violation{},
 	}
 
 	// Check all config values against contract rules
@@ -18,17 +24,17 @@ func Evaluate(c Contract, configValues map[string]string) EvalResult {
 		// Check deny rules first (deny takes precedence)
 		if denyRule, hasDeny := c.Deny[key]; hasDeny {
 			if violation := CheckDenyRule(key, value, denyRule); violation != nil {
-				result.Violations = append(result.Violations, *violation)
-				result.Passed = false
-				continue // Deny violation found, skip allow check for this key
+				result.add(*violation)
+				if !violation.Severity.IsWarning() {
+					continue // Deny violation found, skip allow check for this key
+				}
 			}
 		}
 
-		// Check allow rules (only if no deny violation)
+		// Check allow rules (only if no blocking deny violation)
 		if allowRule, hasAllow := c.Allow[key]; hasAllow {
 			if violation := CheckAllowRule(key, value, allowRule); violation != nil {
-				result.Violations = append(result.Violations, *violation)
-				result.Passed = false
+				result.add(*violation)
 			}
 		}
 		// Keys not mentioned in contract pass without violation
@@ -37,6 +43,16 @@ func Evaluate(c Contract, configValues map[string]string) EvalResult {
 	return result
 }
 
+// add records a violation as blocking or as a warning, by its severity
+func (r *EvalResult) add(v Violation) {
+	if v.Severity.IsWarning() {
+		r.Warnings = append(r.Warnings, v)
+		return
+	}
+	r.Violations = append(r.Violations, v)
+	r.Passed = false
+}
+
 // MatchGlob checks if a value matches a glob pattern.
 // Supports * wildcard matching any sequence of characters.
 // Patterns without * only match exact strings.
@@ -100,10 +116,12 @@ func CheckAllowRule(key string, value string, rule Rule) *Violation {
 
 	// Value not in allow list - violation
 	return &Violation{
+		Code:           codes.ContractNotAllowed,
 		Key:            key,
 		ActualValue:    value,
 		RuleType:       "allow",
 		ExpectedValues: rule.Values,
+		Severity:       rule.Severity,
 	}
 }
 
@@ -120,11 +138,13 @@ func CheckDenyRule(key string, value string, rule Rule) *Violation {
 
 		if matches {
 			return &Violation{
+				Code:           codes.ContractDenied,
 				Key:            key,
 				ActualValue:    value,
 				RuleType:       "deny",
 				ExpectedValues: rule.Values,
 				Pattern:        pattern,
+				Severity:       rule.Severity,
 			}
 		}
 	}
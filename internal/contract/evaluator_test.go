@@ -4,6 +4,9 @@ import (
 	"strings"
 	"testing"
 
+	"admit/internal/codes"
+	"admit/internal/severity"
+
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
 	"github.com/leanovate/gopter/prop"
@@ -694,3 +697,72 @@ func TestProperty9_AllViolationsReported(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+func TestEvaluate_WarnSeverity(t *testing.T) {
+	c := Contract{
+		Name: "prod",
+		Allow: map[string]Rule{
+			"log.format":    {Values: []string{"json"}, Severity: severity.Warn},
+			"payments.mode": {Values: []string{"live"}},
+		},
+		Deny: map[string]Rule{
+			"payments.mode": {Values: []string{"test"}, Severity: severity.Warn},
+		},
+	}
+
+	result := Evaluate(c, map[string]string{"log.format": "text", "payments.mode": "live"})
+	if !result.Passed || len(result.Violations) != 0 || len(result.Warnings) != 1 {
+		t.Fatalf("result = %+v, want passed with one warning", result)
+	}
+	if w := result.Warnings[0]; w.Key != "log.format" || !w.Severity.IsWarning() {
+		t.Errorf("warning = %+v", w)
+	}
+
+	// A warn deny rule does not stop the blocking allow rule from being checked
+	result = Evaluate(c, map[string]string{"payments.mode": "test"})
+	if result.Passed || len(result.Violations) != 1 || result.Violations[0].RuleType != "allow" {
+		t.Errorf("violations = %+v, want the allow rule to block", result.Violations)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].RuleType != "deny" {
+		t.Errorf("warnings = %+v, want the deny rule as a warning", result.Warnings)
+	}
+
+	cli := FormatCLIWarnings(result)
+	if !strings.Contains(cli, "Contract warnings for environment 'prod'") || !strings.Contains(cli, "1 warning(s)") {
+		t.Errorf("FormatCLIWarnings() = %q", cli)
+	}
+	ci := FormatCIWarnings(result)
+	if !strings.Contains(ci, "::warning file=admit.yaml::Contract violation: payments.mode has forbidden value 'test'") {
+		t.Errorf("FormatCIWarnings() = %q", ci)
+	}
+	if FormatCLIWarnings(EvalResult{Passed: true}) != "" || FormatCIWarnings(EvalResult{Passed: true}) != "" {
+		t.Error("warning formatters should be empty without warnings")
+	}
+}
+
+func TestEvaluate_Codes(t *testing.T) {
+	c := Contract{
+		Name:  "prod",
+		Allow: map[string]Rule{"payments.mode": {Values: []string{"live"}}},
+		Deny:  map[string]Rule{"db.url": {Values: []string{"*localhost*"}, IsGlob: true}},
+	}
+
+	result := Evaluate(c, map[string]string{"payments.mode": "test", "db.url": "postgres://localhost/db"})
+	if len(result.Violations) != 2 {
+		t.Fatalf("violations = %+v, want 2", result.Violations)
+	}
+	for _, v := range result.Violations {
+		want := codes.ContractNotAllowed
+		if v.RuleType == "deny" {
+			want = codes.ContractDenied
+		}
+		if v.Code != want {
+			t.Errorf("%s %s violation code = %q, want %q", v.Key, v.RuleType, v.Code, want)
+		}
+	}
+
+	output, err := FormatJSON(result)
+	if err != nil || !strings.Contains(output, `"Code": "ADM004"`) || !strings.Contains(output, `"Code": "ADM005"`) {
+		t.Errorf("FormatJSON() = %s, %v; want both codes", output, err)
+	}
+}
@@ -17,26 +17,48 @@ func FormatCLI(result EvalResult) string {
 	sb.WriteString(fmt.Sprintf("❌ Contract violations for environment '%s':\n\n", result.Environment))
 
 	for _, v := range result.Violations {
-		sb.WriteString(fmt.Sprintf("  Key: %s\n", v.Key))
-		sb.WriteString(fmt.Sprintf("  Value: %s\n", v.ActualValue))
-		sb.WriteString(fmt.Sprintf("  Rule: %s\n", v.RuleType))
-
-		if v.RuleType == "allow" {
-			sb.WriteString(fmt.Sprintf("  Expected: %s\n", formatValues(v.ExpectedValues)))
-		} else if v.RuleType == "deny" {
-			if v.Pattern != "" {
-				sb.WriteString(fmt.Sprintf("  Forbidden: %s (matched pattern: %s)\n", formatValues(v.ExpectedValues), v.Pattern))
-			} else {
-				sb.WriteString(fmt.Sprintf("  Forbidden: %s\n", formatValues(v.ExpectedValues)))
-			}
-		}
-		sb.WriteString("\n")
+		writeViolation(&sb, v)
 	}
 
 	sb.WriteString(fmt.Sprintf("Execution blocked: %d violation(s)\n", len(result.Violations)))
 	return sb.String()
 }
 
+// FormatCLIWarnings formats violations of "warn" rules for terminal output.
+func FormatCLIWarnings(result EvalResult) string {
+	if len(result.Warnings) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("⚠️  Contract warnings for environment '%s':\n\n", result.Environment))
+
+	for _, v := range result.Warnings {
+		writeViolation(&sb, v)
+	}
+
+	sb.WriteString(fmt.Sprintf("Not blocking: %d warning(s)\n", len(result.Warnings)))
+	return sb.String()
+}
+
+// writeViolation writes the terminal description of a single violation
+func writeViolation(sb *strings.Builder, v Violation) {
+	sb.WriteString(fmt.Sprintf("  Key: %s\n", v.Key))
+	sb.WriteString(fmt.Sprintf("  Value: %s\n", v.ActualValue))
+	sb.WriteString(fmt.Sprintf("  Rule: %s\n", v.RuleType))
+
+	if v.RuleType == "allow" {
+		sb.WriteString(fmt.Sprintf("  Expected: %s\n", formatValues(v.ExpectedValues)))
+	} else if v.RuleType == "deny" {
+		if v.Pattern != "" {
+			sb.WriteString(fmt.Sprintf("  Forbidden: %s (matched pattern: %s)\n", formatValues(v.ExpectedValues), v.Pattern))
+		} else {
+			sb.WriteString(fmt.Sprintf("  Forbidden: %s\n", formatValues(v.ExpectedValues)))
+		}
+	}
+	sb.WriteString("\n")
+}
+
 // FormatCI formats violations as GitHub Actions error annotations.
 func FormatCI(result EvalResult) string {
 	if result.Passed || len(result.Violations) == 0 {
@@ -46,20 +68,7 @@ func FormatCI(result EvalResult) string {
 	var sb strings.Builder
 
 	for _, v := range result.Violations {
-		var msg string
-		if v.RuleType == "allow" {
-			msg = fmt.Sprintf("Contract violation: %s has value '%s', expected one of: %s",
-				v.Key, v.ActualValue, formatValues(v.ExpectedValues))
-		} else if v.RuleType == "deny" {
-			if v.Pattern != "" {
-				msg = fmt.Sprintf("Contract violation: %s has forbidden value '%s' (matched pattern: %s)",
-					v.Key, v.ActualValue, v.Pattern)
-			} else {
-				msg = fmt.Sprintf("Contract violation: %s has forbidden value '%s'",
-					v.Key, v.ActualValue)
-			}
-		}
-		sb.WriteString(fmt.Sprintf("::error file=admit.yaml::%s\n", msg))
+		sb.WriteString(fmt.Sprintf("::error file=admit.yaml::%s\n", FormatMessage(v)))
 	}
 
 	sb.WriteString(fmt.Sprintf("\n❌ Contract violations for environment '%s': %d violation(s)\n",
@@ -67,6 +76,37 @@ func FormatCI(result EvalResult) string {
 	return sb.String()
 }
 
+// FormatCIWarnings formats violations of "warn" rules as GitHub Actions warning annotations.
+func FormatCIWarnings(result EvalResult) string {
+	if len(result.Warnings) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	for _, v := range result.Warnings {
+		sb.WriteString(fmt.Sprintf("::warning file=admit.yaml::%s\n", FormatMessage(v)))
+	}
+
+	sb.WriteString(fmt.Sprintf("\n⚠️  Contract warnings for environment '%s': %d warning(s)\n",
+		result.Environment, len(result.Warnings)))
+	return sb.String()
+}
+
+// FormatMessage formats a single violation as a one-line message.
+func FormatMessage(v Violation) string {
+	if v.RuleType == "allow" {
+		return fmt.Sprintf("Contract violation: %s has value '%s', expected one of: %s",
+			v.Key, v.ActualValue, formatValues(v.ExpectedValues))
+	}
+	if v.Pattern != "" {
+		return fmt.Sprintf("Contract violation: %s has forbidden value '%s' (matched pattern: %s)",
+			v.Key, v.ActualValue, v.Pattern)
+	}
+	return fmt.Sprintf("Contract violation: %s has forbidden value '%s'",
+		v.Key, v.ActualValue)
+}
+
 // FormatJSON formats violations as JSON.
 func FormatJSON(result EvalResult) (string, error) {
 	data, err := json.MarshalIndent(result, "", "  ")
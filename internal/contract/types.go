@@ -1,5 +1,10 @@
 package contract
 
+import (
+	"admit/internal/codes"
+	"admit/internal/severity"
+)
+
 // Contract represents an environment contract that defines
 // allowed and denied configuration states for a named environment.
 type Contract struct {
@@ -12,6 +17,8 @@ type Contract struct {
 type Rule struct {
 	Values []string // Exact values (for allow) or patterns (for deny)
 	IsGlob bool     // Whether values contain glob patterns (deny only)
+
+	Severity severity.Level // Whether a violation blocks (zero value) or only warns
 }
 
 // Violation represents a contract violation.
@@ -21,11 +28,15 @@ type Violation struct {
 	RuleType       string   // "allow" or "deny"
 	ExpectedValues []string // What was expected (allow) or forbidden (deny)
 	Pattern        string   // The pattern that matched (for deny rules)
+
+	Code     codes.Code     // codes.ContractNotAllowed or codes.ContractDenied
+	Severity severity.Level // The rule's severity
 }
 
 // EvalResult contains the full contract evaluation result.
 type EvalResult struct {
 	Environment string      // Environment name evaluated
-	Passed      bool        // Whether all rules passed
-	Violations  []Violation // List of violations (empty if passed)
+	Passed      bool        // Whether no blocking rule failed
+	Violations  []Violation // Blocking violations (empty if passed)
+	Warnings    []Violation // Violations of rules with severity "warn"
 }
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"admit/internal/baseline"
+	"admit/internal/codes"
 )
 
 // DriftType represents the type of configuration change.
@@ -18,10 +19,11 @@ const (
 
 // KeyDrift represents a single key's drift.
 type KeyDrift struct {
-	Key           string    `json:"key"`
-	Type          DriftType `json:"type"`
-	BaselineValue string    `json:"baselineValue,omitempty"`
-	CurrentValue  string    `json:"currentValue,omitempty"`
+	Key           string     `json:"key"`
+	Type          DriftType  `json:"type"`
+	Code          codes.Code `json:"code"`
+	BaselineValue string     `json:"baselineValue,omitempty"`
+	CurrentValue  string     `json:"currentValue,omitempty"`
 }
 
 // DriftReport contains the full drift analysis.
@@ -75,6 +77,7 @@ func Detect(b baseline.Baseline, currentValues map[string]string, currentHash st
 			report.Changes = append(report.Changes, KeyDrift{
 				Key:           key,
 				Type:          DriftRemoved,
+				Code:          codes.DriftRemoved,
 				BaselineValue: baselineVal,
 			})
 		} else if !inBaseline && inCurrent {
@@ -82,6 +85,7 @@ func Detect(b baseline.Baseline, currentValues map[string]string, currentHash st
 			report.Changes = append(report.Changes, KeyDrift{
 				Key:          key,
 				Type:         DriftAdded,
+				Code:         codes.DriftAdded,
 				CurrentValue: currentVal,
 			})
 		} else if baselineVal != currentVal {
@@ -89,6 +93,7 @@ func Detect(b baseline.Baseline, currentValues map[string]string, currentHash st
 			report.Changes = append(report.Changes, KeyDrift{
 				Key:           key,
 				Type:          DriftChanged,
+				Code:          codes.DriftChanged,
 				BaselineValue: baselineVal,
 				CurrentValue:  currentVal,
 			})
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"admit/internal/baseline"
+	"admit/internal/codes"
 
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
@@ -226,3 +227,23 @@ func TestDriftReportMetadata(t *testing.T) {
 		t.Errorf("expected baseline time %v, got %v", baselineTime, report.BaselineTime)
 	}
 }
+
+// TestDetect_Codes tests that each kind of change carries its error code
+func TestDetect_Codes(t *testing.T) {
+	b := baseline.Baseline{
+		Name:         "prod",
+		ConfigHash:   "sha256:old",
+		ConfigValues: map[string]string{"a": "1", "b": "2"},
+	}
+	report := Detect(b, map[string]string{"b": "3", "c": "4"}, "sha256:new")
+
+	want := map[string]codes.Code{"a": codes.DriftRemoved, "b": codes.DriftChanged, "c": codes.DriftAdded}
+	if len(report.Changes) != len(want) {
+		t.Fatalf("changes = %+v, want %d", report.Changes, len(want))
+	}
+	for _, change := range report.Changes {
+		if change.Code != want[change.Key] {
+			t.Errorf("%s (%s) code = %q, want %q", change.Key, change.Type, change.Code, want[change.Key])
+		}
+	}
+}
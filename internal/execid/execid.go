@@ -23,6 +23,10 @@ type ExecutionIdentityV4 struct {
 	EnvironmentHash string   `json:"environmentHash"` // Hash of relevant env vars
 	Command         string   `json:"command"`         // The target command
 	Args            []string `json:"args"`            // Command arguments
+
+	// SourcesHash identifies executables that supplied config values (e.g., a helper).
+	// Empty when no such source is configured, leaving the fingerprint unchanged.
+	SourcesHash string `json:"sourcesHash,omitempty"`
 }
 
 // ComputeExecutionID generates the v4 execution identity.
@@ -33,12 +37,32 @@ func ComputeExecutionID(
 	args []string,
 	environ []string,
 	schemaKeys []string,
+) ExecutionIdentityV4 {
+	return ComputeExecutionIDWithSources(configVersion, command, args, environ, schemaKeys, nil)
+}
+
+// ComputeExecutionIDWithSources generates the v4 execution identity, also covering
+// the sources that supplied config values. Each entry of sources identifies one
+// source (e.g., a helper's path and arguments); with none, the result is the same
+// as ComputeExecutionID.
+func ComputeExecutionIDWithSources(
+	configVersion string,
+	command string,
+	args []string,
+	environ []string,
+	schemaKeys []string,
+	sources []string,
 ) ExecutionIdentityV4 {
 	commandHash := ComputeCommandHash(command, args)
 	environmentHash := ComputeEnvironmentHash(environ, schemaKeys)
 
 	// Compute final execution ID by hashing the concatenation of all components
 	combined := configVersion + commandHash + environmentHash
+	var sourcesHash string
+	if len(sources) > 0 {
+		sourcesHash = hashString(strings.Join(sources, "\x00"))
+		combined += sourcesHash
+	}
 	executionID := hashString(combined)
 
 	return ExecutionIdentityV4{
@@ -48,6 +72,7 @@ func ComputeExecutionID(
 		EnvironmentHash: environmentHash,
 		Command:         command,
 		Args:            args,
+		SourcesHash:     sourcesHash,
 	}
 }
 
@@ -629,6 +629,27 @@ func TestComputeCommandHash_NullSeparator(t *testing.T) {
 	}
 }
 
+// TestComputeExecutionIDWithSources verifies that sources change the fingerprint only when present
+func TestComputeExecutionIDWithSources(t *testing.T) {
+	schemaKeys := []string{"db.url"}
+	environ := []string{"DB_URL=postgres://localhost"}
+
+	plain := ComputeExecutionID("sha256:abc", "node", []string{"server.js"}, environ, schemaKeys)
+	none := ComputeExecutionIDWithSources("sha256:abc", "node", []string{"server.js"}, environ, schemaKeys, nil)
+	if none.ExecutionID != plain.ExecutionID || none.SourcesHash != "" {
+		t.Errorf("no sources: got %+v, want same identity as ComputeExecutionID", none)
+	}
+
+	helperA := ComputeExecutionIDWithSources("sha256:abc", "node", []string{"server.js"}, environ, schemaKeys, []string{"helper:/usr/bin/a"})
+	helperB := ComputeExecutionIDWithSources("sha256:abc", "node", []string{"server.js"}, environ, schemaKeys, []string{"helper:/usr/bin/b"})
+	if helperA.SourcesHash == "" || helperA.ExecutionID == plain.ExecutionID {
+		t.Errorf("helper source did not change the execution ID: %+v", helperA)
+	}
+	if helperA.ExecutionID == helperB.ExecutionID {
+		t.Error("different helper paths produced the same execution ID")
+	}
+}
+
 // Helper to sort strings for comparison
 func sortedCopy(s []string) []string {
 	c := make([]string, len(s))
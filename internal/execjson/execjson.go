@@ -0,0 +1,126 @@
+// Package execjson runs executables that take a JSON request on stdin and
+// answer with JSON on stdout, as validate_with validators and the helper
+// source do. Output is size-bounded so a misbehaving executable cannot make
+// admit buffer without limit.
+package execjson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// MaxOutput bounds how much stdout is read before the answer is rejected
+const MaxOutput = 1 << 20
+
+// MaxStderr bounds how much stderr is kept for error messages
+const MaxStderr = 4096
+
+// Command describes an executable to run
+type Command struct {
+	Path    string
+	Args    []string
+	Env     []string      // Environment of the process
+	Timeout time.Duration // Time limit for the whole run (must be positive)
+}
+
+// ExitError reports an executable that exited with a non-zero status
+type ExitError struct {
+	Status int
+	Stderr string // Captured stderr, formatted for appending to a message
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exited with status %d%s", e.Status, e.Stderr)
+}
+
+// OutputError reports an answer that could not be decoded
+type OutputError struct {
+	Err error
+}
+
+func (e *OutputError) Error() string {
+	return "invalid output: " + e.Err.Error()
+}
+
+func (e *OutputError) Unwrap() error {
+	return e.Err
+}
+
+// Run executes the command with request encoded as JSON on stdin and decodes
+// its stdout into response. Timeouts, start failures and non-zero exits
+// (*ExitError) are errors, and so is output that is too large or not valid
+// JSON for response (*OutputError). Error messages include the stderr.
+func Run(cmd Command, request, response interface{}) error {
+	input, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cmd.Timeout)
+	defer cancel()
+
+	c := exec.CommandContext(ctx, cmd.Path, cmd.Args...)
+	c.Env = cmd.Env
+	c.Stdin = bytes.NewReader(input)
+	// Don't wait indefinitely for grandchildren that inherited the output pipes
+	c.WaitDelay = time.Second
+
+	stdout := &boundedBuffer{max: MaxOutput}
+	stderr := &boundedBuffer{max: MaxStderr}
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	err = c.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s%s", cmd.Timeout, stderr.suffix())
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &ExitError{Status: exitErr.ExitCode(), Stderr: stderr.suffix()}
+		}
+		return err
+	}
+
+	if stdout.truncated {
+		return &OutputError{Err: fmt.Errorf("exceeds %d bytes", MaxOutput)}
+	}
+	if err := json.Unmarshal(stdout.buf.Bytes(), response); err != nil {
+		return &OutputError{Err: err}
+	}
+	return nil
+}
+
+// boundedBuffer keeps the first max bytes written to it and discards the rest
+type boundedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *boundedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room < len(p) {
+		b.buf.Write(p[:room])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// suffix formats the captured output for appending to an error message
+func (b *boundedBuffer) suffix() string {
+	text := strings.TrimSpace(b.buf.String())
+	if text == "" {
+		return ""
+	}
+	if b.truncated {
+		text += " ..."
+	}
+	return ": " + text
+}
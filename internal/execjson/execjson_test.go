@@ -0,0 +1,88 @@
+package execjson
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeScript writes an executable shell script and returns its path
+func writeScript(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	// The script answers with its stdin, first argument and environment
+	script := writeScript(t, `printf '{"input": %s, "arg": "%s", "env": "%s"}' "$(cat)" "$1" "$PROFILE"`)
+
+	var response struct {
+		Input map[string]string `json:"input"`
+		Arg   string            `json:"arg"`
+		Env   string            `json:"env"`
+	}
+	cmd := Command{Path: script, Args: []string{"--prod"}, Env: []string{"PROFILE=team"}, Timeout: 5 * time.Second}
+	if err := Run(cmd, map[string]string{"key": "db.url"}, &response); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if response.Input["key"] != "db.url" || response.Arg != "--prod" || response.Env != "team" {
+		t.Errorf("response = %+v", response)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		timeout time.Duration
+		wantErr string
+	}{
+		{name: "non-zero exit includes stderr", script: "echo 'vault sealed' >&2\nexit 3\n", wantErr: "exited with status 3: vault sealed"},
+		{name: "timeout", script: "sleep 5\n", timeout: 100 * time.Millisecond, wantErr: "timed out after 100ms"},
+		{name: "invalid JSON", script: "echo not json\n", wantErr: "invalid output: invalid character"},
+		{name: "output too large", script: "head -c 2000000 /dev/zero | tr '\\0' ' '\n", wantErr: "invalid output: exceeds 1048576 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeout := tt.timeout
+			if timeout == 0 {
+				timeout = 5 * time.Second
+			}
+			var response map[string]string
+			err := Run(Command{Path: writeScript(t, tt.script), Timeout: timeout}, nil, &response)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	var exitErr *ExitError
+	err := Run(Command{Path: writeScript(t, "exit 4\n"), Timeout: 5 * time.Second}, nil, &struct{}{})
+	if !errors.As(err, &exitErr) || exitErr.Status != 4 {
+		t.Errorf("error = %v, want an *ExitError with status 4", err)
+	}
+
+	if err := Run(Command{Path: filepath.Join(t.TempDir(), "missing"), Timeout: time.Second}, nil, &struct{}{}); err == nil {
+		t.Error("expected error for missing executable, got nil")
+	}
+}
+
+func TestRun_StderrBounded(t *testing.T) {
+	script := writeScript(t, "head -c 10000 /dev/zero | tr '\\0' x >&2\nexit 1\n")
+
+	err := Run(Command{Path: script, Timeout: 5 * time.Second}, nil, &struct{}{})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if len(err.Error()) > MaxStderr+200 || !strings.HasSuffix(err.Error(), " ...") {
+		t.Errorf("stderr not truncated: %d bytes", len(err.Error()))
+	}
+}
@@ -0,0 +1,336 @@
+// Package fixture provides v9 schema tests: cases that pin the outcome admit
+// reaches for a given environment, checked by admit test.
+package fixture
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"admit/internal/codes"
+	"admit/internal/contract"
+	"admit/internal/invariant"
+	"admit/internal/validator"
+)
+
+// DefaultFile is the fixtures file admit test reads from the schema's directory
+const DefaultFile = "admit.test.yaml"
+
+// Case is one fixture: the environment admit runs in and the outcome it should reach
+type Case struct {
+	Name        string
+	Env         map[string]string // Process environment variables
+	AdmitEnv    string            // ADMIT_ENV value (empty leaves it unset)
+	EnvFile     string            // Env file loaded like --env-file, relative to the fixtures file
+	Execution   Execution
+	LiveSources bool // Query the vault and helper sources (left out by default)
+	Expect      Expect
+}
+
+// Execution is the execution context invariants see for a case. Fields that
+// are not set are empty, so cases do not depend on the machine they run on.
+type Execution struct {
+	Command  string   // Command admit would run (its base name is matched)
+	Args     []string // Arguments passed to the command
+	User     string
+	Hostname string
+	Cwd      string
+}
+
+// Expect is a case's expected outcome. Fields that are not set are not checked;
+// a list that is set must match exactly, so an empty list expects none.
+type Expect struct {
+	Valid      *bool           // Whether the command would run (exit code 0)
+	ExitCode   *int            // Exit code admit run would return
+	Errors     []ExpectedError // Validation errors
+	Invariants []string        // Names of violated invariants
+	Contract   []string        // Keys that violate the environment's contract
+}
+
+// ExpectedError is an expected validation error
+type ExpectedError struct {
+	Key  string
+	Code codes.Code // Empty matches any code
+}
+
+// String returns the error as "key (CODE)", or "key" without a code
+func (e ExpectedError) String() string {
+	if e.Code == "" {
+		return e.Key
+	}
+	return fmt.Sprintf("%s (%s)", e.Key, e.Code)
+}
+
+// Outcome is what the run pipeline reached for a case. Stages after the one
+// that failed are not run, as with admit run.
+type Outcome struct {
+	ExitCode   int
+	Error      string                      // Setup or resolve error, if any
+	Errors     []validator.ValidationError // Blocking validation errors
+	Invariants []invariant.InvariantResult // Blocking invariant violations
+	Contract   []contract.Violation        // Blocking contract violations
+}
+
+// Reason describes the failure that determined the exit code, or returns ""
+// if the outcome is valid
+func (o Outcome) Reason() string {
+	switch {
+	case o.Error != "":
+		return o.Error
+	case len(o.Errors) > 0:
+		return fmt.Sprintf("%d validation error(s), first: %s", len(o.Errors), validator.FormatError(o.Errors[0]))
+	case len(o.Invariants) > 0:
+		return fmt.Sprintf("invariant '%s' violated: %s", o.Invariants[0].Name, o.Invariants[0].Message)
+	case len(o.Contract) > 0:
+		return "contract violation: " + contract.FormatMessage(o.Contract[0])
+	}
+	return ""
+}
+
+// Result is a checked case
+type Result struct {
+	Name    string
+	Diffs   []string // Differences from the expected outcome (empty if the case passed)
+	Elapsed time.Duration
+}
+
+// Passed reports whether the outcome matched the expectation
+func (r Result) Passed() bool {
+	return len(r.Diffs) == 0
+}
+
+// fixtureFile is the YAML structure of a fixtures file
+type fixtureFile struct {
+	Cases []caseEntry `yaml:"cases"`
+}
+
+type caseEntry struct {
+	Name        string            `yaml:"name"`
+	Env         map[string]string `yaml:"env"`
+	AdmitEnv    string            `yaml:"admit_env"`
+	EnvFile     string            `yaml:"env_file"`
+	Execution   executionEntry    `yaml:"execution"`
+	LiveSources bool              `yaml:"live_sources"`
+	Expect      expectEntry       `yaml:"expect"`
+}
+
+type executionEntry struct {
+	Command  string   `yaml:"command"`
+	Args     []string `yaml:"args"`
+	User     string   `yaml:"user"`
+	Hostname string   `yaml:"hostname"`
+	Cwd      string   `yaml:"cwd"`
+}
+
+type expectEntry struct {
+	Valid      *bool        `yaml:"valid"`
+	ExitCode   *int         `yaml:"exit_code"`
+	Errors     []errorEntry `yaml:"errors"`
+	Invariants []string     `yaml:"invariants"`
+	Contract   []string     `yaml:"contract"`
+}
+
+type errorEntry struct {
+	Key  string `yaml:"key"`
+	Code string `yaml:"code"`
+}
+
+// UnmarshalYAML accepts a key or a mapping with 'key' and 'code'
+func (e *errorEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&e.Key)
+	}
+	type plain errorEntry
+	return node.Decode((*plain)(e))
+}
+
+// Load reads and parses a fixtures file
+func Load(path string) ([]Case, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(content, filepath.Dir(path))
+}
+
+// Parse parses fixtures YAML. Env file paths are resolved against dir.
+func Parse(content []byte, dir string) ([]Case, error) {
+	var ff fixtureFile
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&ff); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(ff.Cases) == 0 {
+		return nil, errors.New("no cases")
+	}
+
+	cases := make([]Case, 0, len(ff.Cases))
+	seen := make(map[string]bool)
+	for i, entry := range ff.Cases {
+		if entry.Name == "" {
+			return nil, fmt.Errorf("case %d has no name", i+1)
+		}
+		if seen[entry.Name] {
+			return nil, fmt.Errorf("duplicate case name '%s'", entry.Name)
+		}
+		seen[entry.Name] = true
+
+		c := Case{
+			Name:     entry.Name,
+			Env:      entry.Env,
+			AdmitEnv: entry.AdmitEnv,
+			EnvFile:  entry.EnvFile,
+			Execution: Execution{
+				Command:  entry.Execution.Command,
+				Args:     entry.Execution.Args,
+				User:     entry.Execution.User,
+				Hostname: entry.Execution.Hostname,
+				Cwd:      entry.Execution.Cwd,
+			},
+			LiveSources: entry.LiveSources,
+			Expect: Expect{
+				Valid:      entry.Expect.Valid,
+				ExitCode:   entry.Expect.ExitCode,
+				Invariants: entry.Expect.Invariants,
+				Contract:   entry.Expect.Contract,
+			},
+		}
+		if len(c.Execution.Args) > 0 && c.Execution.Command == "" {
+			return nil, fmt.Errorf("case '%s': execution args require a command", entry.Name)
+		}
+		if c.EnvFile != "" && !filepath.IsAbs(c.EnvFile) {
+			c.EnvFile = filepath.Join(dir, c.EnvFile)
+		}
+		if entry.Expect.Errors != nil {
+			c.Expect.Errors = []ExpectedError{}
+		}
+		for _, e := range entry.Expect.Errors {
+			if e.Key == "" {
+				return nil, fmt.Errorf("case '%s': expected error has no key", entry.Name)
+			}
+			code := codes.Code(strings.ToUpper(e.Code))
+			if _, ok := codes.Lookup(code); code != "" && !ok {
+				return nil, fmt.Errorf("case '%s': unknown error code '%s'", entry.Name, e.Code)
+			}
+			c.Expect.Errors = append(c.Expect.Errors, ExpectedError{Key: e.Key, Code: code})
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// Environ returns the process environment a case runs in: its env
+// variables and ADMIT_ENV, sorted
+func (c Case) Environ() []string {
+	environ := make([]string, 0, len(c.Env)+1)
+	for name, value := range c.Env {
+		if name != "ADMIT_ENV" || c.AdmitEnv == "" {
+			environ = append(environ, name+"="+value)
+		}
+	}
+	if c.AdmitEnv != "" {
+		environ = append(environ, "ADMIT_ENV="+c.AdmitEnv)
+	}
+	sort.Strings(environ)
+	return environ
+}
+
+// Check compares a case's outcome with its expectation
+func Check(c Case, o Outcome) Result {
+	result := Result{Name: c.Name}
+	diff := func(format string, args ...interface{}) {
+		result.Diffs = append(result.Diffs, fmt.Sprintf(format, args...))
+	}
+
+	// Exit code differences say which failure the code came from
+	got := fmt.Sprintf("%d", o.ExitCode)
+	if reason := o.Reason(); reason != "" {
+		got += " (" + reason + ")"
+	}
+	if c.Expect.Valid != nil && *c.Expect.Valid != (o.ExitCode == 0) {
+		if *c.Expect.Valid {
+			diff("expected valid, got exit code %s", got)
+		} else {
+			diff("expected invalid, got exit code 0")
+		}
+	}
+	if c.Expect.ExitCode != nil && *c.Expect.ExitCode != o.ExitCode {
+		diff("exit code: expected %d, got %s", *c.Expect.ExitCode, got)
+	}
+
+	if c.Expect.Errors != nil {
+		matched := make([]bool, len(o.Errors))
+		for _, want := range c.Expect.Errors {
+			found := false
+			for i, got := range o.Errors {
+				if !matched[i] && got.Key == want.Key && (want.Code == "" || got.Code == want.Code) {
+					matched[i], found = true, true
+					break
+				}
+			}
+			if !found {
+				diff("missing validation error: %s", want)
+			}
+		}
+		for i, got := range o.Errors {
+			if !matched[i] {
+				diff("unexpected validation error (%s): %s", got.Code, validator.FormatError(got))
+			}
+		}
+	}
+
+	if c.Expect.Invariants != nil {
+		violated := make(map[string]bool, len(o.Invariants))
+		for _, r := range o.Invariants {
+			violated[r.Name] = true
+			if !containsString(c.Expect.Invariants, r.Name) {
+				diff("unexpected invariant violation: '%s': %s", r.Name, r.Message)
+			}
+		}
+		for _, name := range c.Expect.Invariants {
+			if !violated[name] {
+				diff("missing invariant violation: '%s'", name)
+			}
+		}
+	}
+
+	if c.Expect.Contract != nil {
+		violated := make(map[string]bool, len(o.Contract))
+		for _, v := range o.Contract {
+			violated[v.Key] = true
+			if !containsString(c.Expect.Contract, v.Key) {
+				diff("unexpected contract violation: %s", contract.FormatMessage(v))
+			}
+		}
+		for _, key := range c.Expect.Contract {
+			if !violated[key] {
+				diff("missing contract violation: '%s'", key)
+			}
+		}
+	}
+
+	// A setup or resolve error explains missing violations
+	if len(result.Diffs) > 0 && o.Error != "" && !strings.Contains(strings.Join(result.Diffs, "\n"), o.Error) {
+		diff("error: %s", o.Error)
+	}
+
+	return result
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package fixture
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"admit/internal/codes"
+	"admit/internal/contract"
+	"admit/internal/invariant"
+	"admit/internal/validator"
+)
+
+func TestParse(t *testing.T) {
+	content := `cases:
+  - name: prod needs live payments
+    admit_env: prod
+    env:
+      PAYMENTS_MODE: sandbox
+    env_file: fixtures/prod.env
+    execution:
+      command: /usr/bin/migrate
+      args: [up, --force]
+      user: deploy
+    live_sources: true
+    expect:
+      exit_code: 2
+      invariants: [prod-live]
+  - name: missing url
+    expect:
+      valid: false
+      errors:
+        - db.url
+        - key: payments.mode
+          code: adm002
+      contract: []
+`
+	cases, err := Parse([]byte(content), "/schemas")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(cases) != 2 {
+		t.Fatalf("got %d cases, want 2", len(cases))
+	}
+
+	prod := cases[0]
+	if prod.AdmitEnv != "prod" || prod.Env["PAYMENTS_MODE"] != "sandbox" || prod.EnvFile != filepath.Join("/schemas", "fixtures/prod.env") {
+		t.Errorf("case = %+v", prod)
+	}
+	wantExecution := Execution{Command: "/usr/bin/migrate", Args: []string{"up", "--force"}, User: "deploy"}
+	if !reflect.DeepEqual(prod.Execution, wantExecution) || !prod.LiveSources {
+		t.Errorf("execution = %+v, live sources = %v", prod.Execution, prod.LiveSources)
+	}
+	if *prod.Expect.ExitCode != 2 || !reflect.DeepEqual(prod.Expect.Invariants, []string{"prod-live"}) {
+		t.Errorf("expect = %+v", prod.Expect)
+	}
+	if prod.Expect.Valid != nil || prod.Expect.Errors != nil || prod.Expect.Contract != nil {
+		t.Errorf("unset expectations = %+v, want nil", prod.Expect)
+	}
+
+	if cases[1].LiveSources || !reflect.DeepEqual(cases[1].Execution, Execution{}) {
+		t.Errorf("case = %+v, want no execution context or live sources", cases[1])
+	}
+
+	missing := cases[1].Expect
+	wantErrors := []ExpectedError{{Key: "db.url"}, {Key: "payments.mode", Code: codes.InvalidEnum}}
+	if *missing.Valid || !reflect.DeepEqual(missing.Errors, wantErrors) {
+		t.Errorf("expect = %+v", missing)
+	}
+	if missing.Contract == nil || len(missing.Contract) != 0 {
+		t.Errorf("Contract = %#v, want an empty list", missing.Contract)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"cases: []", "no cases"},
+		{"cases:\n  - env: {A: b}", "case 1 has no name"},
+		{"cases:\n  - name: a\n  - name: a", "duplicate case name 'a'"},
+		{"cases:\n  - name: a\n    expect:\n      errors: [{code: ADM001}]", "case 'a': expected error has no key"},
+		{"cases:\n  - name: a\n    expect:\n      errors: [{key: a, code: ADM999}]", "case 'a': unknown error code 'ADM999'"},
+		{"cases:\n  - name: a\n    expect:\n      invariant: [x]", "field invariant not found"},
+		{"cases:\n  - name: a\n    execution: {args: [up]}", "case 'a': execution args require a command"},
+		{"cases:\n  - name: a\n    execution: {env: prod}", "field env not found"},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.content), ".")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.content, err, tt.want)
+		}
+	}
+}
+
+func TestCase_Environ(t *testing.T) {
+	c := Case{Env: map[string]string{"DB_URL": "x", "ADMIT_ENV": "dev", "A": "1"}, AdmitEnv: "prod"}
+	want := []string{"A=1", "ADMIT_ENV=prod", "DB_URL=x"}
+	if got := c.Environ(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Environ() = %v, want %v", got, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	valid, exitCode := false, 2
+	c := Case{Name: "prod", Expect: Expect{
+		Valid:      &valid,
+		ExitCode:   &exitCode,
+		Errors:     []ExpectedError{{Key: "db.url", Code: codes.RequiredMissing}},
+		Invariants: []string{"prod-live"},
+		Contract:   []string{},
+	}}
+
+	// The expected outcome
+	outcome := Outcome{
+		ExitCode:   2,
+		Errors:     []validator.ValidationError{{Key: "db.url", Code: codes.RequiredMissing}},
+		Invariants: []invariant.InvariantResult{{Name: "prod-live"}},
+	}
+	if result := Check(c, outcome); !result.Passed() {
+		t.Errorf("Check() diffs = %v, want none", result.Diffs)
+	}
+
+	// A different outcome reports every difference
+	outcome = Outcome{
+		ExitCode:   0,
+		Errors:     []validator.ValidationError{{Key: "db.url", Code: codes.InvalidEnum, Message: "bad"}},
+		Invariants: []invariant.InvariantResult{{Name: "other", Message: "'a' != 'b'"}},
+		Contract:   []contract.Violation{{Key: "db.env", ActualValue: "dev", RuleType: "deny", Pattern: "dev"}},
+	}
+	result := Check(c, outcome)
+	want := []string{
+		"expected invalid, got exit code 0",
+		"exit code: expected 2, got 0",
+		"missing validation error: db.url (ADM001)",
+		"unexpected validation error (ADM002): ",
+		"unexpected invariant violation: 'other': 'a' != 'b'",
+		"missing invariant violation: 'prod-live'",
+		"unexpected contract violation: ",
+	}
+	if len(result.Diffs) != len(want) {
+		t.Fatalf("Check() diffs = %q, want %d", result.Diffs, len(want))
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(result.Diffs[i], prefix) {
+			t.Errorf("diff %d = %q, want prefix %q", i, result.Diffs[i], prefix)
+		}
+	}
+
+	// Exit code differences give the failure the code came from, and errors
+	// explain missing violations
+	c = Case{Name: "resolve", Expect: Expect{ExitCode: &exitCode, Invariants: []string{"prod-live"}}}
+	result = Check(c, Outcome{ExitCode: 1, Error: "cannot resolve config: boom"})
+	want = []string{
+		"exit code: expected 2, got 1 (cannot resolve config: boom)",
+		"missing invariant violation: 'prod-live'",
+	}
+	if !reflect.DeepEqual(result.Diffs, want) {
+		t.Errorf("Check() diffs = %q, want %q", result.Diffs, want)
+	}
+	result = Check(Case{Name: "resolve", Expect: Expect{Invariants: []string{"prod-live"}}}, Outcome{ExitCode: 1, Error: "boom"})
+	if want := []string{"missing invariant violation: 'prod-live'", "error: boom"}; !reflect.DeepEqual(result.Diffs, want) {
+		t.Errorf("Check() diffs = %q, want %q", result.Diffs, want)
+	}
+
+	// Unset expectations are not checked
+	if result := Check(Case{Name: "any"}, outcome); !result.Passed() {
+		t.Errorf("Check() with no expectations diffs = %v, want none", result.Diffs)
+	}
+}
@@ -0,0 +1,86 @@
+package fixture
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FormatText formats checked cases as a human-readable report
+func FormatText(results []Result) string {
+	var sb strings.Builder
+	failed := 0
+	for _, r := range results {
+		if r.Passed() {
+			sb.WriteString(fmt.Sprintf("PASS  %s\n", r.Name))
+			continue
+		}
+		failed++
+		sb.WriteString(fmt.Sprintf("FAIL  %s\n", r.Name))
+		for _, d := range r.Diffs {
+			sb.WriteString(fmt.Sprintf("  %s\n", d))
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("\n%d case(s): %d passed, %d failed\n", len(results), len(results)-failed, failed))
+	return sb.String()
+}
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// FormatJUnit formats checked cases as a JUnit XML report with one test
+// suite, so CI systems can show each case as a test
+func FormatJUnit(suite string, results []Result) (string, error) {
+	ts := junitTestSuite{Name: suite, Tests: len(results), Cases: []junitTestCase{}}
+	var total time.Duration
+	for _, r := range results {
+		total += r.Elapsed
+		tc := junitTestCase{Name: r.Name, ClassName: suite, Time: junitSeconds(r.Elapsed)}
+		if !r.Passed() {
+			ts.Failures++
+			tc.Failure = &junitFailure{Message: r.Diffs[0], Text: strings.Join(r.Diffs, "\n")}
+		}
+		ts.Cases = append(ts.Cases, tc)
+	}
+	ts.Time = junitSeconds(total)
+
+	report := junitTestSuites{Tests: ts.Tests, Failures: ts.Failures, Time: ts.Time, Suites: []junitTestSuite{ts}}
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(data) + "\n", nil
+}
+
+// junitSeconds formats a duration as JUnit's decimal seconds
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
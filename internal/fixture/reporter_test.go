@@ -0,0 +1,63 @@
+package fixture
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatText(t *testing.T) {
+	results := []Result{
+		{Name: "dev is valid"},
+		{Name: "prod needs live", Diffs: []string{"exit code: expected 2, got 1 (cannot resolve config: boom)", "missing invariant violation: 'prod-live'"}},
+	}
+
+	want := `PASS  dev is valid
+FAIL  prod needs live
+  exit code: expected 2, got 1 (cannot resolve config: boom)
+  missing invariant violation: 'prod-live'
+
+2 case(s): 1 passed, 1 failed
+`
+	if got := FormatText(results); got != want {
+		t.Errorf("FormatText() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatJUnit(t *testing.T) {
+	results := []Result{
+		{Name: "dev is valid", Elapsed: 1500 * time.Millisecond},
+		{Name: "prod <live>", Diffs: []string{"missing invariant violation: 'prod-live'", "exit code: expected 2, got 0"}},
+	}
+
+	report, err := FormatJUnit("admit.test.yaml", results)
+	if err != nil {
+		t.Fatalf("FormatJUnit() error = %v", err)
+	}
+	if !strings.HasPrefix(report, xml.Header) {
+		t.Errorf("report does not start with the XML header:\n%s", report)
+	}
+
+	var parsed junitTestSuites
+	if err := xml.Unmarshal([]byte(report), &parsed); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, report)
+	}
+	if parsed.Tests != 2 || parsed.Failures != 1 || len(parsed.Suites) != 1 {
+		t.Fatalf("testsuites = %+v", parsed)
+	}
+	suite := parsed.Suites[0]
+	if suite.Name != "admit.test.yaml" || suite.Time != "1.500" || len(suite.Cases) != 2 {
+		t.Errorf("testsuite = %+v", suite)
+	}
+	if suite.Cases[0].Failure != nil || suite.Cases[0].Time != "1.500" {
+		t.Errorf("passing testcase = %+v", suite.Cases[0])
+	}
+	failure := suite.Cases[1].Failure
+	if suite.Cases[1].Name != "prod <live>" || failure == nil {
+		t.Fatalf("failing testcase = %+v", suite.Cases[1])
+	}
+	if failure.Message != "missing invariant violation: 'prod-live'" || !strings.Contains(failure.Text, "exit code: expected 2, got 0") {
+		t.Errorf("failure = %+v", failure)
+	}
+}
@@ -0,0 +1,443 @@
+package invariant
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AnalysisDomain is what Analyze enumerates: execution.env values and, in
+// each, the values of enum config keys (v9: admit invariants analyze)
+type AnalysisDomain struct {
+	Envs     []string                       // execution.env values (if empty, the values rules compare it with)
+	Values   map[string][]string            // Enum keys and their values
+	Allowed  map[string]map[string][]string // Per environment, the values a contract narrows a key to
+	Nullable map[string]bool                // Enum keys that may be unset (not required and without a default)
+}
+
+// FindingKind is the kind of an analysis finding
+type FindingKind string
+
+const (
+	FindingNeverPasses FindingKind = "never-passes" // The invariant fails for every assignment in an environment
+	FindingNeverFails  FindingKind = "never-fails"  // The invariant passes for every assignment it applies to (a dead rule)
+	FindingConflict    FindingKind = "conflict"     // Two invariants that can each pass cannot both pass in an environment
+)
+
+// maxAssignments caps the assignments enumerated for one invariant or pair
+const maxAssignments = 100000
+
+// Example is a concrete assignment found by analysis
+type Example struct {
+	Values   map[string]*string `json:"values"`             // execution.env and the enum keys involved; nil if unset
+	Violates string             `json:"violates,omitempty"` // Invariant that fails under Values
+	Message  string             `json:"message,omitempty"`  // Its failure message
+}
+
+// Finding is an invariant, or a pair of invariants, analysis flags
+type Finding struct {
+	Kind        FindingKind `json:"kind"`
+	Invariants  []string    `json:"invariants"`    // One invariant, or two for a conflict
+	Env         string      `json:"env,omitempty"` // Environment (never-fails findings hold in all of them)
+	Assignments int         `json:"assignments"`   // Number of assignments enumerated
+	Examples    []Example   `json:"examples"`      // Counterexamples (for never-fails, an assignment it passes)
+}
+
+// SkippedInvariant is an invariant analysis cannot enumerate
+type SkippedInvariant struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Analysis is the result of Analyze
+type Analysis struct {
+	Environments []string           `json:"environments"`
+	Analyzed     int                `json:"analyzed"` // Invariants analyzed (not skipped)
+	Findings     []Finding          `json:"findings"`
+	Skipped      []SkippedInvariant `json:"skipped"`
+}
+
+// HasProblems reports whether a finding means some environment cannot pass:
+// an invariant that never passes or a conflict. Dead rules are not problems.
+func (a Analysis) HasProblems() bool {
+	for _, f := range a.Findings {
+		if f.Kind != FindingNeverFails {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzed is an invariant Analyze can enumerate
+type analyzed struct {
+	inv    Invariant
+	keys   []string        // Enum keys it references, sorted
+	envs   []string        // Environments it applies in
+	passes map[string]bool // Environments in which some assignment passes
+}
+
+// Analyze enumerates the assignments of enum keys in each environment and
+// reports invariants that never pass, invariants that never fail, and pairs
+// of invariants that cannot both pass, each with concrete examples.
+// Invariants that reference other keys or context are skipped.
+func Analyze(invariants []Invariant, domain AnalysisDomain) Analysis {
+	envs := domain.Envs
+	if len(envs) == 0 {
+		envs = envLiterals(invariants)
+	}
+	analysis := Analysis{Environments: envs, Findings: []Finding{}, Skipped: []SkippedInvariant{}}
+
+	var candidates []*analyzed
+	for _, inv := range invariants {
+		a, reason := newAnalyzed(inv, envs, domain)
+		if reason != "" {
+			analysis.Skipped = append(analysis.Skipped, SkippedInvariant{Name: inv.Name, Reason: reason})
+			continue
+		}
+		if size := spaceSize(domain, a.envs, a.keys); size > maxAssignments {
+			analysis.Skipped = append(analysis.Skipped, SkippedInvariant{Name: inv.Name,
+				Reason: fmt.Sprintf("%d assignments exceed the limit of %d", size, maxAssignments)})
+			continue
+		}
+		candidates = append(candidates, a)
+		analysis.Findings = append(analysis.Findings, analyzeOne(a, domain)...)
+	}
+	analysis.Analyzed = len(candidates)
+
+	for i, a := range candidates {
+		for _, b := range candidates[i+1:] {
+			if f, ok := analyzePair(a, b, domain); ok {
+				analysis.Findings = append(analysis.Findings, f)
+			}
+		}
+	}
+	return analysis
+}
+
+// newAnalyzed returns an invariant's keys and environments, or why it cannot
+// be analyzed
+func newAnalyzed(inv Invariant, envs []string, domain AnalysisDomain) (*analyzed, string) {
+	if len(inv.When.Command) > 0 {
+		return nil, "scoped by when.command, which analysis cannot enumerate"
+	}
+
+	keySet := make(map[string]bool)
+	var reason string
+	walkExpr(inv.Expr, func(e RuleExpr) {
+		if reason != "" {
+			return
+		}
+		switch ref := e.(type) {
+		case ConfigRef:
+			if _, ok := domain.Values[ref.Path]; !ok {
+				reason = fmt.Sprintf("references '%s', which is not an enum key", ref.Path)
+			}
+			keySet[ref.Path] = true
+		case ExecutionRef, EnvRef:
+			reason = fmt.Sprintf("references '%s', which analysis cannot enumerate", FormatRule(ref))
+		}
+	})
+	if reason != "" {
+		return nil, reason
+	}
+
+	a := &analyzed{inv: inv, passes: make(map[string]bool)}
+	for key := range keySet {
+		a.keys = append(a.keys, key)
+	}
+	sort.Strings(a.keys)
+	for _, env := range envs {
+		if len(inv.When.Env) == 0 || containsString(inv.When.Env, env) {
+			a.envs = append(a.envs, env)
+		}
+	}
+	if len(a.envs) == 0 {
+		return nil, fmt.Sprintf("applies in none of the environments: %s", strings.Join(envs, ", "))
+	}
+	return a, ""
+}
+
+// analyzeOne reports whether an invariant never passes in an environment or
+// never fails in any, and records where it can pass
+func analyzeOne(a *analyzed, domain AnalysisDomain) []Finding {
+	var findings []Finding
+	var firstPass *Example
+	total, failed := 0, false
+	for _, env := range a.envs {
+		var firstFail *Example
+		count := 0
+		enumerate(domain, env, a.keys, func(values map[string]*string) bool {
+			count++
+			result := Evaluate(a.inv, assignmentContext(env, values))
+			if result.Passed {
+				a.passes[env] = true
+				if firstPass == nil {
+					firstPass = &Example{Values: exampleValues(env, values)}
+				}
+			} else if firstFail == nil {
+				firstFail = &Example{Values: exampleValues(env, values), Violates: a.inv.Name, Message: result.Message}
+			}
+			return true
+		})
+		total += count
+		if firstFail != nil {
+			failed = true
+			if !a.passes[env] {
+				findings = append(findings, Finding{Kind: FindingNeverPasses, Invariants: []string{a.inv.Name},
+					Env: env, Assignments: count, Examples: []Example{*firstFail}})
+			}
+		}
+	}
+	if !failed && firstPass != nil {
+		findings = append(findings, Finding{Kind: FindingNeverFails, Invariants: []string{a.inv.Name},
+			Assignments: total, Examples: []Example{*firstPass}})
+	}
+	return findings
+}
+
+// analyzePair reports the first environment in which two invariants that
+// can each pass cannot both pass. Invariants that share no key cannot
+// conflict.
+func analyzePair(a, b *analyzed, domain AnalysisDomain) (Finding, bool) {
+	keys := unionKeys(a.keys, b.keys)
+	if len(keys) == len(a.keys)+len(b.keys) {
+		return Finding{}, false
+	}
+
+	for _, env := range a.envs {
+		if !a.passes[env] || !b.passes[env] || spaceSize(domain, []string{env}, keys) > maxAssignments {
+			continue
+		}
+		var passA, passB *Example
+		count, both := 0, false
+		enumerate(domain, env, keys, func(values map[string]*string) bool {
+			count++
+			ctx := assignmentContext(env, values)
+			resultA, resultB := Evaluate(a.inv, ctx), Evaluate(b.inv, ctx)
+			switch {
+			case resultA.Passed && resultB.Passed:
+				both = true
+				return false
+			case resultA.Passed && passA == nil:
+				passA = &Example{Values: exampleValues(env, values), Violates: b.inv.Name, Message: resultB.Message}
+			case resultB.Passed && passB == nil:
+				passB = &Example{Values: exampleValues(env, values), Violates: a.inv.Name, Message: resultA.Message}
+			}
+			return true
+		})
+		if !both && passA != nil && passB != nil {
+			return Finding{Kind: FindingConflict, Invariants: []string{a.inv.Name, b.inv.Name},
+				Env: env, Assignments: count, Examples: []Example{*passA, *passB}}, true
+		}
+	}
+	return Finding{}, false
+}
+
+// valuesFor returns the values a key can take in an environment, with nil
+// for unset if the key may be unset
+func valuesFor(domain AnalysisDomain, env, key string) []*string {
+	values, ok := domain.Allowed[env][key]
+	if !ok {
+		values = domain.Values[key]
+	}
+	var out []*string
+	for i := range values {
+		out = append(out, &values[i])
+	}
+	if domain.Nullable[key] {
+		out = append(out, nil)
+	}
+	return out
+}
+
+// spaceSize returns the number of assignments of keys across envs
+func spaceSize(domain AnalysisDomain, envs, keys []string) int {
+	total := 0
+	for _, env := range envs {
+		size := 1
+		for _, key := range keys {
+			size *= len(valuesFor(domain, env, key))
+			if size > maxAssignments {
+				return size
+			}
+		}
+		total += size
+	}
+	return total
+}
+
+// enumerate calls fn with each assignment of keys in env until fn returns false
+func enumerate(domain AnalysisDomain, env string, keys []string, fn func(map[string]*string) bool) {
+	choices := make([][]*string, len(keys))
+	for i, key := range keys {
+		choices[i] = valuesFor(domain, env, key)
+		if len(choices[i]) == 0 {
+			return
+		}
+	}
+
+	index := make([]int, len(keys))
+	for {
+		values := make(map[string]*string, len(keys))
+		for i, key := range keys {
+			values[key] = choices[i][index[i]]
+		}
+		if !fn(values) {
+			return
+		}
+
+		// Advance the mixed-radix counter
+		i := len(index) - 1
+		for ; i >= 0; i-- {
+			index[i]++
+			if index[i] < len(choices[i]) {
+				break
+			}
+			index[i] = 0
+		}
+		if i < 0 {
+			return
+		}
+	}
+}
+
+// assignmentContext returns the context an assignment is evaluated in
+func assignmentContext(env string, values map[string]*string) EvalContext {
+	config := make(map[string]string, len(values))
+	for key, value := range values {
+		if value != nil {
+			config[key] = *value
+		}
+	}
+	return EvalContext{ConfigValues: config, ExecutionEnv: env}
+}
+
+// exampleValues returns an assignment with execution.env for an Example
+func exampleValues(env string, values map[string]*string) map[string]*string {
+	out := make(map[string]*string, len(values)+1)
+	for key, value := range values {
+		out[key] = value
+	}
+	out["execution.env"] = &env
+	return out
+}
+
+// unionKeys merges two sorted key lists
+func unionKeys(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var keys []string
+	for _, key := range append(append([]string{}, a...), b...) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// envLiterals returns the values rules compare execution.env with and their
+// when.env values, sorted
+func envLiterals(invariants []Invariant) []string {
+	seen := make(map[string]bool)
+	for _, inv := range invariants {
+		for _, env := range inv.When.Env {
+			seen[env] = true
+		}
+		walkExpr(inv.Expr, func(e RuleExpr) {
+			switch x := e.(type) {
+			case Comparison:
+				for _, pair := range [][2]RuleExpr{{x.Left, x.Right}, {x.Right, x.Left}} {
+					if lit, ok := pair[1].(StringLiteral); ok && isExecutionEnv(pair[0]) {
+						seen[lit.Value] = true
+					}
+				}
+			case In:
+				if isExecutionEnv(x.Value) {
+					for _, item := range x.List {
+						if lit, ok := item.(StringLiteral); ok {
+							seen[lit.Value] = true
+						}
+					}
+				}
+			}
+		})
+	}
+
+	envs := make([]string, 0, len(seen))
+	for env := range seen {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	return envs
+}
+
+// isExecutionEnv reports whether expr is execution.env
+func isExecutionEnv(expr RuleExpr) bool {
+	_, ok := expr.(ExecutionEnv)
+	return ok
+}
+
+// FormatAnalysis formats an analysis as a human-readable report
+func FormatAnalysis(a Analysis) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Analyzed %d invariant(s) in %d environment(s): %s\n\n",
+		a.Analyzed, len(a.Environments), strings.Join(a.Environments, ", ")))
+
+	for _, f := range a.Findings {
+		switch f.Kind {
+		case FindingNeverPasses:
+			sb.WriteString(fmt.Sprintf("NEVER PASSES: '%s' in %s (%d assignment(s))\n", f.Invariants[0], f.Env, f.Assignments))
+		case FindingConflict:
+			sb.WriteString(fmt.Sprintf("CONFLICT: '%s' and '%s' cannot both pass in %s (%d assignment(s))\n",
+				f.Invariants[0], f.Invariants[1], f.Env, f.Assignments))
+		case FindingNeverFails:
+			sb.WriteString(fmt.Sprintf("NEVER FAILS: '%s' passes for all %d assignment(s)\n", f.Invariants[0], f.Assignments))
+		}
+		for _, ex := range f.Examples {
+			sb.WriteString(fmt.Sprintf("  e.g. %s\n", formatExampleValues(ex.Values)))
+			if ex.Violates != "" {
+				sb.WriteString(fmt.Sprintf("    violates '%s': %s\n", ex.Violates, ex.Message))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, s := range a.Skipped {
+		sb.WriteString(fmt.Sprintf("Skipped '%s': %s\n", s.Name, s.Reason))
+	}
+	if len(a.Skipped) > 0 {
+		sb.WriteString("\n")
+	}
+
+	if len(a.Findings) == 0 {
+		sb.WriteString("No findings\n")
+	}
+	return sb.String()
+}
+
+// formatExampleValues formats an assignment as key=value pairs, execution.env first
+func formatExampleValues(values map[string]*string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if key != "execution.env" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	keys = append([]string{"execution.env"}, keys...)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = fmt.Sprintf("%s=%s", key, operandValue{text: deref(values[key]), null: values[key] == nil})
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// deref returns *s, or "" if s is nil
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,216 @@
+package invariant
+
+import (
+	"strings"
+	"testing"
+)
+
+func analyzeRules(t *testing.T, domain AnalysisDomain, rules ...string) Analysis {
+	t.Helper()
+	var invariants []Invariant
+	for i, rule := range rules {
+		expr, err := ParseRule(rule, nil)
+		if err != nil {
+			t.Fatalf("ParseRule(%q) error = %v", rule, err)
+		}
+		invariants = append(invariants, Invariant{Name: string(rune('a' + i)), Rule: rule, Expr: expr})
+	}
+	return Analyze(invariants, domain)
+}
+
+func TestAnalyze(t *testing.T) {
+	domain := AnalysisDomain{
+		Envs:   []string{"dev", "prod"},
+		Values: map[string][]string{"payments.mode": {"sandbox", "live"}, "cache.mode": {"on", "off"}},
+	}
+	tests := []struct {
+		name     string
+		rules    []string
+		want     []string // kind:invariants:env of each finding
+		problems bool
+	}{
+		{
+			name:  "satisfiable",
+			rules: []string{`execution.env == "prod" => payments.mode == "live"`},
+		},
+		{
+			name:     "never passes",
+			rules:    []string{`cache.mode == "on" && cache.mode == "off"`},
+			want:     []string{"never-passes:a:dev", "never-passes:a:prod"},
+			problems: true,
+		},
+		{
+			name:     "never passes in one environment",
+			rules:    []string{`execution.env == "dev" || payments.mode == "bogus"`},
+			want:     []string{"never-passes:a:prod"},
+			problems: true,
+		},
+		{
+			name:  "never fails",
+			rules: []string{`cache.mode in ["on", "off"]`},
+			want:  []string{"never-fails:a:"},
+		},
+		{
+			name: "conflict",
+			rules: []string{
+				`execution.env == "prod" => payments.mode == "live"`,
+				`execution.env == "prod" => payments.mode == "sandbox"`,
+			},
+			want:     []string{"conflict:a,b:prod"},
+			problems: true,
+		},
+		{
+			name: "independent keys do not conflict",
+			rules: []string{
+				`payments.mode == "live"`,
+				`cache.mode == "on"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := analyzeRules(t, domain, tt.rules...)
+			var got []string
+			for _, f := range analysis.Findings {
+				got = append(got, string(f.Kind)+":"+strings.Join(f.Invariants, ",")+":"+f.Env)
+				if len(f.Examples) == 0 {
+					t.Errorf("%s finding has no examples", f.Kind)
+				}
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("findings = %v, want %v", got, tt.want)
+			}
+			if analysis.HasProblems() != tt.problems {
+				t.Errorf("HasProblems() = %v, want %v", analysis.HasProblems(), tt.problems)
+			}
+		})
+	}
+}
+
+func TestAnalyze_Examples(t *testing.T) {
+	domain := AnalysisDomain{
+		Envs:   []string{"prod"},
+		Values: map[string][]string{"payments.mode": {"sandbox", "live"}},
+	}
+	analysis := analyzeRules(t, domain,
+		`payments.mode == "live"`,
+		`payments.mode == "sandbox"`,
+	)
+	if len(analysis.Findings) != 1 {
+		t.Fatalf("findings = %+v, want one conflict", analysis.Findings)
+	}
+
+	examples := analysis.Findings[0].Examples
+	if len(examples) != 2 {
+		t.Fatalf("examples = %+v, want 2", examples)
+	}
+	for _, ex := range examples {
+		if env := ex.Values["execution.env"]; env == nil || *env != "prod" {
+			t.Errorf("example execution.env = %v, want prod", env)
+		}
+		// Each example passes one invariant and violates the other
+		mode := *ex.Values["payments.mode"]
+		if (mode == "live") != (ex.Violates == "b") || ex.Message == "" {
+			t.Errorf("example %s violates %q (%q)", mode, ex.Violates, ex.Message)
+		}
+	}
+}
+
+func TestAnalyze_Domain(t *testing.T) {
+	t.Run("contracts narrow values per environment", func(t *testing.T) {
+		domain := AnalysisDomain{
+			Envs:    []string{"dev", "prod"},
+			Values:  map[string][]string{"db.env": {"dev", "prod"}},
+			Allowed: map[string]map[string][]string{"dev": {"db.env": {"dev"}}},
+		}
+		analysis := analyzeRules(t, domain, `db.env == "prod"`)
+		if len(analysis.Findings) != 1 || analysis.Findings[0].Env != "dev" || analysis.Findings[0].Assignments != 1 {
+			t.Errorf("findings = %+v, want never-passes in dev over 1 assignment", analysis.Findings)
+		}
+	})
+
+	t.Run("nullable keys include unset", func(t *testing.T) {
+		domain := AnalysisDomain{
+			Envs:     []string{"prod"},
+			Values:   map[string][]string{"cache.mode": {"on", "off"}},
+			Nullable: map[string]bool{"cache.mode": true},
+		}
+		analysis := analyzeRules(t, domain, `cache.mode == "on" || cache.mode == "off"`)
+		if len(analysis.Findings) != 0 {
+			t.Errorf("findings = %+v, want none (unset fails the rule)", analysis.Findings)
+		}
+
+		analysis = analyzeRules(t, domain, `cache.mode != "on" && cache.mode != "off"`)
+		if len(analysis.Findings) != 1 || analysis.Findings[0].Kind != FindingNeverPasses {
+			t.Fatalf("findings = %+v, want never-passes", analysis.Findings)
+		}
+		if value, ok := analysis.Findings[0].Examples[0].Values["cache.mode"]; !ok {
+			t.Errorf("example omits cache.mode")
+		} else if value != nil && *value == "" {
+			t.Errorf("example cache.mode = '', want a value or null")
+		}
+	})
+
+	t.Run("environments from rules", func(t *testing.T) {
+		domain := AnalysisDomain{Values: map[string][]string{"payments.mode": {"sandbox", "live"}}}
+		analysis := analyzeRules(t, domain, `execution.env in ["staging", "prod"] => payments.mode == "live"`)
+		if strings.Join(analysis.Environments, ",") != "prod,staging" {
+			t.Errorf("Environments = %v, want [prod staging]", analysis.Environments)
+		}
+	})
+}
+
+func TestAnalyze_Skipped(t *testing.T) {
+	domain := AnalysisDomain{
+		Envs:   []string{"dev", "prod"},
+		Values: map[string][]string{"payments.mode": {"sandbox", "live"}},
+	}
+	analysis := analyzeRules(t, domain,
+		`db.url != ""`,
+		`env.CI == "true" => payments.mode == "sandbox"`,
+		`payments.mode == "live"`,
+	)
+
+	if analysis.Analyzed != 1 {
+		t.Errorf("Analyzed = %d, want 1", analysis.Analyzed)
+	}
+	want := []string{
+		"a: references 'db.url', which is not an enum key",
+		"b: references 'env.CI', which analysis cannot enumerate",
+	}
+	var got []string
+	for _, s := range analysis.Skipped {
+		got = append(got, s.Name+": "+s.Reason)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Skipped = %v, want %v", got, want)
+	}
+
+	scoped := Invariant{Name: "scoped", Expr: ConfigRef{Path: "payments.mode"}, When: When{Env: []string{"staging"}}}
+	if analysis := Analyze([]Invariant{scoped}, domain); len(analysis.Skipped) != 1 {
+		t.Errorf("Skipped = %+v, want 'scoped' (applies in no environment)", analysis.Skipped)
+	}
+}
+
+func TestFormatAnalysis(t *testing.T) {
+	domain := AnalysisDomain{
+		Envs:     []string{"prod"},
+		Values:   map[string][]string{"payments.mode": {"live"}},
+		Nullable: map[string]bool{"payments.mode": true},
+	}
+	analysis := analyzeRules(t, domain, `payments.mode == "sandbox"`, `db.url != ""`)
+
+	want := `Analyzed 1 invariant(s) in 1 environment(s): prod
+
+NEVER PASSES: 'a' in prod (2 assignment(s))
+  e.g. execution.env='prod', payments.mode='live'
+    violates 'a': 'live' != 'sandbox'
+
+Skipped 'b': references 'db.url', which is not an enum key
+
+`
+	if got := FormatAnalysis(analysis); got != want {
+		t.Errorf("FormatAnalysis() =\n%s\nwant\n%s", got, want)
+	}
+}
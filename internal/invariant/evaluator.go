@@ -2,32 +2,97 @@ package invariant
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"admit/internal/codes"
+	"admit/internal/contract"
 )
 
 // EvalContext provides values for invariant evaluation
 type EvalContext struct {
 	ConfigValues map[string]string // Resolved config values
 	ExecutionEnv string            // ADMIT_ENV value
+	Sensitive    map[string]bool   // Config paths (and env.NAME references) whose values are redacted in results
+
+	// v9: the rest of the execution context (execution.<field> and env.NAME)
+	Command  string            // Base name of the command being run
+	Args     []string          // Its arguments (execution.args joins them with spaces)
+	User     string            // Name of the user running admit
+	Hostname string            // Host name of the machine
+	Cwd      string            // Working directory
+	Env      map[string]string // Process environment
+
+	Trace bool // v9: record each invariant's evaluation tree in InvariantResult.Trace (--explain)
 }
 
+// RedactedValue replaces sensitive config values in results and messages
+const RedactedValue = "[sensitive]"
+
 // Evaluate evaluates a single invariant against the context
 // Returns an InvariantResult with the evaluation outcome
 func Evaluate(inv Invariant, ctx EvalContext) InvariantResult {
 	result := InvariantResult{
-		Name:   inv.Name,
-		Rule:   inv.Rule,
-		Passed: true,
+		Name:     inv.Name,
+		Rule:     inv.Rule,
+		Passed:   true,
+		Severity: inv.Severity,
+	}
+
+	// v9: invariants outside their when: selector are skipped, not passed
+	if applies, reason := inv.When.Applies(ctx); !applies {
+		result.Passed = false
+		result.Skipped = true
+		result.Message = reason
+		return result
 	}
 
-	passed, leftVal, rightVal, msg := evalExpr(inv.Expr, ctx)
-	result.Passed = passed
-	result.LeftValue = leftVal
-	result.RightValue = rightVal
+	// A rule that is unknown (it depends on a null operand) does not pass
+	outcome, leftVal, rightVal, msg := evalExpr(inv.Expr, ctx)
+	result.Passed = outcome == truthTrue
+	result.LeftValue, result.LeftNull = leftVal.text, leftVal.null
+	result.RightValue, result.RightNull = rightVal.text, rightVal.null
 	result.Message = msg
+	if !result.Passed {
+		result.Code = codes.InvariantViolated
+	}
+	if ctx.Trace {
+		trace := TraceExpr(inv.Expr, ctx)
+		result.Trace = &trace
+	}
 
 	return result
 }
 
+// Applies reports whether the selector matches the context. If it does not,
+// reason says which field did not match.
+func (w When) Applies(ctx EvalContext) (applies bool, reason string) {
+	if len(w.Env) > 0 && !containsString(w.Env, ctx.ExecutionEnv) {
+		return false, fmt.Sprintf("execution.env '%s' is not one of: %s", ctx.ExecutionEnv, strings.Join(w.Env, ", "))
+	}
+	if len(w.Command) > 0 {
+		for _, pattern := range w.Command {
+			if contract.MatchGlob(pattern, ctx.Command) {
+				return true, ""
+			}
+		}
+		return false, fmt.Sprintf("execution.command '%s' does not match: %s", ctx.Command, strings.Join(w.Command, ", "))
+	}
+	return true, ""
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
 // EvaluateAll evaluates all invariants against the context
 // Returns results for all invariants (both passing and failing)
 func EvaluateAll(invariants []Invariant, ctx EvalContext) []InvariantResult {
@@ -38,89 +103,546 @@ func EvaluateAll(invariants []Invariant, ctx EvalContext) []InvariantResult {
 	return results
 }
 
+// truth is the value of a condition under three-valued logic. A condition on
+// a null operand (an unset config key or environment variable) is unknown;
+// && and || follow Kleene logic, so false && unknown is false and
+// true || unknown is true.
+type truth int
+
+const (
+	truthFalse truth = iota
+	truthTrue
+	truthUnknown
+)
+
+// String returns "true", "false" or "unknown"
+func (t truth) String() string {
+	switch t {
+	case truthTrue:
+		return "true"
+	case truthUnknown:
+		return "unknown"
+	}
+	return "false"
+}
+
+// truthOf converts a two-valued result
+func truthOf(b bool) truth {
+	if b {
+		return truthTrue
+	}
+	return truthFalse
+}
+
+// operandValue is an operand's value as reported in results: its display
+// value, or null
+type operandValue struct {
+	text string
+	null bool
+}
+
+// String returns the value quoted for messages, or null
+func (v operandValue) String() string {
+	if v.null {
+		return "null"
+	}
+	return fmt.Sprintf("'%s'", v.text)
+}
+
+// isEmpty reports whether the value is empty and not null
+func (v operandValue) isEmpty() bool {
+	return v.text == "" && !v.null
+}
+
+// firstValue returns a, or b if a is empty, for reporting the first value of
+// a subexpression
+func firstValue(a, b operandValue) operandValue {
+	if a.isEmpty() {
+		return b
+	}
+	return a
+}
+
 // evalExpr evaluates a rule expression and returns:
-// - passed: whether the expression evaluated to true
+// - outcome: whether the expression is true, false or unknown
 // - leftVal: the evaluated left operand value (for reporting)
 // - rightVal: the evaluated right operand value (for reporting)
 // - message: human-readable explanation
-func evalExpr(expr RuleExpr, ctx EvalContext) (passed bool, leftVal, rightVal, message string) {
+func evalExpr(expr RuleExpr, ctx EvalContext) (outcome truth, leftVal, rightVal operandValue, message string) {
 	switch e := expr.(type) {
 	case Implication:
 		return evalImplication(e, ctx)
 	case Comparison:
 		return evalComparison(e, ctx)
-	case ConfigRef:
-		val := resolveValue(e, ctx)
-		return val != "", val, "", ""
-	case ExecutionEnv:
-		val := ctx.ExecutionEnv
-		return val != "", val, "", ""
+	case And:
+		return evalAnd(e, ctx)
+	case Or:
+		return evalOr(e, ctx)
+	case Not:
+		return evalNot(e, ctx)
+	case In:
+		return evalIn(e, ctx)
+	case Match:
+		return evalMatch(e, ctx)
+	case Like:
+		return evalLike(e, ctx)
+	case IsNull:
+		return evalIsNull(e, ctx)
+	case ConfigRef, ExecutionEnv, ExecutionRef, EnvRef:
+		// A bare reference holds if it is set and non-empty, so it is
+		// false rather than unknown when null
+		leftVal = reportOperand(e, ctx)
+		return truthOf(!leftVal.null && resolveValue(e, ctx) != ""), leftVal, operandValue{}, ""
 	case StringLiteral:
-		return true, e.Value, "", ""
+		return truthTrue, operandValue{text: e.Value}, operandValue{}, ""
+	case NumberLiteral:
+		return truthTrue, operandValue{text: e.Value}, operandValue{}, ""
+	case Call:
+		return evalCallExpr(e, ctx)
 	default:
-		return false, "", "", "unknown expression type"
+		return truthFalse, operandValue{}, operandValue{}, "unknown expression type"
 	}
 }
 
 // evalImplication evaluates an implication expression: A => B
 // Returns true if A is false OR B is true (logical implication)
-func evalImplication(impl Implication, ctx EvalContext) (passed bool, leftVal, rightVal, message string) {
+func evalImplication(impl Implication, ctx EvalContext) (outcome truth, leftVal, rightVal operandValue, message string) {
 	// Evaluate antecedent (left side)
-	antPassed, antLeft, antRight, _ := evalExpr(impl.Antecedent, ctx)
+	antOutcome, antLeft, antRight, antMessage := evalExpr(impl.Antecedent, ctx)
 
 	// Evaluate consequent (right side)
-	conPassed, conLeft, conRight, _ := evalExpr(impl.Consequent, ctx)
+	conOutcome, conLeft, conRight, conMessage := evalExpr(impl.Consequent, ctx)
 
 	// Implication truth table: A => B is true if A is false OR B is true
-	// (F,F) -> T, (F,T) -> T, (T,F) -> F, (T,T) -> T
-	passed = !antPassed || conPassed
+	// (F,F) -> T, (F,T) -> T, (T,F) -> F, (T,T) -> T; otherwise it is unknown
+	switch {
+	case antOutcome == truthFalse || conOutcome == truthTrue:
+		outcome = truthTrue
+	case antOutcome == truthTrue && conOutcome == truthFalse:
+		outcome = truthFalse
+	default:
+		outcome = truthUnknown
+	}
 
 	// For reporting, we want to show the values from both sides
 	// Left value comes from antecedent, right value from consequent
-	if antLeft != "" {
-		leftVal = antLeft
-	} else {
-		leftVal = antRight
+	leftVal = firstValue(antLeft, antRight)
+	rightVal = firstValue(conLeft, conRight)
+
+	switch {
+	case outcome == truthFalse:
+		message = fmt.Sprintf("condition '%s' is true but '%s' is false",
+			FormatRule(impl.Antecedent), FormatRule(impl.Consequent)) + callNote(ctx, collectCalls(impl.Consequent)...)
+	case outcome == truthUnknown && antOutcome == truthTrue:
+		message = fmt.Sprintf("condition '%s' is true but %s", FormatRule(impl.Antecedent), conMessage)
+	case outcome == truthUnknown:
+		message = fmt.Sprintf("%s, and '%s' is %s", antMessage, FormatRule(impl.Consequent), conOutcome)
+	}
+
+	return outcome, leftVal, rightVal, message
+}
+
+// evalAnd evaluates a conjunction: A && B
+// B is not evaluated when A is false; a failure reports the side that failed
+func evalAnd(and And, ctx EvalContext) (outcome truth, leftVal, rightVal operandValue, message string) {
+	left, leftVal, rightVal, message := evalExpr(and.Left, ctx)
+	if left == truthFalse {
+		return truthFalse, leftVal, rightVal, falseMessage(and.Left, message)
 	}
-	if conLeft != "" {
-		rightVal = conLeft
-	} else {
-		rightVal = conRight
+
+	right, rightLeft, rightRight, rightMessage := evalExpr(and.Right, ctx)
+	switch {
+	case right == truthFalse:
+		return truthFalse, rightLeft, rightRight, falseMessage(and.Right, rightMessage)
+	case left == truthUnknown:
+		return truthUnknown, leftVal, rightVal, message
+	case right == truthUnknown:
+		return truthUnknown, rightLeft, rightRight, rightMessage
 	}
+	return truthTrue, rightLeft, rightRight, ""
+}
 
-	if !passed {
-		message = fmt.Sprintf("condition '%s' is true but '%s' is false",
-			FormatRule(impl.Antecedent), FormatRule(impl.Consequent))
+// evalOr evaluates a disjunction: A || B
+// B is not evaluated when A is true
+func evalOr(or Or, ctx EvalContext) (outcome truth, leftVal, rightVal operandValue, message string) {
+	left, leftLeft, leftRight, leftMessage := evalExpr(or.Left, ctx)
+	if left == truthTrue {
+		return truthTrue, leftLeft, leftRight, ""
 	}
 
-	return passed, leftVal, rightVal, message
+	right, rightLeft, rightRight, rightMessage := evalExpr(or.Right, ctx)
+	if right == truthTrue {
+		return truthTrue, rightLeft, rightRight, ""
+	}
+
+	// Report the first value of each side, like an implication
+	leftVal = firstValue(leftLeft, leftRight)
+	rightVal = firstValue(rightLeft, rightRight)
+	message = fmt.Sprintf("neither '%s' nor '%s' is true", FormatRule(or.Left), FormatRule(or.Right))
+	switch {
+	case left == truthUnknown:
+		return truthUnknown, leftVal, rightVal, message + " (" + leftMessage + ")"
+	case right == truthUnknown:
+		return truthUnknown, leftVal, rightVal, message + " (" + rightMessage + ")"
+	}
+	return truthFalse, leftVal, rightVal, message + callNote(ctx, collectCalls(or)...)
+}
+
+// evalNot evaluates a negation: !A
+// The negation of an unknown condition is unknown
+func evalNot(not Not, ctx EvalContext) (outcome truth, leftVal, rightVal operandValue, message string) {
+	operandOutcome, leftVal, rightVal, operandMessage := evalExpr(not.Operand, ctx)
+	switch operandOutcome {
+	case truthUnknown:
+		return truthUnknown, leftVal, rightVal, operandMessage
+	case truthTrue:
+		operand := FormatRule(not.Operand)
+		if call, ok := not.Operand.(Call); ok {
+			operand = formatCall(call, ctx)
+		}
+		return truthFalse, leftVal, rightVal, fmt.Sprintf("'%s' is true", operand)
+	}
+	return truthTrue, leftVal, rightVal, ""
+}
+
+// falseMessage returns the message of a failed subexpression, or a generic
+// one for subexpressions that have none (e.g., a bare config reference)
+func falseMessage(expr RuleExpr, message string) string {
+	if message != "" {
+		return message
+	}
+	return fmt.Sprintf("'%s' is false", FormatRule(expr))
+}
+
+// nullMessage explains why cond is unknown: the first null operand among
+// operands (e.g., "'db.mode' is null, so 'db.mode != "live"' is unknown")
+func nullMessage(cond RuleExpr, ctx EvalContext, operands ...RuleExpr) string {
+	for _, operand := range operands {
+		if null := nullOperand(operand, ctx); null != nil {
+			return fmt.Sprintf("'%s' is null, so '%s' is unknown", FormatRule(null), FormatRule(cond))
+		}
+	}
+	return fmt.Sprintf("'%s' is unknown", FormatRule(cond))
 }
 
 // evalComparison evaluates a comparison expression: A == B or A != B
-func evalComparison(comp Comparison, ctx EvalContext) (passed bool, leftVal, rightVal, message string) {
-	leftVal = resolveValue(comp.Left, ctx)
-	rightVal = resolveValue(comp.Right, ctx)
+// A comparison with a null operand is unknown
+func evalComparison(comp Comparison, ctx EvalContext) (outcome truth, leftVal, rightVal operandValue, message string) {
+	left := resolveValue(comp.Left, ctx)
+	right := resolveValue(comp.Right, ctx)
+
+	// Report operands with sensitive values redacted
+	leftVal = reportOperand(comp.Left, ctx)
+	rightVal = reportOperand(comp.Right, ctx)
+	if leftVal.null || rightVal.null {
+		return truthUnknown, leftVal, rightVal, nullMessage(comp, ctx, comp.Left, comp.Right)
+	}
+
+	var passed bool
+	if comp.Operator.IsOrdering() {
+		cmp, err := compareValues(comp.Type, left, right, leftVal.text, rightVal.text)
+		if err != nil {
+			return truthFalse, leftVal, rightVal, err.Error()
+		}
+		switch comp.Operator {
+		case OpLess:
+			passed = cmp < 0
+		case OpLessEqual:
+			passed = cmp <= 0
+		case OpGreater:
+			passed = cmp > 0
+		case OpGreaterEqual:
+			passed = cmp >= 0
+		}
+		if !passed {
+			message = fmt.Sprintf("%s is not %s %s", leftVal, comp.Operator, rightVal) + callNote(ctx, comp.Left, comp.Right)
+		}
+		return truthOf(passed), leftVal, rightVal, message
+	}
+
+	// Typed operands are equal if they are the same number (e.g., 1.0 == 1),
+	// otherwise they are compared as strings
+	equal := left == right
+	if comp.Type != "" {
+		if cmp, err := compareValues(comp.Type, left, right, leftVal.text, rightVal.text); err == nil {
+			equal = cmp == 0
+		}
+	}
 
 	switch comp.Operator {
 	case OpEqual:
-		passed = leftVal == rightVal
+		passed = equal
 		if !passed {
-			message = fmt.Sprintf("'%s' != '%s'", leftVal, rightVal)
+			message = fmt.Sprintf("%s != %s", leftVal, rightVal) + callNote(ctx, comp.Left, comp.Right)
 		}
 	case OpNotEqual:
-		passed = leftVal != rightVal
+		passed = !equal
 		if !passed {
-			message = fmt.Sprintf("'%s' == '%s'", leftVal, rightVal)
+			message = fmt.Sprintf("%s == %s", leftVal, rightVal) + callNote(ctx, comp.Left, comp.Right)
 		}
 	default:
 		passed = false
 		message = fmt.Sprintf("unknown operator: %s", comp.Operator)
 	}
 
-	return passed, leftVal, rightVal, message
+	return truthOf(passed), leftVal, rightVal, message
+}
+
+// compareValues compares two values as numbers of type t, returning -1, 0
+// or 1. Untyped values compare as numbers if both are numbers, otherwise as
+// durations. Errors name the display values, never the raw ones.
+func compareValues(t ValueType, left, right, leftVal, rightVal string) (int, error) {
+	if t == "" {
+		t = ValueFloat
+		if (!isFloat(left) || !isFloat(right)) && (isValueOf(ValueDuration, left) || isValueOf(ValueDuration, right)) {
+			t = ValueDuration
+		}
+	}
+
+	switch t {
+	case ValueInt:
+		a, errA := strconv.ParseInt(left, 10, 64)
+		b, errB := strconv.ParseInt(right, 10, 64)
+		if errA == nil && errB == nil {
+			return compareOrdered(a, b), nil
+		}
+		// An int may be compared with a float literal
+		if isFloat(left) && isFloat(right) {
+			return compareValues(ValueFloat, left, right, leftVal, rightVal)
+		}
+	case ValueFloat:
+		a, errA := strconv.ParseFloat(left, 64)
+		b, errB := strconv.ParseFloat(right, 64)
+		if errA == nil && errB == nil {
+			return compareOrdered(a, b), nil
+		}
+	case ValueDuration:
+		a, errA := time.ParseDuration(left)
+		b, errB := time.ParseDuration(right)
+		if errA == nil && errB == nil {
+			return compareOrdered(a, b), nil
+		}
+	}
+
+	bad := leftVal
+	if isValueOf(t, left) {
+		bad = rightVal
+	}
+	return 0, fmt.Errorf("'%s' is not a valid %s", bad, t)
+}
+
+// isValueOf reports whether value parses as type t
+func isValueOf(t ValueType, value string) bool {
+	switch t {
+	case ValueInt, ValueFloat:
+		return isFloat(value)
+	case ValueDuration:
+		_, err := time.ParseDuration(value)
+		return err == nil
+	}
+	return false
+}
+
+// isFloat reports whether value is a number
+func isFloat(value string) bool {
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
+
+// compareOrdered returns -1, 0 or 1 as a is less than, equal to or greater than b
+func compareOrdered[T int64 | float64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// evalIn evaluates set membership: A in [x, y, ...]
+// It is unknown if A is null, or if A matches no item and an item is null
+func evalIn(in In, ctx EvalContext) (outcome truth, leftVal, rightVal operandValue, message string) {
+	value := resolveValue(in.Value, ctx)
+	leftVal = reportOperand(in.Value, ctx)
+
+	var passed bool
+	var nullItems []RuleExpr
+	items := make([]string, len(in.List))
+	for i, item := range in.List {
+		itemVal := reportOperand(item, ctx)
+		if itemVal.null {
+			nullItems = append(nullItems, item)
+		} else if resolveValue(item, ctx) == value {
+			passed = true
+		}
+		items[i] = itemVal.String()
+	}
+	rightVal = operandValue{text: "[" + strings.Join(items, ", ") + "]"}
+
+	switch {
+	case leftVal.null:
+		return truthUnknown, leftVal, rightVal, nullMessage(in, ctx, in.Value)
+	case !passed && len(nullItems) > 0:
+		return truthUnknown, leftVal, rightVal, nullMessage(in, ctx, nullItems...)
+	case !passed:
+		message = fmt.Sprintf("%s is not in %s", leftVal, rightVal.text) + callNote(ctx, append([]RuleExpr{in.Value}, in.List...)...)
+	}
+	return truthOf(passed), leftVal, rightVal, message
+}
+
+// evalMatch evaluates a regular expression match: A =~ "pattern"
+func evalMatch(match Match, ctx EvalContext) (outcome truth, leftVal, rightVal operandValue, message string) {
+	value := resolveValue(match.Value, ctx)
+	leftVal = reportOperand(match.Value, ctx)
+	rightVal = operandValue{text: match.Pattern}
+	if leftVal.null {
+		return truthUnknown, leftVal, rightVal, nullMessage(match, ctx, match.Value)
+	}
+
+	re := match.Regexp
+	if re == nil {
+		// Expressions built without ParseRule have no compiled pattern
+		var err error
+		if re, err = regexp.Compile(match.Pattern); err != nil {
+			return truthFalse, leftVal, rightVal, fmt.Sprintf("invalid regular expression: %v", err)
+		}
+	}
+
+	passed := re.MatchString(value)
+	if !passed {
+		message = fmt.Sprintf("%s does not match '%s'", leftVal, match.Pattern) + callNote(ctx, match.Value)
+	}
+	return truthOf(passed), leftVal, rightVal, message
+}
+
+// evalLike evaluates a glob match: A like "pattern"
+// Globs match as in environment contracts (see contract.MatchGlob)
+func evalLike(like Like, ctx EvalContext) (outcome truth, leftVal, rightVal operandValue, message string) {
+	value := resolveValue(like.Value, ctx)
+	leftVal = reportOperand(like.Value, ctx)
+	rightVal = operandValue{text: like.Pattern}
+	if leftVal.null {
+		return truthUnknown, leftVal, rightVal, nullMessage(like, ctx, like.Value)
+	}
+
+	passed := contract.MatchGlob(like.Pattern, value)
+	if !passed {
+		message = fmt.Sprintf("%s is not like '%s'", leftVal, like.Pattern) + callNote(ctx, like.Value)
+	}
+	return truthOf(passed), leftVal, rightVal, message
+}
+
+// evalIsNull evaluates a null check: A is null, A is not null
+// Unlike other conditions it is never unknown
+func evalIsNull(isNull IsNull, ctx EvalContext) (outcome truth, leftVal, rightVal operandValue, message string) {
+	leftVal = reportOperand(isNull.Value, ctx)
+	passed := leftVal.null != isNull.Negated
+	switch {
+	case passed:
+	case isNull.Negated:
+		message = fmt.Sprintf("'%s' is null", FormatRule(isNull.Value))
+	default:
+		message = fmt.Sprintf("'%s' is %s, not null", FormatRule(isNull.Value), leftVal)
+	}
+	return truthOf(passed), leftVal, operandValue{}, message
+}
+
+// evalCallExpr evaluates a function call used as a condition: a bool result
+// must be true, any other result must be non-empty
+func evalCallExpr(call Call, ctx EvalContext) (outcome truth, leftVal, rightVal operandValue, message string) {
+	leftVal = reportOperand(call, ctx)
+	if leftVal.null {
+		return truthUnknown, leftVal, operandValue{}, nullMessage(call, ctx, call)
+	}
+
+	val := evalCall(call, ctx)
+	passed := val != ""
+	if fn, _ := LookupFunction(call.Func); fn.Result == ValueBool {
+		passed = val == "true"
+	}
+	if !passed {
+		message = fmt.Sprintf("'%s' is false", formatCall(call, ctx))
+	}
+	return truthOf(passed), leftVal, operandValue{}, message
+}
+
+// displayValue returns an operand's value for reporting, redacting sensitive
+// config values and string results computed from them
+func displayValue(expr RuleExpr, val string, ctx EvalContext) string {
+	switch e := expr.(type) {
+	case ConfigRef:
+		if ctx.Sensitive[e.Path] {
+			return RedactedValue
+		}
+	case EnvRef:
+		if ctx.Sensitive[FormatRule(e)] {
+			return RedactedValue
+		}
+	case Call:
+		if callSensitive(e, ctx) {
+			return RedactedValue
+		}
+	}
+	return val
+}
+
+// reportOperand returns an operand's value for reporting: its display value,
+// or null
+func reportOperand(expr RuleExpr, ctx EvalContext) operandValue {
+	if nullOperand(expr, ctx) != nil {
+		return operandValue{null: true}
+	}
+	return operandValue{text: displayValue(expr, resolveValue(expr, ctx), ctx)}
+}
+
+// nullOperand returns the operand that makes expr null, or nil if expr is
+// not null. Unset config keys and environment variables are null, and so is
+// a call with a null argument, except to functions that take config keys.
+func nullOperand(expr RuleExpr, ctx EvalContext) RuleExpr {
+	switch e := expr.(type) {
+	case ConfigRef:
+		if _, ok := ctx.ConfigValues[e.Path]; !ok {
+			return e
+		}
+	case EnvRef:
+		if _, ok := ctx.Env[e.Name]; !ok {
+			return e
+		}
+	case Call:
+		if fn, _ := LookupFunction(e.Func); fn.KeyArgs {
+			return nil
+		}
+		for _, arg := range e.Args {
+			if null := nullOperand(arg, ctx); null != nil {
+				return null
+			}
+		}
+	}
+	return nil
+}
+
+// executionField returns the value of execution.<field>
+func executionField(field string, ctx EvalContext) string {
+	switch field {
+	case "env":
+		return ctx.ExecutionEnv
+	case "command":
+		return ctx.Command
+	case "args":
+		return strings.Join(ctx.Args, " ")
+	case "user":
+		return ctx.User
+	case "hostname":
+		return ctx.Hostname
+	case "cwd":
+		return ctx.Cwd
+	}
+	return ""
 }
 
 // resolveValue resolves a rule expression to its string value
+// Null operands resolve to ""; see nullOperand
 func resolveValue(expr RuleExpr, ctx EvalContext) string {
 	switch e := expr.(type) {
 	case ConfigRef:
@@ -130,22 +652,20 @@ func resolveValue(expr RuleExpr, ctx EvalContext) string {
 		return ""
 	case ExecutionEnv:
 		return ctx.ExecutionEnv
+	case ExecutionRef:
+		return executionField(e.Field, ctx)
+	case EnvRef:
+		return ctx.Env[e.Name]
 	case StringLiteral:
 		return e.Value
-	case Comparison:
-		// For nested comparisons, evaluate and return "true" or "false"
-		passed, _, _, _ := evalComparison(e, ctx)
-		if passed {
-			return "true"
-		}
-		return "false"
-	case Implication:
-		// For nested implications, evaluate and return "true" or "false"
-		passed, _, _, _ := evalImplication(e, ctx)
-		if passed {
-			return "true"
-		}
-		return "false"
+	case NumberLiteral:
+		return e.Value
+	case Call:
+		return evalCall(e, ctx)
+	case Implication, Comparison, And, Or, Not, In, Match, Like, IsNull:
+		// For nested conditions, evaluate and return "true", "false" or "unknown"
+		outcome, _, _, _ := evalExpr(e, ctx)
+		return outcome.String()
 	default:
 		return ""
 	}
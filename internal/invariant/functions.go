@@ -0,0 +1,248 @@
+package invariant
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Function is a built-in function that rules can call (e.g., len(api.token))
+type Function struct {
+	Name    string
+	Params  []ValueType // Argument types; ValueString accepts any operand except a bool
+	KeyArgs bool        // Arguments must be config references and are passed by path (e.g., exists)
+	Result  ValueType   // Type of the result
+	Secret  bool        // The result can reveal a sensitive argument, so it is redacted like one
+	Doc     string      // One-line description
+
+	// Eval computes the result from the arguments' values (their paths if KeyArgs)
+	Eval func(args []string, ctx EvalContext) string
+}
+
+// builtins is the registry of functions available in rules
+var builtins = map[string]Function{
+	"exists": {
+		Name:    "exists",
+		Params:  []ValueType{ValueString},
+		KeyArgs: true,
+		Result:  ValueBool,
+		Doc:     "true if the config key is set",
+		Eval: func(args []string, ctx EvalContext) string {
+			_, ok := ctx.ConfigValues[args[0]]
+			return strconv.FormatBool(ok)
+		},
+	},
+	"len": {
+		Name:   "len",
+		Params: []ValueType{ValueString},
+		Result: ValueInt,
+		Doc:    "number of characters in a value",
+		Eval: func(args []string, _ EvalContext) string {
+			return strconv.Itoa(utf8.RuneCountInString(args[0]))
+		},
+	},
+	"host": {
+		Name:   "host",
+		Params: []ValueType{ValueString},
+		Result: ValueString,
+		Doc:    "host of a URL or host:port, empty if the value has none",
+		Eval: func(args []string, _ EvalContext) string {
+			return hostOf(args[0])
+		},
+	},
+	"startsWith": {
+		Name:   "startsWith",
+		Params: []ValueType{ValueString, ValueString},
+		Result: ValueBool,
+		Doc:    "true if the first value starts with the second",
+		Eval: func(args []string, _ EvalContext) string {
+			return strconv.FormatBool(strings.HasPrefix(args[0], args[1]))
+		},
+	},
+	"lower": {
+		Name:   "lower",
+		Params: []ValueType{ValueString},
+		Result: ValueString,
+		Secret: true,
+		Doc:    "value in lower case",
+		Eval: func(args []string, _ EvalContext) string {
+			return strings.ToLower(args[0])
+		},
+	},
+}
+
+// LookupFunction returns the built-in function with the given name
+func LookupFunction(name string) (Function, bool) {
+	fn, ok := builtins[name]
+	return fn, ok
+}
+
+// Functions returns the built-in functions sorted by name
+func Functions() []Function {
+	fns := make([]Function, 0, len(builtins))
+	for _, fn := range builtins {
+		fns = append(fns, fn)
+	}
+	sort.Slice(fns, func(i, j int) bool { return fns[i].Name < fns[j].Name })
+	return fns
+}
+
+// functionNames returns the names of the built-in functions, sorted
+func functionNames() string {
+	var names []string
+	for _, fn := range Functions() {
+		names = append(names, fn.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// checkCall checks a call's function, arity and argument types
+func (p *parser) checkCall(call Call) error {
+	fn, ok := LookupFunction(call.Func)
+	if !ok {
+		return fmt.Errorf("unknown function '%s' (available: %s)", call.Func, functionNames())
+	}
+
+	if len(call.Args) != len(fn.Params) {
+		return fmt.Errorf("%s() takes %d argument(s), got %d", fn.Name, len(fn.Params), len(call.Args))
+	}
+
+	for i, arg := range call.Args {
+		if fn.KeyArgs {
+			if _, ok := arg.(ConfigRef); !ok {
+				return fmt.Errorf("%s() takes a config key, got %s", fn.Name, FormatRule(arg))
+			}
+			continue
+		}
+		if typ := p.operandType(arg); typ == ValueBool && fn.Params[i] != ValueBool {
+			return fmt.Errorf("argument %d of %s() is bool %s, want %s", i+1, fn.Name, FormatRule(arg), fn.Params[i])
+		}
+	}
+	return nil
+}
+
+// hostOf returns the host of a URL or host:port value, or "" if it has none
+func hostOf(value string) string {
+	if u, err := url.Parse(value); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		return host
+	}
+	return ""
+}
+
+// evalCall calls a built-in function with its evaluated arguments
+func evalCall(call Call, ctx EvalContext) string {
+	fn, ok := LookupFunction(call.Func)
+	if !ok {
+		return ""
+	}
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		if ref, ok := arg.(ConfigRef); ok && fn.KeyArgs {
+			args[i] = ref.Path
+			continue
+		}
+		args[i] = resolveValue(arg, ctx)
+	}
+	return fn.Eval(args, ctx)
+}
+
+// callSensitive reports whether a call's result may reveal a sensitive config
+// value. A host or a length does not; the value in lower case does.
+func callSensitive(call Call, ctx EvalContext) bool {
+	fn, _ := LookupFunction(call.Func)
+	if !fn.Secret {
+		return false
+	}
+	for _, arg := range call.Args {
+		switch a := arg.(type) {
+		case ConfigRef:
+			if ctx.Sensitive[a.Path] {
+				return true
+			}
+		case EnvRef:
+			if ctx.Sensitive[FormatRule(a)] {
+				return true
+			}
+		case Call:
+			if callSensitive(a, ctx) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// formatCall formats a call with its evaluated arguments for messages
+// (e.g., host('postgres://db/app')). Key arguments are shown by path.
+func formatCall(call Call, ctx EvalContext) string {
+	fn, _ := LookupFunction(call.Func)
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		switch a := arg.(type) {
+		case Call:
+			args[i] = formatCall(a, ctx)
+		case ConfigRef:
+			if fn.KeyArgs {
+				args[i] = a.Path
+				continue
+			}
+			args[i] = reportOperand(a, ctx).String()
+		default:
+			args[i] = reportOperand(a, ctx).String()
+		}
+	}
+	return fmt.Sprintf("%s(%s)", call.Func, strings.Join(args, ", "))
+}
+
+// collectCalls returns the outermost calls in an expression
+func collectCalls(expr RuleExpr) []RuleExpr {
+	switch e := expr.(type) {
+	case Call:
+		return []RuleExpr{e}
+	case Implication:
+		return append(collectCalls(e.Antecedent), collectCalls(e.Consequent)...)
+	case And:
+		return append(collectCalls(e.Left), collectCalls(e.Right)...)
+	case Or:
+		return append(collectCalls(e.Left), collectCalls(e.Right)...)
+	case Not:
+		return collectCalls(e.Operand)
+	case Comparison:
+		return append(collectCalls(e.Left), collectCalls(e.Right)...)
+	case In:
+		calls := collectCalls(e.Value)
+		for _, item := range e.List {
+			calls = append(calls, collectCalls(item)...)
+		}
+		return calls
+	case Match:
+		return collectCalls(e.Value)
+	case Like:
+		return collectCalls(e.Value)
+	case IsNull:
+		return collectCalls(e.Value)
+	}
+	return nil
+}
+
+// callNote describes the calls among operands with their evaluated arguments,
+// for appending to a failure message (e.g., " (len('abc') = '3')")
+func callNote(ctx EvalContext, operands ...RuleExpr) string {
+	var notes []string
+	for _, operand := range operands {
+		if call, ok := operand.(Call); ok {
+			notes = append(notes, fmt.Sprintf("%s = %s", formatCall(call, ctx), reportOperand(call, ctx)))
+		}
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(notes, ", ") + ")"
+}
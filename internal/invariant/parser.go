@@ -2,7 +2,10 @@ package invariant
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 )
 
@@ -14,9 +17,32 @@ const (
 	tokenIdent
 	tokenString
 	tokenDot
-	tokenImply    // => or ⇒
-	tokenEqual    // ==
-	tokenNotEqual // !=
+	tokenImply        // => or ⇒
+	tokenEqual        // ==
+	tokenNotEqual     // !=
+	tokenAnd          // &&
+	tokenOr           // ||
+	tokenNot          // !
+	tokenLParen       // (
+	tokenRParen       // )
+	tokenMatch        // =~
+	tokenLBracket     // [
+	tokenRBracket     // ]
+	tokenComma        // ,
+	tokenLess         // <
+	tokenLessEqual    // <=
+	tokenGreater      // >
+	tokenGreaterEqual // >=
+	tokenNumber       // 10, -2.5, 30s
+)
+
+// Keywords recognized in operator position
+const (
+	keywordIn   = "in"
+	keywordLike = "like"
+	keywordIs   = "is"
+	keywordNot  = "not"
+	keywordNull = "null"
 )
 
 // token represents a lexical token
@@ -87,6 +113,26 @@ func (l *lexer) nextToken() (token, error) {
 		l.advance(2)
 		return token{typ: tokenNotEqual, value: "!="}, nil
 	}
+	if l.peekN(2) == "<=" {
+		l.advance(2)
+		return token{typ: tokenLessEqual, value: "<="}, nil
+	}
+	if l.peekN(2) == ">=" {
+		l.advance(2)
+		return token{typ: tokenGreaterEqual, value: ">="}, nil
+	}
+	if l.peekN(2) == "=~" {
+		l.advance(2)
+		return token{typ: tokenMatch, value: "=~"}, nil
+	}
+	if l.peekN(2) == "&&" {
+		l.advance(2)
+		return token{typ: tokenAnd, value: "&&"}, nil
+	}
+	if l.peekN(2) == "||" {
+		l.advance(2)
+		return token{typ: tokenOr, value: "||"}, nil
+	}
 
 	// Check for Unicode implication arrow ⇒ (3 bytes in UTF-8)
 	if strings.HasPrefix(l.input[l.pos:], "⇒") {
@@ -96,10 +142,40 @@ func (l *lexer) nextToken() (token, error) {
 
 	ch := l.peek()
 
-	// Dot
-	if ch == '.' {
+	// Single-character operators
+	switch ch {
+	case '.':
 		l.advance(1)
 		return token{typ: tokenDot, value: "."}, nil
+	case '!':
+		l.advance(1)
+		return token{typ: tokenNot, value: "!"}, nil
+	case '(':
+		l.advance(1)
+		return token{typ: tokenLParen, value: "("}, nil
+	case ')':
+		l.advance(1)
+		return token{typ: tokenRParen, value: ")"}, nil
+	case '[':
+		l.advance(1)
+		return token{typ: tokenLBracket, value: "["}, nil
+	case ']':
+		l.advance(1)
+		return token{typ: tokenRBracket, value: "]"}, nil
+	case ',':
+		l.advance(1)
+		return token{typ: tokenComma, value: ","}, nil
+	case '<':
+		l.advance(1)
+		return token{typ: tokenLess, value: "<"}, nil
+	case '>':
+		l.advance(1)
+		return token{typ: tokenGreater, value: ">"}, nil
+	}
+
+	// Number or duration, optionally negative
+	if isDigit(ch) || (ch == '-' && isDigit(l.peekAt(1))) {
+		return l.readNumber(), nil
 	}
 
 	// String literal
@@ -134,6 +210,25 @@ func (l *lexer) readString() (token, error) {
 	return token{typ: tokenString, value: value}, nil
 }
 
+// readNumber reads a number or duration literal: digits, '.', and unit or
+// exponent letters (e.g., "10", "-2.5", "1e3", "1h30m"). Its form is checked by the parser.
+func (l *lexer) readNumber() token {
+	start := l.pos
+	l.advance(1) // digit or sign
+	for l.pos < len(l.input) && (isIdentChar(l.input[l.pos]) || l.input[l.pos] == '.') && l.input[l.pos] != '-' {
+		l.advance(1)
+	}
+	return token{typ: tokenNumber, value: l.input[start:l.pos]}
+}
+
+// peekAt returns the character at offset from the current position without advancing
+func (l *lexer) peekAt(offset int) byte {
+	if l.pos+offset >= len(l.input) {
+		return 0
+	}
+	return l.input[l.pos+offset]
+}
+
 // readIdent reads an identifier
 func (l *lexer) readIdent() token {
 	start := l.pos
@@ -143,6 +238,11 @@ func (l *lexer) readIdent() token {
 	return token{typ: tokenIdent, value: l.input[start:l.pos]}
 }
 
+// isDigit returns true if ch is a decimal digit
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
 // isIdentStart returns true if ch can start an identifier
 func isIdentStart(ch byte) bool {
 	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_'
@@ -158,11 +258,13 @@ func isIdentChar(ch byte) bool {
 type parser struct {
 	lexer   *lexer
 	current token
+	types   map[string]ValueType // Types of config keys (nil if unknown)
+	keys    map[string]bool      // Config keys, which take precedence over the execution. and env. namespaces
 }
 
 // newParser creates a new parser for the given input
-func newParser(input string) (*parser, error) {
-	p := &parser{lexer: newLexer(input)}
+func newParser(input string, types map[string]ValueType) (*parser, error) {
+	p := &parser{lexer: newLexer(input), types: types}
 	// Prime the parser with the first token
 	tok, err := p.lexer.nextToken()
 	if err != nil {
@@ -185,15 +287,38 @@ func (p *parser) advance() error {
 // ParseRule parses a rule expression string into an AST
 // configKeys is used to validate that all config references exist
 func ParseRule(rule string, configKeys []string) (RuleExpr, error) {
+	return parseRule(rule, configKeys, nil)
+}
+
+// ParseTypedRule parses a rule like ParseRule, where keyTypes maps each config
+// key to its schema type. Comparisons with a numeric side are typed, and
+// comparisons that mix enums or strings with numbers are rejected.
+func ParseTypedRule(rule string, keyTypes map[string]ValueType) (RuleExpr, error) {
+	var configKeys []string
+	for key := range keyTypes {
+		configKeys = append(configKeys, key)
+	}
+	if keyTypes != nil && configKeys == nil {
+		configKeys = []string{}
+	}
+	return parseRule(rule, configKeys, keyTypes)
+}
+
+// parseRule parses and validates a rule string
+func parseRule(rule string, configKeys []string, types map[string]ValueType) (RuleExpr, error) {
 	rule = strings.TrimSpace(rule)
 	if rule == "" {
 		return nil, fmt.Errorf("empty rule expression")
 	}
 
-	p, err := newParser(rule)
+	p, err := newParser(rule, types)
 	if err != nil {
 		return nil, err
 	}
+	p.keys = make(map[string]bool, len(configKeys))
+	for _, key := range configKeys {
+		p.keys[key] = true
+	}
 
 	expr, err := p.parseRule()
 	if err != nil {
@@ -210,14 +335,18 @@ func ParseRule(rule string, configKeys []string) (RuleExpr, error) {
 		if err := ValidateRuleRefs(expr, configKeys); err != nil {
 			return nil, err
 		}
+	} else if err := validateContextRefs(expr); err != nil {
+		return nil, err
 	}
 
 	return expr, nil
 }
 
-// parseRule parses a rule (implication or comparison)
+// parseRule parses a rule. Operators bind from loosest to tightest:
+// =>, ||, &&, !, then comparisons. && and || are left-associative; a rule
+// has at most one => outside parentheses.
 func (p *parser) parseRule() (RuleExpr, error) {
-	left, err := p.parseComparison()
+	left, err := p.parseOr()
 	if err != nil {
 		return nil, err
 	}
@@ -227,7 +356,7 @@ func (p *parser) parseRule() (RuleExpr, error) {
 		if err := p.advance(); err != nil {
 			return nil, err
 		}
-		right, err := p.parseComparison()
+		right, err := p.parseOr()
 		if err != nil {
 			return nil, err
 		}
@@ -237,6 +366,84 @@ func (p *parser) parseRule() (RuleExpr, error) {
 	return left, nil
 }
 
+// parseOr parses a disjunction: A || B || ...
+func (p *parser) parseOr() (RuleExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.current.typ == tokenOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+// parseAnd parses a conjunction: A && B && ...
+func (p *parser) parseAnd() (RuleExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.current.typ == tokenAnd {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+// parseUnary parses a negation, a parenthesized rule or a comparison
+func (p *parser) parseUnary() (RuleExpr, error) {
+	switch p.current.typ {
+	case tokenNot:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Operand: operand}, nil
+
+	case tokenLParen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseRule()
+		if err != nil {
+			return nil, err
+		}
+		if p.current.typ != tokenRParen {
+			if p.current.typ == tokenEOF {
+				return nil, fmt.Errorf("missing closing ')'")
+			}
+			return nil, fmt.Errorf("expected ')', got '%s'", p.current.value)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	return p.parseComparison()
+}
+
 // parseComparison parses a comparison expression
 func (p *parser) parseComparison() (RuleExpr, error) {
 	left, err := p.parseOperand()
@@ -245,11 +452,7 @@ func (p *parser) parseComparison() (RuleExpr, error) {
 	}
 
 	// Check for comparison operator
-	if p.current.typ == tokenEqual || p.current.typ == tokenNotEqual {
-		op := OpEqual
-		if p.current.typ == tokenNotEqual {
-			op = OpNotEqual
-		}
+	if op, ok := comparisonOps[p.current.typ]; ok {
 		if err := p.advance(); err != nil {
 			return nil, err
 		}
@@ -257,12 +460,202 @@ func (p *parser) parseComparison() (RuleExpr, error) {
 		if err != nil {
 			return nil, err
 		}
-		return Comparison{Left: left, Right: right, Operator: op}, nil
+		comp := Comparison{Left: left, Right: right, Operator: op}
+		if comp.Type, err = p.comparisonType(comp); err != nil {
+			return nil, err
+		}
+		return comp, nil
+	}
+
+	switch {
+	case p.current.typ == tokenMatch:
+		pattern, err := p.parsePattern("=~")
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %v", err)
+		}
+		return Match{Value: left, Pattern: pattern, Regexp: re}, nil
+
+	case p.current.typ == tokenIdent && p.current.value == keywordLike:
+		pattern, err := p.parsePattern(keywordLike)
+		if err != nil {
+			return nil, err
+		}
+		return Like{Value: left, Pattern: pattern}, nil
+
+	case p.current.typ == tokenIdent && p.current.value == keywordIn:
+		list, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return In{Value: left, List: list}, nil
+
+	case p.current.typ == tokenIdent && p.current.value == keywordIs:
+		return p.parseIsNull(left)
 	}
 
 	return left, nil
 }
 
+// parseIsNull parses the rest of a null check after its operand:
+// is null, or is not null
+func (p *parser) parseIsNull(value RuleExpr) (RuleExpr, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	isNull := IsNull{Value: value}
+	if p.current.typ == tokenIdent && p.current.value == keywordNot {
+		isNull.Negated = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.current.typ != tokenIdent || p.current.value != keywordNull {
+		return nil, fmt.Errorf("expected 'null' or 'not null' after 'is', got '%s'", p.current.value)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	return isNull, nil
+}
+
+// comparisonOps maps comparison tokens to their operators
+var comparisonOps = map[tokenType]CompOp{
+	tokenEqual:        OpEqual,
+	tokenNotEqual:     OpNotEqual,
+	tokenLess:         OpLess,
+	tokenLessEqual:    OpLessEqual,
+	tokenGreater:      OpGreater,
+	tokenGreaterEqual: OpGreaterEqual,
+}
+
+// comparisonType checks that a comparison's operands can be compared and
+// returns the numeric type they compare as (empty to compare strings).
+// Enums never compare with numbers, durations only with durations, and
+// ordering operators need numeric operands.
+func (p *parser) comparisonType(comp Comparison) (ValueType, error) {
+	left, right := p.operandType(comp.Left), p.operandType(comp.Right)
+
+	mismatch := func(a, b ValueType) bool {
+		return ((a == ValueEnum || a == ValueBool) && b.IsNumeric()) || (a == ValueDuration && b.IsNumeric() && b != ValueDuration)
+	}
+	if mismatch(left, right) || mismatch(right, left) {
+		return "", fmt.Errorf("cannot compare %s %s with %s %s", left, FormatRule(comp.Left), right, FormatRule(comp.Right))
+	}
+
+	if comp.Operator.IsOrdering() {
+		for _, side := range []struct {
+			expr RuleExpr
+			typ  ValueType
+		}{{comp.Left, left}, {comp.Right, right}} {
+			if side.typ == ValueString || side.typ == ValueEnum || side.typ == ValueBool {
+				return "", fmt.Errorf("'%s' compares numbers or durations, not %s %s", comp.Operator, side.typ, FormatRule(side.expr))
+			}
+		}
+	}
+
+	switch {
+	case left == ValueDuration || right == ValueDuration:
+		return ValueDuration, nil
+	case left == ValueFloat || right == ValueFloat:
+		return ValueFloat, nil
+	case left == ValueInt || right == ValueInt:
+		return ValueInt, nil
+	}
+	return "", nil
+}
+
+// operandType returns the type of an operand, or "" if it is not known
+func (p *parser) operandType(expr RuleExpr) ValueType {
+	switch e := expr.(type) {
+	case ConfigRef:
+		return p.types[e.Path]
+	case NumberLiteral:
+		return e.Type
+	case ExecutionRef, EnvRef:
+		return ValueString
+	case Call:
+		fn, _ := LookupFunction(e.Func)
+		return fn.Result
+	case StringLiteral, ExecutionEnv:
+		return ValueString
+	}
+	return ""
+}
+
+// numberType returns the type of a number literal: int, float or duration
+func numberType(literal string) (ValueType, bool) {
+	if _, err := strconv.ParseInt(literal, 10, 64); err == nil {
+		return ValueInt, true
+	}
+	if _, err := strconv.ParseFloat(literal, 64); err == nil {
+		return ValueFloat, true
+	}
+	if _, err := time.ParseDuration(literal); err == nil {
+		return ValueDuration, true
+	}
+	return "", false
+}
+
+// parsePattern parses the quoted pattern after a =~ or like operator
+func (p *parser) parsePattern(op string) (string, error) {
+	if err := p.advance(); err != nil {
+		return "", err
+	}
+	if p.current.typ != tokenString {
+		return "", fmt.Errorf("expected a quoted pattern after '%s', got '%s'", op, p.current.value)
+	}
+	pattern := p.current.value
+	if err := p.advance(); err != nil {
+		return "", err
+	}
+	return pattern, nil
+}
+
+// parseList parses the bracketed operand list after an in operator: ["a", b.c]
+func (p *parser) parseList() ([]RuleExpr, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.current.typ != tokenLBracket {
+		return nil, fmt.Errorf("expected '[' after 'in', got '%s'", p.current.value)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.current.typ == tokenRBracket {
+		return nil, fmt.Errorf("empty list after 'in'")
+	}
+
+	var list []RuleExpr
+	for {
+		item, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+
+		switch p.current.typ {
+		case tokenComma:
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		case tokenRBracket:
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			return list, nil
+		case tokenEOF:
+			return nil, fmt.Errorf("missing closing ']'")
+		default:
+			return nil, fmt.Errorf("expected ',' or ']' in list, got '%s'", p.current.value)
+		}
+	}
+}
+
 // parseOperand parses an operand (config ref, execution.env, or string literal)
 func (p *parser) parseOperand() (RuleExpr, error) {
 	switch p.current.typ {
@@ -273,14 +666,71 @@ func (p *parser) parseOperand() (RuleExpr, error) {
 		}
 		return StringLiteral{Value: value}, nil
 
+	case tokenNumber:
+		literal := p.current.value
+		typ, ok := numberType(literal)
+		if !ok {
+			return nil, fmt.Errorf("invalid number '%s'", literal)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return NumberLiteral{Value: literal, Type: typ}, nil
+
 	case tokenIdent:
-		return p.parseRef()
+		ref, err := p.parseRef()
+		if err != nil {
+			return nil, err
+		}
+		// A single identifier followed by '(' is a function call
+		if name, ok := ref.(ConfigRef); ok && p.current.typ == tokenLParen && !strings.Contains(name.Path, ".") {
+			return p.parseCall(name.Path)
+		}
+		return ref, nil
 
 	default:
 		return nil, fmt.Errorf("expected operand, got '%s'", p.current.value)
 	}
 }
 
+// parseCall parses the arguments of a call to the named function; the
+// current token is the opening '('
+func (p *parser) parseCall(name string) (RuleExpr, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	call := Call{Func: name}
+	for p.current.typ != tokenRParen {
+		if len(call.Args) > 0 {
+			if p.current.typ != tokenComma {
+				if p.current.typ == tokenEOF {
+					return nil, fmt.Errorf("missing closing ')' in call to %s()", name)
+				}
+				return nil, fmt.Errorf("expected ',' or ')' in call to %s()", name)
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		if p.current.typ == tokenEOF {
+			return nil, fmt.Errorf("missing closing ')' in call to %s()", name)
+		}
+		arg, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if err := p.checkCall(call); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
 
 // parseRef parses a config reference or execution.env
 func (p *parser) parseRef() (RuleExpr, error) {
@@ -307,34 +757,136 @@ func (p *parser) parseRef() (RuleExpr, error) {
 
 	path := strings.Join(parts, ".")
 
+	// null is not a value that compares equal to unset keys
+	if path == keywordNull && !p.keys[path] {
+		return nil, fmt.Errorf("'null' is not a value; use 'is null' or 'is not null'")
+	}
+
 	// Check for special execution.env reference
 	if path == "execution.env" {
 		return ExecutionEnv{}, nil
 	}
 
+	// The execution context and the environment, unless a config key has the path
+	if len(parts) > 1 && !p.keys[path] {
+		switch parts[0] {
+		case "execution":
+			return ExecutionRef{Field: strings.Join(parts[1:], ".")}, nil
+		case "env":
+			return EnvRef{Name: strings.Join(parts[1:], ".")}, nil
+		}
+	}
+
 	return ConfigRef{Path: path}, nil
 }
 
-// FormatRule formats a RuleExpr back to a string representation
+// Operator precedence for formatting, from loosest to tightest
+const (
+	precImply = iota + 1
+	precOr
+	precAnd
+	precNot
+	precComparison
+)
+
+// precedence returns how tightly an expression binds
+func precedence(expr RuleExpr) int {
+	switch expr.(type) {
+	case Implication:
+		return precImply
+	case Or:
+		return precOr
+	case And:
+		return precAnd
+	case Not:
+		return precNot
+	default:
+		return precComparison
+	}
+}
+
+// isOperand reports whether expr is a single value rather than an operation
+func isOperand(expr RuleExpr) bool {
+	switch expr.(type) {
+	case ConfigRef, ExecutionEnv, ExecutionRef, EnvRef, StringLiteral, NumberLiteral, Call:
+		return true
+	}
+	return false
+}
+
+// formatOperand formats expr, parenthesized if it binds looser than min
+func formatOperand(expr RuleExpr, min int) string {
+	if precedence(expr) < min {
+		return "(" + FormatRule(expr) + ")"
+	}
+	return FormatRule(expr)
+}
+
+// FormatRule formats a RuleExpr back to a string representation.
+// Parentheses are added only where precedence requires them, so the
+// result parses back to the same expression.
 func FormatRule(expr RuleExpr) string {
 	switch e := expr.(type) {
 	case Implication:
-		return fmt.Sprintf("%s => %s", FormatRule(e.Antecedent), FormatRule(e.Consequent))
+		return fmt.Sprintf("%s => %s", formatOperand(e.Antecedent, precOr), formatOperand(e.Consequent, precOr))
+	case Or:
+		return fmt.Sprintf("%s || %s", formatOperand(e.Left, precOr), formatOperand(e.Right, precAnd))
+	case And:
+		return fmt.Sprintf("%s && %s", formatOperand(e.Left, precAnd), formatOperand(e.Right, precNot))
+	case Not:
+		// Comparisons are parenthesized for readability: !(a == "b")
+		if precedence(e.Operand) == precComparison && !isOperand(e.Operand) {
+			return "!(" + FormatRule(e.Operand) + ")"
+		}
+		return "!" + formatOperand(e.Operand, precNot)
 	case Comparison:
 		return fmt.Sprintf("%s %s %s", FormatRule(e.Left), e.Operator, FormatRule(e.Right))
+	case In:
+		items := make([]string, len(e.List))
+		for i, item := range e.List {
+			items[i] = FormatRule(item)
+		}
+		return fmt.Sprintf("%s in [%s]", FormatRule(e.Value), strings.Join(items, ", "))
+	case Match:
+		return fmt.Sprintf(`%s =~ "%s"`, FormatRule(e.Value), e.Pattern)
+	case Like:
+		return fmt.Sprintf(`%s like "%s"`, FormatRule(e.Value), e.Pattern)
+	case IsNull:
+		if e.Negated {
+			return FormatRule(e.Value) + " is not null"
+		}
+		return FormatRule(e.Value) + " is null"
 	case ConfigRef:
 		return e.Path
 	case ExecutionEnv:
 		return "execution.env"
+	case ExecutionRef:
+		return "execution." + e.Field
+	case EnvRef:
+		return "env." + e.Name
 	case StringLiteral:
 		return fmt.Sprintf(`"%s"`, e.Value)
+	case NumberLiteral:
+		return e.Value
+	case Call:
+		args := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = FormatRule(arg)
+		}
+		return fmt.Sprintf("%s(%s)", e.Func, strings.Join(args, ", "))
 	default:
 		return "<unknown>"
 	}
 }
 
-// ValidateRuleRefs validates that all config references in the expression exist in the schema
+// ValidateRuleRefs validates that all config references in the expression exist
+// in the schema, that execution.* fields exist, and that env.* names are
+// environment variable names
 func ValidateRuleRefs(expr RuleExpr, configKeys []string) error {
+	if err := validateContextRefs(expr); err != nil {
+		return err
+	}
+
 	refs := collectConfigRefs(expr)
 	keySet := make(map[string]bool)
 	for _, k := range configKeys {
@@ -355,6 +907,80 @@ func ValidateRuleRefs(expr RuleExpr, configKeys []string) error {
 	return nil
 }
 
+// ExecutionFields are the fields of the execution context rules can reference
+// as execution.<field>
+var ExecutionFields = []string{"args", "command", "cwd", "env", "hostname", "user"}
+
+// envNameRegex matches environment variable names
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateContextRefs checks execution.* and env.* references
+func validateContextRefs(expr RuleExpr) error {
+	var err error
+	walkExpr(expr, func(e RuleExpr) {
+		if err != nil {
+			return
+		}
+		switch ref := e.(type) {
+		case ExecutionRef:
+			if !isExecutionField(ref.Field) {
+				err = fmt.Errorf("unknown execution field 'execution.%s' (available: execution.%s)", ref.Field, strings.Join(ExecutionFields, ", execution."))
+			}
+		case EnvRef:
+			if !envNameRegex.MatchString(ref.Name) {
+				err = fmt.Errorf("invalid environment variable name in 'env.%s'", ref.Name)
+			}
+		}
+	})
+	return err
+}
+
+// isExecutionField reports whether field is one of ExecutionFields
+func isExecutionField(field string) bool {
+	for _, f := range ExecutionFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// walkExpr calls fn for expr and each of its subexpressions
+func walkExpr(expr RuleExpr, fn func(RuleExpr)) {
+	fn(expr)
+	switch e := expr.(type) {
+	case Implication:
+		walkExpr(e.Antecedent, fn)
+		walkExpr(e.Consequent, fn)
+	case Comparison:
+		walkExpr(e.Left, fn)
+		walkExpr(e.Right, fn)
+	case And:
+		walkExpr(e.Left, fn)
+		walkExpr(e.Right, fn)
+	case Or:
+		walkExpr(e.Left, fn)
+		walkExpr(e.Right, fn)
+	case Not:
+		walkExpr(e.Operand, fn)
+	case In:
+		walkExpr(e.Value, fn)
+		for _, item := range e.List {
+			walkExpr(item, fn)
+		}
+	case Match:
+		walkExpr(e.Value, fn)
+	case Like:
+		walkExpr(e.Value, fn)
+	case IsNull:
+		walkExpr(e.Value, fn)
+	case Call:
+		for _, arg := range e.Args {
+			walkExpr(arg, fn)
+		}
+	}
+}
+
 // collectConfigRefs walks the AST and collects all ConfigRef paths
 func collectConfigRefs(expr RuleExpr) []string {
 	var refs []string
@@ -366,11 +992,34 @@ func collectConfigRefs(expr RuleExpr) []string {
 	case Comparison:
 		refs = append(refs, collectConfigRefs(e.Left)...)
 		refs = append(refs, collectConfigRefs(e.Right)...)
+	case And:
+		refs = append(refs, collectConfigRefs(e.Left)...)
+		refs = append(refs, collectConfigRefs(e.Right)...)
+	case Or:
+		refs = append(refs, collectConfigRefs(e.Left)...)
+		refs = append(refs, collectConfigRefs(e.Right)...)
+	case Not:
+		refs = append(refs, collectConfigRefs(e.Operand)...)
+	case In:
+		refs = append(refs, collectConfigRefs(e.Value)...)
+		for _, item := range e.List {
+			refs = append(refs, collectConfigRefs(item)...)
+		}
+	case Match:
+		refs = append(refs, collectConfigRefs(e.Value)...)
+	case Like:
+		refs = append(refs, collectConfigRefs(e.Value)...)
+	case IsNull:
+		refs = append(refs, collectConfigRefs(e.Value)...)
+	case Call:
+		for _, arg := range e.Args {
+			refs = append(refs, collectConfigRefs(arg)...)
+		}
 	case ConfigRef:
 		refs = append(refs, e.Path)
-	case ExecutionEnv:
+	case ExecutionEnv, ExecutionRef, EnvRef:
 		// Not a config ref
-	case StringLiteral:
+	case StringLiteral, NumberLiteral:
 		// Not a config ref
 	}
 
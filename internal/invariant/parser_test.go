@@ -2,6 +2,7 @@ package invariant
 
 import (
 	"reflect"
+	"regexp"
 	"testing"
 
 	"github.com/leanovate/gopter"
@@ -119,6 +120,66 @@ func TestParseRule_Errors(t *testing.T) {
 			rule:    `db. == "prod"`,
 			wantErr: "expected identifier after '.'",
 		},
+		{
+			name:    "missing closing parenthesis",
+			rule:    `(a == "x" && b == "y"`,
+			wantErr: "missing closing ')'",
+		},
+		{
+			name:    "unbalanced closing parenthesis",
+			rule:    `a == "x")`,
+			wantErr: "unexpected token ')'",
+		},
+		{
+			name:    "missing operand after &&",
+			rule:    `a == "x" &&`,
+			wantErr: "expected operand",
+		},
+		{
+			name:    "single ampersand",
+			rule:    `a == "x" & b == "y"`,
+			wantErr: "unexpected character '&'",
+		},
+		{
+			name:    "chained implication",
+			rule:    `a == "x" => b == "y" => c == "z"`,
+			wantErr: "unexpected token '=>'",
+		},
+		{
+			name:    "empty in list",
+			rule:    `a in []`,
+			wantErr: "empty list after 'in'",
+		},
+		{
+			name:    "in without brackets",
+			rule:    `a in "x"`,
+			wantErr: "expected '[' after 'in'",
+		},
+		{
+			name:    "unterminated in list",
+			rule:    `a in ["x", "y"`,
+			wantErr: "missing closing ']'",
+		},
+		{
+			name:    "in list without commas",
+			rule:    `a in ["x" "y"]`,
+			wantErr: "expected ',' or ']' in list",
+		},
+		{
+			name:    "regex pattern must be quoted",
+			rule:    `a =~ b`,
+			wantErr: "expected a quoted pattern after '=~'",
+		},
+		{
+			name:    "invalid regex",
+			rule:    `a =~ "[prod"`,
+			wantErr: "invalid regular expression: error parsing regexp: missing closing ]",
+		},
+		{
+			name:    "glob pattern must be quoted",
+			rule:    `a like b`,
+			wantErr: "expected a quoted pattern after 'like'",
+		},
 	}
 
 	for _, tt := range tests {
@@ -148,6 +209,284 @@ func containsHelper(s, substr string) bool {
 	return false
 }
 
+func TestParseRule_BooleanOperators(t *testing.T) {
+	eq := func(path, value string) RuleExpr {
+		return Comparison{Left: ConfigRef{Path: path}, Right: StringLiteral{Value: value}, Operator: OpEqual}
+	}
+	env := Comparison{Left: ExecutionEnv{}, Right: StringLiteral{Value: "prod"}, Operator: OpEqual}
+
+	tests := []struct {
+		name string
+		rule string
+		want RuleExpr
+	}{
+		{
+			name: "and within implication",
+			rule: `execution.env == "prod" && payments.mode == "live" => db.env == "prod"`,
+			want: Implication{Antecedent: And{Left: env, Right: eq("payments.mode", "live")}, Consequent: eq("db.env", "prod")},
+		},
+		{
+			name: "and binds tighter than or",
+			rule: `a == "1" || b == "2" && c == "3"`,
+			want: Or{Left: eq("a", "1"), Right: And{Left: eq("b", "2"), Right: eq("c", "3")}},
+		},
+		{
+			name: "left-associative",
+			rule: `a == "1" || b == "2" || c == "3"`,
+			want: Or{Left: Or{Left: eq("a", "1"), Right: eq("b", "2")}, Right: eq("c", "3")},
+		},
+		{
+			name: "parentheses override precedence",
+			rule: `(a == "1" || b == "2") && c == "3"`,
+			want: And{Left: Or{Left: eq("a", "1"), Right: eq("b", "2")}, Right: eq("c", "3")},
+		},
+		{
+			name: "not applies to a comparison",
+			rule: `!a == "1" && !(b == "2" || c == "3")`,
+			want: And{Left: Not{Operand: eq("a", "1")}, Right: Not{Operand: Or{Left: eq("b", "2"), Right: eq("c", "3")}}},
+		},
+		{
+			name: "double negation and bare reference",
+			rule: `!!feature.enabled`,
+			want: Not{Operand: Not{Operand: ConfigRef{Path: "feature.enabled"}}},
+		},
+		{
+			name: "implication in parentheses",
+			rule: `(a == "1" => b == "2") && c == "3"`,
+			want: And{Left: Implication{Antecedent: eq("a", "1"), Consequent: eq("b", "2")}, Right: eq("c", "3")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRule(tt.rule, nil)
+			if err != nil {
+				t.Fatalf("ParseRule() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// References inside every operator are validated
+	_, err := ParseRule(`a == "1" && !(b == "2" || missing == "3")`, []string{"a", "b"})
+	if err == nil || !contains(err.Error(), "undefined config key(s): missing") {
+		t.Errorf("error = %v, want undefined key error", err)
+	}
+}
+
+func TestParseRule_SetAndPatternOperators(t *testing.T) {
+	mode := ConfigRef{Path: "payments.mode"}
+	url := ConfigRef{Path: "db.url"}
+
+	tests := []struct {
+		name string
+		rule string
+		want RuleExpr
+	}{
+		{
+			name: "in list",
+			rule: `payments.mode in ["sandbox", "test"]`,
+			want: In{Value: mode, List: []RuleExpr{StringLiteral{Value: "sandbox"}, StringLiteral{Value: "test"}}},
+		},
+		{
+			name: "in list with references and one item",
+			rule: `execution.env in [allowed.env]`,
+			want: In{Value: ExecutionEnv{}, List: []RuleExpr{ConfigRef{Path: "allowed.env"}}},
+		},
+		{
+			name: "glob",
+			rule: `db.url like "*-staging*"`,
+			want: Like{Value: url, Pattern: "*-staging*"},
+		},
+		{
+			name: "keywords are references outside operator position",
+			rule: `in.like == like.in`,
+			want: Comparison{Left: ConfigRef{Path: "in.like"}, Right: ConfigRef{Path: "like.in"}, Operator: OpEqual},
+		},
+		{
+			name: "combined with connectives",
+			rule: `execution.env == "prod" => !(payments.mode in ["sandbox"]) && db.url like "*prod*"`,
+			want: Implication{
+				Antecedent: Comparison{Left: ExecutionEnv{}, Right: StringLiteral{Value: "prod"}, Operator: OpEqual},
+				Consequent: And{Left: Not{Operand: In{Value: mode, List: []RuleExpr{StringLiteral{Value: "sandbox"}}}}, Right: Like{Value: url, Pattern: "*prod*"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRule(tt.rule, nil)
+			if err != nil {
+				t.Fatalf("ParseRule() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Regexes are compiled when the rule is parsed
+	expr, err := ParseRule(`db.url =~ "^postgres://.*prod"`, []string{"db.url"})
+	if err != nil {
+		t.Fatalf("ParseRule() error = %v", err)
+	}
+	match, ok := expr.(Match)
+	if !ok || match.Pattern != "^postgres://.*prod" || match.Regexp == nil || !match.Regexp.MatchString("postgres://db-prod") {
+		t.Errorf("ParseRule() = %+v, want a compiled Match", expr)
+	}
+
+	_, err = ParseRule(`payments.mode in ["live", missing.mode]`, []string{"payments.mode"})
+	if err == nil || !contains(err.Error(), "undefined config key(s): missing.mode") {
+		t.Errorf("error = %v, want undefined key error for list items", err)
+	}
+}
+
+func TestParseRule_NullChecks(t *testing.T) {
+	tests := []struct {
+		rule string
+		want RuleExpr
+	}{
+		{rule: `tls.key is null`, want: IsNull{Value: ConfigRef{Path: "tls.key"}}},
+		{rule: `env.DEPLOY_ID is not null`, want: IsNull{Value: EnvRef{Name: "DEPLOY_ID"}, Negated: true}},
+		{rule: `!(len(api.token) is null)`, want: Not{Operand: IsNull{Value: Call{Func: "len", Args: []RuleExpr{ConfigRef{Path: "api.token"}}}}}},
+		{
+			rule: `execution.env == "prod" => tls.key is not null && tls.key != ""`,
+			want: Implication{
+				Antecedent: Comparison{Left: ExecutionEnv{}, Right: StringLiteral{Value: "prod"}, Operator: OpEqual},
+				Consequent: And{
+					Left:  IsNull{Value: ConfigRef{Path: "tls.key"}, Negated: true},
+					Right: Comparison{Left: ConfigRef{Path: "tls.key"}, Right: StringLiteral{Value: ""}, Operator: OpNotEqual},
+				},
+			},
+		},
+		// Keywords are references outside operator position
+		{rule: `is.not == null.is`, want: Comparison{Left: ConfigRef{Path: "is.not"}, Right: ConfigRef{Path: "null.is"}, Operator: OpEqual}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := ParseRule(tt.rule, nil)
+			if err != nil {
+				t.Fatalf("ParseRule() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRule() = %+v, want %+v", got, tt.want)
+			}
+			if formatted := FormatRule(got); formatted != tt.rule {
+				t.Errorf("FormatRule() = %q, want %q", formatted, tt.rule)
+			}
+		})
+	}
+
+	errTests := []struct {
+		rule    string
+		wantErr string
+	}{
+		{rule: `tls.key == null`, wantErr: "'null' is not a value; use 'is null' or 'is not null'"},
+		{rule: `tls.key is nil`, wantErr: "expected 'null' or 'not null' after 'is', got 'nil'"},
+		{rule: `tls.key is not`, wantErr: "expected 'null' or 'not null' after 'is', got ''"},
+		{rule: `missing.key is null`, wantErr: "undefined config key(s): missing.key"},
+	}
+	for _, tt := range errTests {
+		_, err := ParseRule(tt.rule, []string{"tls.key"})
+		if err == nil || !contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParseRule(%q) error = %v, want containing %q", tt.rule, err, tt.wantErr)
+		}
+	}
+
+	// A config key named null is still a reference
+	if _, err := ParseRule(`tls.key == null`, []string{"tls.key", "null"}); err != nil {
+		t.Errorf("ParseRule() with a null key: %v", err)
+	}
+}
+
+func TestParseTypedRule(t *testing.T) {
+	types := map[string]ValueType{
+		"pool.min":      ValueInt,
+		"pool.max":      ValueInt,
+		"cache.ratio":   ValueFloat,
+		"http.timeout":  ValueDuration,
+		"payments.mode": ValueEnum,
+		"db.url":        ValueString,
+	}
+
+	tests := []struct {
+		name string
+		rule string
+		want RuleExpr
+	}{
+		{
+			name: "int keys",
+			rule: `pool.max >= pool.min`,
+			want: Comparison{Left: ConfigRef{Path: "pool.max"}, Right: ConfigRef{Path: "pool.min"}, Operator: OpGreaterEqual, Type: ValueInt},
+		},
+		{
+			name: "float literal widens an int",
+			rule: `pool.max < 2.5`,
+			want: Comparison{Left: ConfigRef{Path: "pool.max"}, Right: NumberLiteral{Value: "2.5", Type: ValueFloat}, Operator: OpLess, Type: ValueFloat},
+		},
+		{
+			name: "duration literal",
+			rule: `http.timeout <= 1m30s`,
+			want: Comparison{Left: ConfigRef{Path: "http.timeout"}, Right: NumberLiteral{Value: "1m30s", Type: ValueDuration}, Operator: OpLessEqual, Type: ValueDuration},
+		},
+		{
+			name: "negative number and equality",
+			rule: `cache.ratio != -1`,
+			want: Comparison{Left: ConfigRef{Path: "cache.ratio"}, Right: NumberLiteral{Value: "-1", Type: ValueInt}, Operator: OpNotEqual, Type: ValueFloat},
+		},
+		{
+			name: "strings still compare as strings",
+			rule: `payments.mode == "live" => pool.min > 0`,
+			want: Implication{
+				Antecedent: Comparison{Left: ConfigRef{Path: "payments.mode"}, Right: StringLiteral{Value: "live"}, Operator: OpEqual},
+				Consequent: Comparison{Left: ConfigRef{Path: "pool.min"}, Right: NumberLiteral{Value: "0", Type: ValueInt}, Operator: OpGreater, Type: ValueInt},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTypedRule(tt.rule, types)
+			if err != nil {
+				t.Fatalf("ParseTypedRule() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTypedRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	errTests := []struct {
+		rule    string
+		wantErr string
+	}{
+		{rule: `payments.mode == 1`, wantErr: "cannot compare enum payments.mode with int 1"},
+		{rule: `http.timeout > pool.max`, wantErr: "cannot compare duration http.timeout with int pool.max"},
+		{rule: `db.url < 10`, wantErr: "'<' compares numbers or durations, not string db.url"},
+		{rule: `pool.max >= "10"`, wantErr: "'>=' compares numbers or durations, not string \"10\""},
+		{rule: `pool.max > 10abc`, wantErr: "invalid number '10abc'"},
+		{rule: `pool.max > missing.key`, wantErr: "undefined config key(s): missing.key"},
+	}
+	for _, tt := range errTests {
+		_, err := ParseTypedRule(tt.rule, types)
+		if err == nil || !contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParseTypedRule(%q) error = %v, want containing %q", tt.rule, err, tt.wantErr)
+		}
+	}
+
+	// Without types, ordering comparisons are left for the evaluator
+	expr, err := ParseRule(`a.b >= 10`, nil)
+	if err != nil || expr.(Comparison).Type != ValueInt {
+		t.Errorf("ParseRule() = %+v, %v; want an int comparison", expr, err)
+	}
+	if got := FormatRule(expr); got != "a.b >= 10" {
+		t.Errorf("FormatRule() = %q", got)
+	}
+}
+
 func TestValidateRuleRefs(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -179,6 +518,12 @@ func TestValidateRuleRefs(t *testing.T) {
 			configKeys: []string{},
 			wantErr:    false,
 		},
+		{
+			name:       "execution context and environment not config keys",
+			rule:       `execution.command == "migrate" && env.DEPLOY_ID != ""`,
+			configKeys: []string{},
+			wantErr:    false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -194,6 +539,76 @@ func TestValidateRuleRefs(t *testing.T) {
 			}
 		})
 	}
+
+	// Namespaces are validated
+	namespaceTests := []struct {
+		expr    RuleExpr
+		wantErr string
+	}{
+		{expr: ExecutionRef{Field: "pid"}, wantErr: "unknown execution field 'execution.pid' (available: execution.args, execution.command, execution.cwd, execution.env, execution.hostname, execution.user)"},
+		{expr: Not{Operand: EnvRef{Name: "db.url"}}, wantErr: "invalid environment variable name in 'env.db.url'"},
+		{expr: EnvRef{Name: "1PASSWORD"}, wantErr: "invalid environment variable name in 'env.1PASSWORD'"},
+	}
+	for _, tt := range namespaceTests {
+		err := ValidateRuleRefs(tt.expr, []string{})
+		if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("ValidateRuleRefs(%s) error = %v, want %q", FormatRule(tt.expr), err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseRule_ExecutionContext(t *testing.T) {
+	tests := []struct {
+		rule       string
+		configKeys []string
+		want       RuleExpr
+	}{
+		{
+			rule:       `execution.command == "migrate" => db.role == "owner"`,
+			configKeys: []string{"db.role"},
+			want: Implication{
+				Antecedent: Comparison{Left: ExecutionRef{Field: "command"}, Right: StringLiteral{Value: "migrate"}, Operator: OpEqual},
+				Consequent: Comparison{Left: ConfigRef{Path: "db.role"}, Right: StringLiteral{Value: "owner"}, Operator: OpEqual},
+			},
+		},
+		{
+			rule: `env.CI_COMMIT_TAG || execution.user != "root"`,
+			want: Or{Left: EnvRef{Name: "CI_COMMIT_TAG"}, Right: Comparison{Left: ExecutionRef{Field: "user"}, Right: StringLiteral{Value: "root"}, Operator: OpNotEqual}},
+		},
+		{
+			// A config key with the path is still a config reference
+			rule:       `env.region == execution.hostname`,
+			configKeys: []string{"env.region"},
+			want:       Comparison{Left: ConfigRef{Path: "env.region"}, Right: ExecutionRef{Field: "hostname"}, Operator: OpEqual},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := ParseRule(tt.rule, tt.configKeys)
+			if err != nil {
+				t.Fatalf("ParseRule() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRule() = %+v, want %+v", got, tt.want)
+			}
+			if formatted := FormatRule(got); formatted != tt.rule {
+				t.Errorf("FormatRule() = %q, want %q", formatted, tt.rule)
+			}
+		})
+	}
+
+	for rule, wantErr := range map[string]string{
+		`execution.pid == "1"`:   "unknown execution field 'execution.pid'",
+		`env.db.url == "x"`:      "invalid environment variable name in 'env.db.url'",
+		`execution.cmd => a.b`:   "unknown execution field 'execution.cmd'",
+		`len(env.TOKEN-X) >= 32`: "invalid environment variable name in 'env.TOKEN-X'",
+	} {
+		_, err := ParseRule(rule, nil)
+		if err == nil || !contains(err.Error(), wantErr) {
+			t.Errorf("ParseRule(%q) error = %v, want containing %q", rule, err, wantErr)
+		}
+	}
 }
 
 
@@ -252,6 +667,46 @@ func TestFormatRule(t *testing.T) {
 			},
 			expected: `execution.env == "prod" => db.url.env == "prod"`,
 		},
+		{
+			name:     "and binds tighter than or",
+			expr:     Or{Left: And{Left: ConfigRef{Path: "a"}, Right: ConfigRef{Path: "b"}}, Right: ConfigRef{Path: "c"}},
+			expected: "a && b || c",
+		},
+		{
+			name:     "or inside and is parenthesized",
+			expr:     And{Left: Or{Left: ConfigRef{Path: "a"}, Right: ConfigRef{Path: "b"}}, Right: ConfigRef{Path: "c"}},
+			expected: "(a || b) && c",
+		},
+		{
+			name:     "right-nested and is parenthesized",
+			expr:     And{Left: ConfigRef{Path: "a"}, Right: And{Left: ConfigRef{Path: "b"}, Right: ConfigRef{Path: "c"}}},
+			expected: "a && (b && c)",
+		},
+		{
+			name:     "negated comparison",
+			expr:     Not{Operand: Comparison{Left: ExecutionEnv{}, Right: StringLiteral{Value: "prod"}, Operator: OpEqual}},
+			expected: `!(execution.env == "prod")`,
+		},
+		{
+			name:     "negated reference and conjunction",
+			expr:     And{Left: Not{Operand: ConfigRef{Path: "a"}}, Right: Not{Operand: Or{Left: ConfigRef{Path: "b"}, Right: ConfigRef{Path: "c"}}}},
+			expected: "!a && !(b || c)",
+		},
+		{
+			name:     "in list",
+			expr:     In{Value: ConfigRef{Path: "payments.mode"}, List: []RuleExpr{StringLiteral{Value: "sandbox"}, ConfigRef{Path: "default.mode"}}},
+			expected: `payments.mode in ["sandbox", default.mode]`,
+		},
+		{
+			name:     "regex and glob",
+			expr:     Or{Left: Match{Value: ConfigRef{Path: "db.url"}, Pattern: `^postgres://.*prod`}, Right: Not{Operand: Like{Value: ConfigRef{Path: "db.url"}, Pattern: "*-staging*"}}},
+			expected: `db.url =~ "^postgres://.*prod" || !(db.url like "*-staging*")`,
+		},
+		{
+			name:     "nested implication is parenthesized",
+			expr:     Implication{Antecedent: ConfigRef{Path: "a"}, Consequent: Implication{Antecedent: ConfigRef{Path: "b"}, Consequent: ConfigRef{Path: "c"}}},
+			expected: "a => (b => c)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -400,6 +855,76 @@ func TestProperty3_RuleExpressionRoundTrip(t *testing.T) {
 	properties.TestingRun(t)
 }
 
+// TestProperty_BooleanRuleRoundTrip checks that formatting any tree of
+// &&, ||, ! and => and parsing it back gives the same tree
+func TestProperty_BooleanRuleRoundTrip(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = 200
+
+	properties := gopter.NewProperties(parameters)
+
+	leaves := []RuleExpr{
+		ConfigRef{Path: "a"},
+		ConfigRef{Path: "b.c"},
+		Comparison{Left: ExecutionEnv{}, Right: StringLiteral{Value: "prod"}, Operator: OpEqual},
+		Comparison{Left: ConfigRef{Path: "d"}, Right: ConfigRef{Path: "e"}, Operator: OpNotEqual},
+		In{Value: ConfigRef{Path: "f"}, List: []RuleExpr{StringLiteral{Value: "x"}, ExecutionEnv{}}},
+		Match{Value: ConfigRef{Path: "g"}, Pattern: "^a.*z$", Regexp: regexp.MustCompile("^a.*z$")},
+		Like{Value: ExecutionEnv{}, Pattern: "prod-*"},
+		Comparison{Left: ConfigRef{Path: "h"}, Right: NumberLiteral{Value: "-2.5", Type: ValueFloat}, Operator: OpGreaterEqual, Type: ValueFloat},
+		Comparison{Left: NumberLiteral{Value: "30s", Type: ValueDuration}, Right: ConfigRef{Path: "i"}, Operator: OpLess, Type: ValueDuration},
+		Call{Func: "startsWith", Args: []RuleExpr{Call{Func: "lower", Args: []RuleExpr{ConfigRef{Path: "j"}}}, StringLiteral{Value: "eu-"}}},
+	}
+
+	// build consumes ops to build a tree: 0-1 And, 2-3 Or, 4 Not, 5 Implication,
+	// anything else a leaf
+	var build func(ops []int, depth int) (RuleExpr, []int)
+	build = func(ops []int, depth int) (RuleExpr, []int) {
+		if len(ops) == 0 || depth > 5 {
+			return leaves[depth%len(leaves)], ops
+		}
+		op, ops := ops[0], ops[1:]
+		switch op {
+		case 0, 1, 2, 3, 5:
+			left, rest := build(ops, depth+1)
+			right, rest := build(rest, depth+2)
+			switch op {
+			case 0, 1:
+				return And{Left: left, Right: right}, rest
+			case 2, 3:
+				return Or{Left: left, Right: right}, rest
+			default:
+				return Implication{Antecedent: left, Consequent: right}, rest
+			}
+		case 4:
+			operand, rest := build(ops, depth+1)
+			return Not{Operand: operand}, rest
+		default:
+			return leaves[op%len(leaves)], ops
+		}
+	}
+
+	properties.Property("round-trip preserves boolean rule expression", prop.ForAll(
+		func(ops []int) bool {
+			original, _ := build(ops, 0)
+			formatted := FormatRule(original)
+
+			parsed, err := ParseRule(formatted, nil)
+			if err != nil {
+				t.Logf("ParseRule failed for %q: %v", formatted, err)
+				return false
+			}
+			if !reflect.DeepEqual(original, parsed) {
+				t.Logf("Round-trip mismatch:\n  original: %+v\n  formatted: %q\n  parsed: %+v", original, formatted, parsed)
+				return false
+			}
+			return true
+		},
+		gen.SliceOfN(12, gen.IntRange(0, 9)),
+	))
+
+	properties.TestingRun(t)
+}
 
 // Feature: admit-v2-invariants, Property 4: Undefined Key Detection
 // For any rule expression referencing a config key not defined in the schema,
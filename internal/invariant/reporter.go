@@ -8,9 +8,11 @@ import (
 
 // ViolationReport represents the JSON output format for invariant results
 type ViolationReport struct {
-	Invariants  []InvariantResultJSON `json:"invariants"`
-	AllPassed   bool                  `json:"allPassed"`
-	FailedCount int                   `json:"failedCount"`
+	Invariants   []InvariantResultJSON `json:"invariants"`
+	AllPassed    bool                  `json:"allPassed"`
+	FailedCount  int                   `json:"failedCount"`  // Blocking failures
+	WarningCount int                   `json:"warningCount"` // Failures of "warn" invariants
+	SkippedCount int                   `json:"skippedCount"` // Invariants whose when: selector did not match
 }
 
 // InvariantResultJSON represents a single invariant result in JSON format
@@ -21,18 +23,68 @@ type InvariantResultJSON struct {
 	LeftValue  string `json:"leftValue"`
 	RightValue string `json:"rightValue"`
 	Message    string `json:"message"`
+	Code       string `json:"code,omitempty"` // Set for failed invariants
+	Severity   string `json:"severity"`
+	Skipped    bool   `json:"skipped,omitempty"` // Not evaluated: the when: selector did not match (Message says why)
+	Trace      *Trace `json:"trace,omitempty"`   // Evaluation tree with --explain
+
+	LeftNull  bool `json:"-"` // Marshal leftValue as null
+	RightNull bool `json:"-"` // Marshal rightValue as null
+}
+
+// MarshalJSON marshals the result, with null operand values as null rather
+// than an empty string
+func (r InvariantResultJSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name       string  `json:"name"`
+		Rule       string  `json:"rule"`
+		Passed     bool    `json:"passed"`
+		LeftValue  *string `json:"leftValue"`
+		RightValue *string `json:"rightValue"`
+		Message    string  `json:"message"`
+		Code       string  `json:"code,omitempty"`
+		Severity   string  `json:"severity"`
+		Skipped    bool    `json:"skipped,omitempty"`
+		Trace      *Trace  `json:"trace,omitempty"`
+	}{
+		Name:       r.Name,
+		Rule:       r.Rule,
+		Passed:     r.Passed,
+		LeftValue:  nullable(r.LeftValue, r.LeftNull),
+		RightValue: nullable(r.RightValue, r.RightNull),
+		Message:    r.Message,
+		Code:       r.Code,
+		Severity:   r.Severity,
+		Skipped:    r.Skipped,
+		Trace:      r.Trace,
+	})
+}
+
+// nullable returns nil if null is set, otherwise a pointer to value
+func nullable(value string, null bool) *string {
+	if null {
+		return nil
+	}
+	return &value
 }
 
 // FormatViolation formats a single invariant violation as a human-readable string
 // The output prominently displays the invariant name, rule, and evaluated values
+// Failures of "warn" invariants are labeled as warnings
 func FormatViolation(result InvariantResult) string {
 	var sb strings.Builder
 
-	sb.WriteString(fmt.Sprintf("INVARIANT VIOLATION: '%s'\n", result.Name))
+	label := "INVARIANT VIOLATION"
+	if result.Severity.IsWarning() {
+		label = "INVARIANT WARNING"
+	}
+	sb.WriteString(fmt.Sprintf("%s: '%s'\n", label, result.Name))
 	sb.WriteString(fmt.Sprintf("  Rule: %s\n", result.Rule))
 
-	if result.LeftValue != "" || result.RightValue != "" {
-		sb.WriteString(fmt.Sprintf("  Values: left='%s', right='%s'\n", result.LeftValue, result.RightValue))
+	if result.LeftValue != "" || result.RightValue != "" || result.LeftNull || result.RightNull {
+		left := operandValue{text: result.LeftValue, null: result.LeftNull}
+		right := operandValue{text: result.RightValue, null: result.RightNull}
+		sb.WriteString(fmt.Sprintf("  Values: left=%s, right=%s\n", left, right))
 	}
 
 	if result.Message != "" {
@@ -43,14 +95,9 @@ func FormatViolation(result InvariantResult) string {
 }
 
 // FormatViolations formats multiple invariant violations as a human-readable string
-// All violations are included in the output
+// All blocking violations are included in the output
 func FormatViolations(results []InvariantResult) string {
-	var violations []InvariantResult
-	for _, r := range results {
-		if !r.Passed {
-			violations = append(violations, r)
-		}
-	}
+	violations := GetViolations(results)
 
 	if len(violations) == 0 {
 		return ""
@@ -67,6 +114,25 @@ func FormatViolations(results []InvariantResult) string {
 	return sb.String()
 }
 
+// FormatWarnings formats failures of "warn" invariants as a human-readable string
+func FormatWarnings(results []InvariantResult) string {
+	warnings := GetWarnings(results)
+
+	if len(warnings) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Invariant warnings: %d (not blocking)\n\n", len(warnings)))
+
+	for _, w := range warnings {
+		sb.WriteString(FormatViolation(w))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
 // FormatJSON formats invariant results as JSON for --invariants-json output
 func FormatJSON(results []InvariantResult) (string, error) {
 	report := ViolationReport{
@@ -83,12 +149,26 @@ func FormatJSON(results []InvariantResult) (string, error) {
 			LeftValue:  r.LeftValue,
 			RightValue: r.RightValue,
 			Message:    r.Message,
+			Code:       string(r.Code),
+			Severity:   r.Severity.String(),
+			Skipped:    r.Skipped,
+			Trace:      r.Trace,
+			LeftNull:   r.LeftNull,
+			RightNull:  r.RightNull,
 		}
 		report.Invariants = append(report.Invariants, jsonResult)
 
+		if r.Skipped {
+			report.SkippedCount++
+			continue
+		}
 		if !r.Passed {
 			report.AllPassed = false
-			report.FailedCount++
+			if r.Severity.IsWarning() {
+				report.WarningCount++
+			} else {
+				report.FailedCount++
+			}
 		}
 	}
 
@@ -100,23 +180,34 @@ func FormatJSON(results []InvariantResult) (string, error) {
 	return string(jsonBytes), nil
 }
 
-// HasViolations returns true if any invariant result is a violation
+// HasViolations returns true if any invariant result is a blocking violation
 func HasViolations(results []InvariantResult) bool {
 	for _, r := range results {
-		if !r.Passed {
+		if !r.Passed && !r.Skipped && !r.Severity.IsWarning() {
 			return true
 		}
 	}
 	return false
 }
 
-// GetViolations returns only the failed invariant results
+// GetViolations returns only the blocking failed invariant results
 func GetViolations(results []InvariantResult) []InvariantResult {
 	var violations []InvariantResult
 	for _, r := range results {
-		if !r.Passed {
+		if !r.Passed && !r.Skipped && !r.Severity.IsWarning() {
 			violations = append(violations, r)
 		}
 	}
 	return violations
 }
+
+// GetWarnings returns the failed results of "warn" invariants
+func GetWarnings(results []InvariantResult) []InvariantResult {
+	var warnings []InvariantResult
+	for _, r := range results {
+		if !r.Passed && !r.Skipped && r.Severity.IsWarning() {
+			warnings = append(warnings, r)
+		}
+	}
+	return warnings
+}
@@ -5,6 +5,8 @@ import (
 	"strings"
 	"testing"
 
+	"admit/internal/severity"
+
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
 	"github.com/leanovate/gopter/prop"
@@ -172,6 +174,14 @@ func TestHasViolations(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "only warnings fail",
+			results: []InvariantResult{
+				{Passed: true},
+				{Passed: false, Severity: severity.Warn},
+			},
+			want: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -202,6 +212,119 @@ func TestGetViolations(t *testing.T) {
 	}
 }
 
+func TestWarnSeverity(t *testing.T) {
+	results := []InvariantResult{
+		{Name: "blocking", Rule: `a == "x"`, Passed: false, Message: "a is not x"},
+		{Name: "advisory", Rule: `b == "y"`, Passed: false, Message: "b is not y", Severity: severity.Warn},
+		{Name: "advisory-ok", Rule: `c == "z"`, Passed: true, Severity: severity.Warn},
+	}
+
+	if v := GetViolations(results); len(v) != 1 || v[0].Name != "blocking" {
+		t.Errorf("GetViolations() = %+v, want only the blocking failure", v)
+	}
+	if w := GetWarnings(results); len(w) != 1 || w[0].Name != "advisory" {
+		t.Errorf("GetWarnings() = %+v, want only the failed warn invariant", w)
+	}
+
+	violations := FormatViolations(results)
+	if !strings.Contains(violations, "1 violation(s)") || strings.Contains(violations, "advisory") {
+		t.Errorf("FormatViolations() should only list blocking failures:\n%s", violations)
+	}
+	warnings := FormatWarnings(results)
+	if !strings.Contains(warnings, "INVARIANT WARNING: 'advisory'") || strings.Contains(warnings, "'blocking'") {
+		t.Errorf("FormatWarnings() = %q", warnings)
+	}
+	if FormatWarnings(results[:1]) != "" {
+		t.Error("FormatWarnings() should be empty without warnings")
+	}
+
+	output, err := FormatJSON(results)
+	if err != nil {
+		t.Fatalf("FormatJSON() error: %v", err)
+	}
+	var report ViolationReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if report.FailedCount != 1 || report.WarningCount != 1 || report.AllPassed {
+		t.Errorf("failedCount = %d, warningCount = %d, allPassed = %v", report.FailedCount, report.WarningCount, report.AllPassed)
+	}
+	if report.Invariants[0].Severity != "error" || report.Invariants[1].Severity != "warn" {
+		t.Errorf("severities = %q, %q", report.Invariants[0].Severity, report.Invariants[1].Severity)
+	}
+}
+
+func TestSkippedInvariants(t *testing.T) {
+	results := []InvariantResult{
+		{Name: "ran", Rule: `a == "x"`, Passed: true},
+		{Name: "prod-only", Rule: `b == "y"`, Skipped: true, Message: "execution.env 'dev' is not one of: prod"},
+		{Name: "prod-only-warn", Rule: `c == "z"`, Skipped: true, Severity: severity.Warn},
+	}
+
+	if HasViolations(results) || len(GetViolations(results)) != 0 || len(GetWarnings(results)) != 0 {
+		t.Error("skipped invariants should not be violations or warnings")
+	}
+	if FormatViolations(results) != "" || FormatWarnings(results) != "" {
+		t.Error("skipped invariants should not be reported as failures")
+	}
+
+	output, err := FormatJSON(results)
+	if err != nil {
+		t.Fatalf("FormatJSON() error: %v", err)
+	}
+	var report ViolationReport
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !report.AllPassed || report.FailedCount != 0 || report.WarningCount != 0 || report.SkippedCount != 2 {
+		t.Errorf("report = %+v, want all passed with 2 skipped", report)
+	}
+	skipped := report.Invariants[1]
+	if !skipped.Skipped || skipped.Passed || skipped.Message != "execution.env 'dev' is not one of: prod" {
+		t.Errorf("skipped result = %+v, want skipped, not passed, with the reason", skipped)
+	}
+	if strings.Contains(output[:strings.Index(output, `"prod-only"`)], `"skipped"`) {
+		t.Errorf("results that ran should not have a skipped field:\n%s", output)
+	}
+}
+
+
+func TestNullValues(t *testing.T) {
+	results := []InvariantResult{
+		{Name: "unset", Rule: `payments.mode != "live"`, LeftNull: true, RightValue: "live",
+			Message: `'payments.mode' is null, so 'payments.mode != "live"' is unknown`},
+		{Name: "empty", Rule: `payments.mode != ""`, RightValue: ""},
+	}
+
+	if output := FormatViolation(results[0]); !strings.Contains(output, "Values: left=null, right='live'") {
+		t.Errorf("FormatViolation() should show null unquoted:\n%s", output)
+	}
+
+	output, err := FormatJSON(results)
+	if err != nil {
+		t.Fatalf("FormatJSON() error: %v", err)
+	}
+	var report struct {
+		Invariants []map[string]interface{} `json:"invariants"`
+	}
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if left, ok := report.Invariants[0]["leftValue"]; !ok || left != nil {
+		t.Errorf("leftValue = %v (present %v), want null", left, ok)
+	}
+	if left := report.Invariants[1]["leftValue"]; left != "" {
+		t.Errorf("leftValue = %v, want an empty string", left)
+	}
+	if right := report.Invariants[0]["rightValue"]; right != "live" {
+		t.Errorf("rightValue = %v, want 'live'", right)
+	}
+	if !strings.Contains(output, `"rule": "payments.mode != \"live\"",
+      "passed": false,
+      "leftValue": null,`) {
+		t.Errorf("fields should keep their order:\n%s", output)
+	}
+}
 
 // Feature: admit-v2-invariants, Property 9: Violation Output Completeness
 // For any invariant violation, the error output SHALL contain:
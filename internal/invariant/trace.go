@@ -0,0 +1,121 @@
+package invariant
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Trace is the evaluation tree of a rule (--explain): every sub-expression
+// with its resolved value and whether it held as a condition
+type Trace struct {
+	Expr           string  `json:"expr"`                     // The sub-expression, as formatted by FormatRule
+	Value          *string `json:"value"`                    // Resolved value ("true"/"false"/"unknown" for conditions), redacted if sensitive; nil if null
+	Result         bool    `json:"result"`                   // Whether the sub-expression held (non-empty for a bare value)
+	ShortCircuited bool    `json:"shortCircuited,omitempty"` // Not evaluated: the other side of && or || decided the result
+	Children       []Trace `json:"children,omitempty"`
+
+	outcome truth // Result, or unknown
+	operand bool  // A value rather than a condition
+	literal bool  // A literal, whose value is the expression itself
+}
+
+// TraceExpr evaluates expr and records the evaluation of each sub-expression.
+// Sides of && and || that evaluation skips are recorded as short-circuited.
+func TraceExpr(expr RuleExpr, ctx EvalContext) Trace {
+	outcome, _, _, _ := evalExpr(expr, ctx)
+	t := Trace{
+		Expr:    FormatRule(expr),
+		Result:  outcome == truthTrue,
+		outcome: outcome,
+		operand: isOperand(expr),
+	}
+	if value := reportOperand(expr, ctx); !value.null {
+		t.Value = &value.text
+	}
+
+	switch e := expr.(type) {
+	case Implication:
+		t.Children = []Trace{TraceExpr(e.Antecedent, ctx), TraceExpr(e.Consequent, ctx)}
+	case And:
+		left := TraceExpr(e.Left, ctx)
+		t.Children = []Trace{left, traceUnless(left.outcome == truthFalse, e.Right, ctx)}
+	case Or:
+		left := TraceExpr(e.Left, ctx)
+		t.Children = []Trace{left, traceUnless(left.outcome == truthTrue, e.Right, ctx)}
+	case Not:
+		t.Children = []Trace{TraceExpr(e.Operand, ctx)}
+	case Comparison:
+		t.Children = []Trace{TraceExpr(e.Left, ctx), TraceExpr(e.Right, ctx)}
+	case In:
+		t.Children = []Trace{TraceExpr(e.Value, ctx)}
+		for _, item := range e.List {
+			t.Children = append(t.Children, TraceExpr(item, ctx))
+		}
+	case Match:
+		t.Children = []Trace{TraceExpr(e.Value, ctx)}
+	case Like:
+		t.Children = []Trace{TraceExpr(e.Value, ctx)}
+	case IsNull:
+		t.Children = []Trace{TraceExpr(e.Value, ctx)}
+	case Call:
+		for _, arg := range e.Args {
+			t.Children = append(t.Children, TraceExpr(arg, ctx))
+		}
+	case StringLiteral, NumberLiteral:
+		t.literal = true
+	}
+	return t
+}
+
+// traceUnless traces expr, or records it as short-circuited if skip is true
+func traceUnless(skip bool, expr RuleExpr, ctx EvalContext) Trace {
+	if skip {
+		return Trace{Expr: FormatRule(expr), ShortCircuited: true, operand: isOperand(expr)}
+	}
+	return TraceExpr(expr, ctx)
+}
+
+// FormatTraces formats the evaluation tree of each invariant for --explain
+func FormatTraces(results []InvariantResult) string {
+	var sb strings.Builder
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			sb.WriteString(fmt.Sprintf("Invariant '%s': skipped (%s)\n", r.Name, r.Message))
+		case r.Passed:
+			sb.WriteString(fmt.Sprintf("Invariant '%s': passed\n", r.Name))
+		default:
+			sb.WriteString(fmt.Sprintf("Invariant '%s': failed\n", r.Name))
+		}
+		if r.Trace != nil {
+			writeTrace(&sb, *r.Trace, "  ")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// writeTrace writes a trace node and its children, one per line.
+// Conditions are marked ✓, ✗ or ? (unknown); values are shown as
+// expr = 'value' or expr = null.
+func writeTrace(sb *strings.Builder, t Trace, indent string) {
+	switch {
+	case t.ShortCircuited:
+		sb.WriteString(fmt.Sprintf("%s- %s (not evaluated)\n", indent, t.Expr))
+	case t.literal:
+		sb.WriteString(fmt.Sprintf("%s%s\n", indent, t.Expr))
+	case t.operand && t.Value == nil:
+		sb.WriteString(fmt.Sprintf("%s%s = null\n", indent, t.Expr))
+	case t.operand:
+		sb.WriteString(fmt.Sprintf("%s%s = '%s'\n", indent, t.Expr, *t.Value))
+	case t.outcome == truthUnknown:
+		sb.WriteString(fmt.Sprintf("%s? %s\n", indent, t.Expr))
+	case t.Result:
+		sb.WriteString(fmt.Sprintf("%s✓ %s\n", indent, t.Expr))
+	default:
+		sb.WriteString(fmt.Sprintf("%s✗ %s\n", indent, t.Expr))
+	}
+	for _, child := range t.Children {
+		writeTrace(sb, child, indent+"  ")
+	}
+}
@@ -0,0 +1,145 @@
+package invariant
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTraceExpr(t *testing.T) {
+	rule := `execution.env == "prod" && (db.env == "prod" || len(db.url) > 100) => payments.mode in ["live"]`
+	expr, err := ParseRule(rule, nil)
+	if err != nil {
+		t.Fatalf("ParseRule() error = %v", err)
+	}
+	ctx := EvalContext{
+		ConfigValues: map[string]string{"db.env": "prod", "db.url": "postgres://x", "payments.mode": "sandbox"},
+		ExecutionEnv: "prod",
+		Sensitive:    map[string]bool{"db.url": true},
+	}
+
+	trace := TraceExpr(expr, ctx)
+	if trace.Expr != rule || trace.Result {
+		t.Fatalf("root = %q (result %v), want %q (false)", trace.Expr, trace.Result, rule)
+	}
+	if len(trace.Children) != 2 {
+		t.Fatalf("root has %d children, want 2", len(trace.Children))
+	}
+
+	antecedent := trace.Children[0]
+	if !antecedent.Result {
+		t.Errorf("antecedent result = false, want true")
+	}
+	or := antecedent.Children[1]
+	if !or.Children[0].Result {
+		t.Errorf("%s result = false, want true", or.Children[0].Expr)
+	}
+	if skipped := or.Children[1]; !skipped.ShortCircuited || skipped.Children != nil {
+		t.Errorf("%s = %+v, want short-circuited with no children", skipped.Expr, skipped)
+	}
+
+	consequent := trace.Children[1]
+	if consequent.Result || consequent.Children[0].Value == nil || *consequent.Children[0].Value != "sandbox" {
+		t.Errorf("consequent = %+v, want failed with value 'sandbox'", consequent)
+	}
+}
+
+func TestTraceExpr_Sensitive(t *testing.T) {
+	expr, err := ParseRule(`db.password != "changeme" && lower(db.password) != "changeme"`, nil)
+	if err != nil {
+		t.Fatalf("ParseRule() error = %v", err)
+	}
+	ctx := EvalContext{
+		ConfigValues: map[string]string{"db.password": "Hunter2"},
+		Sensitive:    map[string]bool{"db.password": true},
+	}
+
+	var values []string
+	var walk func(Trace)
+	walk = func(t Trace) {
+		if t.Value != nil {
+			values = append(values, *t.Value)
+		}
+		for _, child := range t.Children {
+			walk(child)
+		}
+	}
+	walk(TraceExpr(expr, ctx))
+
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), "hunter2") {
+			t.Errorf("trace reveals sensitive value: %q", value)
+		}
+	}
+}
+
+func TestFormatTraces(t *testing.T) {
+	expr, err := ParseRule(`db.env == "prod" || len(db.url) > 100`, nil)
+	if err != nil {
+		t.Fatalf("ParseRule() error = %v", err)
+	}
+	ctx := EvalContext{
+		ConfigValues: map[string]string{"db.env": "prod", "db.url": "postgres://x"},
+		Trace:        true,
+	}
+	results := []InvariantResult{
+		Evaluate(Invariant{Name: "prod-db", Expr: expr}, ctx),
+		{Name: "scoped", Skipped: true, Message: "execution.env 'dev' is not one of: prod"},
+	}
+
+	want := `Invariant 'prod-db': passed
+  ✓ db.env == "prod" || len(db.url) > 100
+    ✓ db.env == "prod"
+      db.env = 'prod'
+      "prod"
+    - len(db.url) > 100 (not evaluated)
+
+Invariant 'scoped': skipped (execution.env 'dev' is not one of: prod)
+
+`
+	if got := FormatTraces(results); got != want {
+		t.Errorf("FormatTraces() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatTraces_Null(t *testing.T) {
+	expr, err := ParseRule(`db.env == "dev" || payments.mode != "live"`, nil)
+	if err != nil {
+		t.Fatalf("ParseRule() error = %v", err)
+	}
+	ctx := EvalContext{ConfigValues: map[string]string{"db.env": "prod"}, Trace: true}
+	result := Evaluate(Invariant{Name: "live-guard", Expr: expr}, ctx)
+
+	want := `Invariant 'live-guard': failed
+  ? db.env == "dev" || payments.mode != "live"
+    ✗ db.env == "dev"
+      db.env = 'prod'
+      "dev"
+    ? payments.mode != "live"
+      payments.mode = null
+      "live"
+
+`
+	if got := FormatTraces([]InvariantResult{result}); got != want {
+		t.Errorf("FormatTraces() =\n%s\nwant\n%s", got, want)
+	}
+	if value := result.Trace.Value; value == nil || *value != "unknown" {
+		t.Errorf("root value = %v, want unknown", value)
+	}
+	if null := result.Trace.Children[1].Children[0]; null.Value != nil {
+		t.Errorf("%s value = %q, want nil", null.Expr, *null.Value)
+	}
+}
+
+func TestEvaluate_TraceOnlyWhenRequested(t *testing.T) {
+	expr, _ := ParseRule(`a == "b"`, nil)
+	inv := Invariant{Name: "test", Expr: expr}
+	ctx := EvalContext{ConfigValues: map[string]string{"a": "b"}}
+
+	if result := Evaluate(inv, ctx); result.Trace != nil {
+		t.Errorf("Evaluate() without Trace set a trace: %+v", result.Trace)
+	}
+	ctx.Trace = true
+	if result := Evaluate(inv, ctx); result.Trace == nil || !result.Trace.Result {
+		t.Errorf("Evaluate() with Trace = %+v, want a passing trace", result.Trace)
+	}
+}
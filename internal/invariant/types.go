@@ -1,5 +1,12 @@
 package invariant
 
+import (
+	"regexp"
+
+	"admit/internal/codes"
+	"admit/internal/severity"
+)
+
 // RuleExpr represents a parsed rule expression in the AST
 type RuleExpr interface {
 	isRuleExpr()
@@ -9,10 +16,37 @@ type RuleExpr interface {
 type CompOp string
 
 const (
-	OpEqual    CompOp = "=="
-	OpNotEqual CompOp = "!="
+	OpEqual        CompOp = "=="
+	OpNotEqual     CompOp = "!="
+	OpLess         CompOp = "<" // v9: ordering operators compare numbers or durations
+	OpLessEqual    CompOp = "<="
+	OpGreater      CompOp = ">"
+	OpGreaterEqual CompOp = ">="
 )
 
+// IsOrdering reports whether the operator compares order rather than equality
+func (op CompOp) IsOrdering() bool {
+	return op == OpLess || op == OpLessEqual || op == OpGreater || op == OpGreaterEqual
+}
+
+// ValueType is the type of a rule operand. Config references take the
+// schema type of their key; comparisons with a numeric side compare as numbers.
+type ValueType string
+
+const (
+	ValueString   ValueType = "string"
+	ValueEnum     ValueType = "enum"
+	ValueInt      ValueType = "int"
+	ValueFloat    ValueType = "float"
+	ValueDuration ValueType = "duration"
+	ValueBool     ValueType = "bool" // Result of a comparison or a function such as exists()
+)
+
+// IsNumeric reports whether values of the type compare as numbers
+func (t ValueType) IsNumeric() bool {
+	return t == ValueInt || t == ValueFloat || t == ValueDuration
+}
+
 // Implication represents an implication expression: A => B (if A then B)
 // The implication is true if the antecedent is false OR the consequent is true
 type Implication struct {
@@ -22,15 +56,78 @@ type Implication struct {
 
 func (Implication) isRuleExpr() {}
 
-// Comparison represents a comparison expression: A == B or A != B
+// Comparison represents a comparison expression: A == B, A != B, A < B, ...
 type Comparison struct {
 	Left     RuleExpr
 	Right    RuleExpr
 	Operator CompOp
+	Type     ValueType // Numeric type the operands are compared as (empty compares strings)
 }
 
 func (Comparison) isRuleExpr() {}
 
+// In represents set membership: A in ["x", "y"]
+// It is true if A equals any element of the list
+type In struct {
+	Value RuleExpr
+	List  []RuleExpr
+}
+
+func (In) isRuleExpr() {}
+
+// Match represents a regular expression match: A =~ "pattern"
+// The pattern is unanchored; Regexp is compiled when the rule is parsed
+type Match struct {
+	Value   RuleExpr
+	Pattern string
+	Regexp  *regexp.Regexp
+}
+
+func (Match) isRuleExpr() {}
+
+// Like represents a glob match: A like "pattern"
+// '*' matches any run of characters, as in environment contract rules
+type Like struct {
+	Value   RuleExpr
+	Pattern string
+}
+
+func (Like) isRuleExpr() {}
+
+// IsNull represents a null check: A is null, A is not null (v9)
+// Unset config keys and environment variables are null; see nullOperand
+type IsNull struct {
+	Value   RuleExpr
+	Negated bool // A is not null
+}
+
+func (IsNull) isRuleExpr() {}
+
+// And represents a conjunction: A && B
+// The right side is only evaluated if the left side is true
+type And struct {
+	Left  RuleExpr
+	Right RuleExpr
+}
+
+func (And) isRuleExpr() {}
+
+// Or represents a disjunction: A || B
+// The right side is only evaluated if the left side is false
+type Or struct {
+	Left  RuleExpr
+	Right RuleExpr
+}
+
+func (Or) isRuleExpr() {}
+
+// Not represents a negation: !A
+type Not struct {
+	Operand RuleExpr
+}
+
+func (Not) isRuleExpr() {}
+
 // ConfigRef represents a reference to a config value using dot notation (e.g., "db.url.env")
 type ConfigRef struct {
 	Path string
@@ -44,6 +141,22 @@ type ExecutionEnv struct{}
 
 func (ExecutionEnv) isRuleExpr() {}
 
+// ExecutionRef represents another field of the execution context
+// (e.g., execution.command); see ExecutionFields
+type ExecutionRef struct {
+	Field string
+}
+
+func (ExecutionRef) isRuleExpr() {}
+
+// EnvRef represents an environment variable of the process, whether or not
+// the schema declares it (e.g., env.DEPLOY_ID)
+type EnvRef struct {
+	Name string
+}
+
+func (EnvRef) isRuleExpr() {}
+
 // StringLiteral represents a quoted string value (e.g., "prod")
 type StringLiteral struct {
 	Value string
@@ -51,11 +164,43 @@ type StringLiteral struct {
 
 func (StringLiteral) isRuleExpr() {}
 
+// Call represents a call to a built-in function: len(api.token)
+// The function and its arity are checked when the rule is parsed
+type Call struct {
+	Func string
+	Args []RuleExpr
+}
+
+func (Call) isRuleExpr() {}
+
+// NumberLiteral represents an unquoted number or duration (e.g., 10, 0.5, 30s)
+type NumberLiteral struct {
+	Value string    // The literal as written
+	Type  ValueType // ValueInt, ValueFloat or ValueDuration
+}
+
+func (NumberLiteral) isRuleExpr() {}
+
+// When restricts the contexts an invariant applies in (v9)
+// An empty When always applies; otherwise every non-empty field must match
+type When struct {
+	Env     []string // execution.env values the invariant applies in
+	Command []string // Glob patterns for execution.command
+}
+
+// IsZero reports whether the selector is empty, so the invariant always applies
+func (w When) IsZero() bool {
+	return len(w.Env) == 0 && len(w.Command) == 0
+}
+
 // Invariant represents a named invariant rule
 type Invariant struct {
 	Name string   // Unique identifier (e.g., "prod-db-guard")
 	Rule string   // Original rule string
 	Expr RuleExpr // Parsed expression
+
+	Severity severity.Level // Whether a violation blocks (zero value) or only warns
+	When     When           // Contexts the invariant applies in (v9)
 }
 
 // InvariantResult represents the evaluation result of an invariant
@@ -66,4 +211,12 @@ type InvariantResult struct {
 	LeftValue  string // Evaluated left operand value
 	RightValue string // Evaluated right operand value
 	Message    string // Human-readable explanation
+
+	LeftNull  bool // The left operand was null (an unset config key or environment variable); LeftValue is empty
+	RightNull bool // The right operand was null
+
+	Code     codes.Code     // codes.InvariantViolated when the invariant failed
+	Severity severity.Level // The invariant's severity
+	Skipped  bool           // The when: selector did not match, so the rule was not evaluated (Passed is false)
+	Trace    *Trace         // Evaluation tree, if EvalContext.Trace is set (v9: --explain)
 }
@@ -0,0 +1,222 @@
+// Package probe provides v9 pre-exec dependency probes: reachability checks of
+// the hosts, sockets and URLs that config values point at.
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"admit/internal/codes"
+	"admit/internal/resolver"
+	"admit/internal/schema"
+	"admit/internal/severity"
+)
+
+// Probe defaults
+const (
+	DefaultTimeout   = 2 * time.Second        // Time limit for one attempt
+	DefaultRetryWait = 200 * time.Millisecond // Wait before the first retry; doubles each time
+)
+
+// defaultPorts are the ports used for URL schemes when the URL has none
+var defaultPorts = map[string]string{
+	"http":       "80",
+	"https":      "443",
+	"postgres":   "5432",
+	"postgresql": "5432",
+	"mysql":      "3306",
+	"redis":      "6379",
+	"rediss":     "6379",
+	"amqp":       "5672",
+	"amqps":      "5671",
+	"mongodb":    "27017",
+	"nats":       "4222",
+}
+
+// Result is the outcome of probing one config key
+type Result struct {
+	Key      string         `json:"key"`
+	Type     string         `json:"type"`   // schema.ProbeTCP, ProbeUnix or ProbeHTTP
+	Target   string         `json:"target"` // host:port, socket path or URL probed (no credentials)
+	Passed   bool           `json:"passed"`
+	Attempts int            `json:"attempts"`
+	Error    string         `json:"error,omitempty"` // Reason for the last failed attempt
+	Code     codes.Code     `json:"code,omitempty"`  // codes.ProbeFailed when the probe failed
+	Severity severity.Level `json:"severity"`        // The config key's severity
+}
+
+// Options configures how probes are run
+type Options struct {
+	RetryWait time.Duration // Wait before the first retry (0 uses DefaultRetryWait)
+}
+
+// Run probes every key that has a probe and a value, in parallel.
+// Results are in the order of resolved.
+func Run(s schema.Schema, resolved []resolver.ResolvedValue, opts Options) []Result {
+	var probed []resolver.ResolvedValue
+	for _, rv := range resolved {
+		if configKey, ok := s.Config[rv.Key]; ok && configKey.Probe != nil && rv.Present {
+			probed = append(probed, rv)
+		}
+	}
+
+	results := make([]Result, len(probed))
+	var wg sync.WaitGroup
+	for i, rv := range probed {
+		wg.Add(1)
+		go func(i int, rv resolver.ResolvedValue) {
+			defer wg.Done()
+			results[i] = probeKey(s.Config[rv.Key], rv.Value, opts)
+		}(i, rv)
+	}
+	wg.Wait()
+	return results
+}
+
+// probeKey runs a key's probe, retrying failed attempts with exponential backoff
+func probeKey(configKey schema.ConfigKey, value string, opts Options) Result {
+	spec := *configKey.Probe
+	result := Result{Key: configKey.Path, Type: spec.Type, Severity: configKey.Severity}
+
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	wait := opts.RetryWait
+	if wait <= 0 {
+		wait = DefaultRetryWait
+	}
+
+	target, err := Target(spec, value)
+	if err != nil {
+		result.Code = codes.ProbeFailed
+		result.Error = err.Error()
+		return result
+	}
+	result.Target = target
+
+	for attempt := 0; ; attempt++ {
+		result.Attempts = attempt + 1
+		err := attemptProbe(spec.Type, target, timeout)
+		if err == nil {
+			result.Passed = true
+			result.Error = ""
+			return result
+		}
+		result.Error = err.Error()
+		if attempt >= spec.Retries {
+			result.Code = codes.ProbeFailed
+			return result
+		}
+		time.Sleep(wait << attempt)
+	}
+}
+
+// Target returns what a probe of value connects to: host:port for tcp, the
+// socket path for unix, the URL (with any health path) for http. Credentials
+// in the value are never part of the target.
+func Target(spec schema.ProbeSpec, value string) (string, error) {
+	switch spec.Type {
+	case schema.ProbeTCP:
+		return hostPort(value)
+
+	case schema.ProbeUnix:
+		path := strings.TrimPrefix(value, "unix://")
+		if path == "" {
+			return "", fmt.Errorf("empty socket path")
+		}
+		return path, nil
+
+	case schema.ProbeHTTP:
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", fmt.Errorf("not an http(s) URL")
+		}
+		u.User = nil
+		if spec.Path != "" {
+			u.Path = "/" + strings.TrimPrefix(spec.Path, "/")
+			u.RawQuery = ""
+		}
+		u.Fragment = ""
+		return u.String(), nil
+	}
+	return "", fmt.Errorf("unknown probe type '%s'", spec.Type)
+}
+
+// hostPort extracts host:port from a URL or a bare host:port value.
+// A URL without a port uses its scheme's default port.
+func hostPort(value string) (string, error) {
+	if !strings.Contains(value, "://") {
+		if _, _, err := net.SplitHostPort(value); err != nil {
+			return "", fmt.Errorf("not a URL or host:port")
+		}
+		return value, nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Hostname() == "" {
+		return "", fmt.Errorf("URL has no host")
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPorts[strings.ToLower(u.Scheme)]
+		if port == "" {
+			return "", fmt.Errorf("URL has no port and scheme '%s' has no default", u.Scheme)
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// attemptProbe makes one attempt to reach target
+func attemptProbe(probeType, target string, timeout time.Duration) error {
+	switch probeType {
+	case schema.ProbeTCP:
+		conn, err := net.DialTimeout("tcp", target, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case schema.ProbeUnix:
+		info, err := os.Stat(target)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("%s is not a socket", target)
+		}
+		conn, err := net.DialTimeout("unix", target, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case schema.ProbeHTTP:
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return fmt.Errorf("request timed out after %s", timeout)
+			}
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("server returned %d", resp.StatusCode)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown probe type '%s'", probeType)
+}
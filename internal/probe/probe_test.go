@@ -0,0 +1,215 @@
+package probe
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"admit/internal/codes"
+	"admit/internal/resolver"
+	"admit/internal/schema"
+	"admit/internal/severity"
+)
+
+func TestTarget(t *testing.T) {
+	tests := []struct {
+		spec    schema.ProbeSpec
+		value   string
+		want    string
+		wantErr string
+	}{
+		{spec: schema.ProbeSpec{Type: schema.ProbeTCP}, value: "postgres://app:s3cret@db:6543/app", want: "db:6543"},
+		{spec: schema.ProbeSpec{Type: schema.ProbeTCP}, value: "postgres://app:s3cret@db/app", want: "db:5432"},
+		{spec: schema.ProbeSpec{Type: schema.ProbeTCP}, value: "redis://[::1]/0", want: "[::1]:6379"},
+		{spec: schema.ProbeSpec{Type: schema.ProbeTCP}, value: "cache:11211", want: "cache:11211"},
+		{spec: schema.ProbeSpec{Type: schema.ProbeTCP}, value: "custom://host/x", wantErr: "scheme 'custom' has no default"},
+		{spec: schema.ProbeSpec{Type: schema.ProbeTCP}, value: "just-a-host", wantErr: "not a URL or host:port"},
+		{spec: schema.ProbeSpec{Type: schema.ProbeUnix}, value: "unix:///run/app.sock", want: "/run/app.sock"},
+		{spec: schema.ProbeSpec{Type: schema.ProbeUnix}, value: "/run/app.sock", want: "/run/app.sock"},
+		{spec: schema.ProbeSpec{Type: schema.ProbeHTTP}, value: "https://user:pw@api.example.com/v1?x=1", want: "https://api.example.com/v1?x=1"},
+		{spec: schema.ProbeSpec{Type: schema.ProbeHTTP, Path: "healthz"}, value: "http://api:8080/v1?x=1", want: "http://api:8080/healthz"},
+		{spec: schema.ProbeSpec{Type: schema.ProbeHTTP}, value: "api:8080", wantErr: "not an http(s) URL"},
+	}
+
+	for _, tt := range tests {
+		got, err := Target(tt.spec, tt.value)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Target(%s, %q) error = %v, want containing %q", tt.spec.Type, tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Target(%s, %q) = %q, %v; want %q", tt.spec.Type, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+// probeSchema returns a schema with one probed key per value in probes
+func probeSchema(probes map[string]schema.ProbeSpec) schema.Schema {
+	s := schema.Schema{Config: map[string]schema.ConfigKey{}}
+	for key, spec := range probes {
+		spec := spec
+		s.Config[key] = schema.ConfigKey{Path: key, Type: schema.TypeString, Probe: &spec}
+	}
+	return s
+}
+
+// closedPort returns a local address nothing listens on
+func closedPort(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestRun_TCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+
+	s := probeSchema(map[string]schema.ProbeSpec{
+		"db.url":    {Type: schema.ProbeTCP},
+		"cache.url": {Type: schema.ProbeTCP, Retries: 2},
+		"queue.url": {Type: schema.ProbeTCP},
+	})
+	resolved := []resolver.ResolvedValue{
+		{Key: "db.url", Value: "postgres://" + l.Addr().String() + "/app", Present: true},
+		{Key: "cache.url", Value: "redis://" + closedPort(t), Present: true},
+		{Key: "queue.url"}, // not set: not probed
+	}
+
+	results := Run(s, resolved, Options{RetryWait: time.Millisecond})
+	if len(results) != 2 {
+		t.Fatalf("results = %+v, want 2 (unset keys are not probed)", results)
+	}
+	if r := results[0]; !r.Passed || r.Key != "db.url" || r.Attempts != 1 || r.Code != "" {
+		t.Errorf("db.url result = %+v, want passed on the first attempt", r)
+	}
+	if r := results[1]; r.Passed || r.Attempts != 3 || r.Code != codes.ProbeFailed || !strings.Contains(r.Error, "refused") {
+		t.Errorf("cache.url result = %+v, want failed after 3 attempts", r)
+	}
+	if !HasFailures(results) {
+		t.Error("HasFailures() = false, want true")
+	}
+}
+
+func TestRun_Unix(t *testing.T) {
+	dir, err := os.MkdirTemp("", "probe") // socket paths must be short
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "app.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	plainFile := filepath.Join(dir, "file")
+	if err := os.WriteFile(plainFile, nil, 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	s := probeSchema(map[string]schema.ProbeSpec{
+		"a": {Type: schema.ProbeUnix},
+		"b": {Type: schema.ProbeUnix},
+		"c": {Type: schema.ProbeUnix},
+	})
+	results := Run(s, []resolver.ResolvedValue{
+		{Key: "a", Value: "unix://" + socket, Present: true},
+		{Key: "b", Value: plainFile, Present: true},
+		{Key: "c", Value: filepath.Join(dir, "missing.sock"), Present: true},
+	}, Options{})
+
+	if !results[0].Passed {
+		t.Errorf("socket result = %+v, want passed", results[0])
+	}
+	if results[1].Passed || !strings.Contains(results[1].Error, "is not a socket") {
+		t.Errorf("plain file result = %+v, want not a socket", results[1])
+	}
+	if results[2].Passed || !strings.Contains(results[2].Error, "no such file") {
+		t.Errorf("missing socket result = %+v, want not found", results[2])
+	}
+}
+
+func TestRun_HTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	s := probeSchema(map[string]schema.ProbeSpec{
+		"api.url":  {Type: schema.ProbeHTTP, Path: "/healthz"},
+		"docs.url": {Type: schema.ProbeHTTP},
+		"slow.url": {Type: schema.ProbeHTTP, Timeout: 50 * time.Millisecond},
+	})
+	results := Run(s, []resolver.ResolvedValue{
+		{Key: "api.url", Value: server.URL + "/v1", Present: true},
+		{Key: "docs.url", Value: server.URL + "/docs", Present: true},
+		{Key: "slow.url", Value: slow.URL, Present: true},
+	}, Options{})
+
+	if !results[0].Passed || results[0].Target != server.URL+"/healthz" {
+		t.Errorf("api.url result = %+v, want the health path to pass", results[0])
+	}
+	if results[1].Passed || results[1].Error != "server returned 404" {
+		t.Errorf("docs.url result = %+v, want 404", results[1])
+	}
+	if results[2].Passed || results[2].Error != "request timed out after 50ms" {
+		t.Errorf("slow.url result = %+v, want timeout", results[2])
+	}
+}
+
+func TestRun_Parallel(t *testing.T) {
+	s := probeSchema(map[string]schema.ProbeSpec{})
+	var resolved []resolver.ResolvedValue
+	for _, key := range []string{"a", "b", "c", "d"} {
+		// Non-routable address: each attempt waits for the full timeout
+		s.Config[key] = schema.ConfigKey{Path: key, Type: schema.TypeString, Probe: &schema.ProbeSpec{Type: schema.ProbeTCP, Timeout: 300 * time.Millisecond}}
+		resolved = append(resolved, resolver.ResolvedValue{Key: key, Value: "10.255.255.1:9", Present: true})
+	}
+
+	start := time.Now()
+	results := Run(s, resolved, Options{})
+	if len(results) != 4 {
+		t.Fatalf("results = %+v", results)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("4 probes with a 300ms timeout took %s, want them run in parallel", elapsed)
+	}
+}
+
+func TestRun_WarnSeverity(t *testing.T) {
+	s := schema.Schema{Config: map[string]schema.ConfigKey{
+		"db.url": {Path: "db.url", Type: schema.TypeString, Probe: &schema.ProbeSpec{Type: schema.ProbeTCP}, Severity: severity.Warn},
+	}}
+	results := Run(s, []resolver.ResolvedValue{{Key: "db.url", Value: closedPort(t), Present: true}}, Options{})
+
+	if HasFailures(results) || len(GetWarnings(results)) != 1 {
+		t.Errorf("results = %+v, want a non-blocking warning", results)
+	}
+	if out := FormatWarnings(results); !strings.Contains(out, "Probe warnings: 1 (not blocking)") || !strings.Contains(out, "PROBE WARNING: db.url") {
+		t.Errorf("FormatWarnings() = %q", out)
+	}
+	if out := FormatCI(results); !strings.HasPrefix(out, "::warning file=admit.yaml::Probe failed: db.url (tcp ") {
+		t.Errorf("FormatCI() = %q", out)
+	}
+}
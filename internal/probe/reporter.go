@@ -0,0 +1,161 @@
+package probe
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FormatFailure formats a single failed probe as a human-readable string.
+// Failures of keys with severity "warn" are labeled as warnings.
+func FormatFailure(result Result) string {
+	var sb strings.Builder
+
+	label := "PROBE FAILED"
+	if result.Severity.IsWarning() {
+		label = "PROBE WARNING"
+	}
+	sb.WriteString(fmt.Sprintf("%s: %s\n", label, result.Key))
+	if result.Target != "" {
+		sb.WriteString(fmt.Sprintf("  Target: %s %s\n", result.Type, result.Target))
+	}
+	if result.Attempts > 0 {
+		sb.WriteString(fmt.Sprintf("  Attempts: %d\n", result.Attempts))
+	}
+	sb.WriteString(fmt.Sprintf("  Reason: %s\n", result.Error))
+
+	return sb.String()
+}
+
+// FormatFailures formats all blocking probe failures as a human-readable string
+func FormatFailures(results []Result) string {
+	failures := GetFailures(results)
+
+	if len(failures) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Probe check failed: %d unreachable dependency(ies)\n\n", len(failures)))
+
+	for _, f := range failures {
+		sb.WriteString(FormatFailure(f))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// FormatWarnings formats failed probes of keys with severity "warn" as a human-readable string
+func FormatWarnings(results []Result) string {
+	warnings := GetWarnings(results)
+
+	if len(warnings) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Probe warnings: %d (not blocking)\n\n", len(warnings)))
+
+	for _, w := range warnings {
+		sb.WriteString(FormatFailure(w))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// FormatCI formats failed probes as GitHub Actions annotations,
+// ::error for blocking failures and ::warning for warnings
+func FormatCI(results []Result) string {
+	var sb strings.Builder
+	for _, r := range results {
+		if r.Passed {
+			continue
+		}
+		command := "error"
+		if r.Severity.IsWarning() {
+			command = "warning"
+		}
+		sb.WriteString(fmt.Sprintf("::%s file=admit.yaml::%s\n", command, FormatMessage(r)))
+	}
+	return sb.String()
+}
+
+// FormatMessage formats a failed probe as a single line
+func FormatMessage(result Result) string {
+	if result.Target == "" {
+		return fmt.Sprintf("Probe failed: %s (%s): %s", result.Key, result.Type, result.Error)
+	}
+	return fmt.Sprintf("Probe failed: %s (%s %s) after %d attempt(s): %s", result.Key, result.Type, result.Target, result.Attempts, result.Error)
+}
+
+// HasFailures returns true if any probe failed for a key whose failures block
+func HasFailures(results []Result) bool {
+	return len(GetFailures(results)) > 0
+}
+
+// GetFailures returns the failed probes of keys whose failures block
+func GetFailures(results []Result) []Result {
+	var failures []Result
+	for _, r := range results {
+		if !r.Passed && !r.Severity.IsWarning() {
+			failures = append(failures, r)
+		}
+	}
+	return failures
+}
+
+// GetWarnings returns the failed probes of keys with severity "warn"
+func GetWarnings(results []Result) []Result {
+	var warnings []Result
+	for _, r := range results {
+		if !r.Passed && r.Severity.IsWarning() {
+			warnings = append(warnings, r)
+		}
+	}
+	return warnings
+}
+
+// FormatWaitTimeout formats the wait targets that never became ready as a human-readable string
+func FormatWaitTimeout(results []WaitResult, timeout time.Duration) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Wait timed out after %s: %d of %d target(s) not ready\n\n", timeout, countNotReady(results), len(results)))
+
+	for _, r := range results {
+		if r.Ready {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("NOT READY: %s\n", r.Target))
+		if r.Key != "" {
+			sb.WriteString(fmt.Sprintf("  Key: %s\n", r.Key))
+		}
+		sb.WriteString(fmt.Sprintf("  Attempts: %d\n", r.Attempts))
+		sb.WriteString(fmt.Sprintf("  Reason: %s\n", r.Error))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// FormatWaitCI formats the wait targets that never became ready as GitHub Actions annotations
+func FormatWaitCI(results []WaitResult, timeout time.Duration) string {
+	var sb strings.Builder
+	for _, r := range results {
+		if !r.Ready {
+			sb.WriteString(fmt.Sprintf("::error file=admit.yaml::Wait timed out after %s: %s not ready after %d attempt(s): %s\n", timeout, r.Target, r.Attempts, r.Error))
+		}
+	}
+	return sb.String()
+}
+
+// countNotReady returns the number of wait targets that never became ready
+func countNotReady(results []WaitResult) int {
+	count := 0
+	for _, r := range results {
+		if !r.Ready {
+			count++
+		}
+	}
+	return count
+}
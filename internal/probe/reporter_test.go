@@ -0,0 +1,61 @@
+package probe
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"admit/internal/codes"
+)
+
+func TestFormatFailures(t *testing.T) {
+	results := []Result{
+		{Key: "db.url", Type: "tcp", Target: "db:5432", Passed: true, Attempts: 1},
+		{Key: "cache.url", Type: "tcp", Target: "cache:6379", Attempts: 3, Error: "connection refused", Code: codes.ProbeFailed},
+		{Key: "api.url", Type: "http", Error: "not an http(s) URL", Code: codes.ProbeFailed},
+	}
+
+	out := FormatFailures(results)
+	for _, want := range []string{
+		"Probe check failed: 2 unreachable dependency(ies)",
+		"PROBE FAILED: cache.url\n  Target: tcp cache:6379\n  Attempts: 3\n  Reason: connection refused\n",
+		"PROBE FAILED: api.url\n  Reason: not an http(s) URL\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("FormatFailures() missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "db.url") {
+		t.Errorf("FormatFailures() should not list passed probes:\n%s", out)
+	}
+
+	ci := FormatCI(results)
+	want := "::error file=admit.yaml::Probe failed: cache.url (tcp cache:6379) after 3 attempt(s): connection refused\n" +
+		"::error file=admit.yaml::Probe failed: api.url (http): not an http(s) URL\n"
+	if ci != want {
+		t.Errorf("FormatCI() = %q, want %q", ci, want)
+	}
+
+	if FormatFailures(results[:1]) != "" || FormatWarnings(results) != "" {
+		t.Error("formatters should be empty without failures of their kind")
+	}
+}
+
+func TestFormatWaitTimeout(t *testing.T) {
+	results := []WaitResult{
+		{Target: "file:/tmp/ready", Ready: true, Attempts: 1},
+		{Target: "tcp://db:5432", Key: "db.url", Attempts: 7, Error: "connection refused", Code: codes.WaitTimeout},
+	}
+
+	out := FormatWaitTimeout(results, 30*time.Second)
+	want := "Wait timed out after 30s: 1 of 2 target(s) not ready\n\n" +
+		"NOT READY: tcp://db:5432\n  Key: db.url\n  Attempts: 7\n  Reason: connection refused\n\n"
+	if out != want {
+		t.Errorf("FormatWaitTimeout() = %q, want %q", out, want)
+	}
+
+	ci := FormatWaitCI(results, 30*time.Second)
+	if ci != "::error file=admit.yaml::Wait timed out after 30s: tcp://db:5432 not ready after 7 attempt(s): connection refused\n" {
+		t.Errorf("FormatWaitCI() = %q", ci)
+	}
+}
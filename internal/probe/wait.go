@@ -0,0 +1,185 @@
+package probe
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"admit/internal/codes"
+)
+
+// Wait target types
+const (
+	WaitTCP  = "tcp"  // A host:port accepting connections
+	WaitFile = "file" // A file that exists
+)
+
+// Wait defaults
+const (
+	DefaultWaitTimeout = 30 * time.Second // Overall deadline for all targets
+	MaxRetryWait       = 2 * time.Second  // Longest wait between two attempts
+)
+
+// minDialTimeout is the shortest time a tcp wait attempt is given
+const minDialTimeout = 100 * time.Millisecond
+
+// WaitTarget is something that must be ready before the command is executed
+type WaitTarget struct {
+	Type    string // WaitTCP or WaitFile
+	Address string // host:port for tcp, the path for file
+	Key     string // Config key the address was resolved from (--wait-for-key)
+}
+
+// String returns the target in --wait-for form (e.g., "tcp://db:5432")
+func (t WaitTarget) String() string {
+	if t.Type == WaitFile {
+		return "file:" + t.Address
+	}
+	return t.Type + "://" + t.Address
+}
+
+// ParseWaitTarget parses a --wait-for value: tcp://host:port or file:/path
+func ParseWaitTarget(spec string) (WaitTarget, error) {
+	switch {
+	case strings.HasPrefix(spec, "tcp://"):
+		address := strings.TrimSuffix(strings.TrimPrefix(spec, "tcp://"), "/")
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || host == "" || port == "" {
+			return WaitTarget{}, fmt.Errorf("invalid wait target '%s': expected tcp://host:port", spec)
+		}
+		return WaitTarget{Type: WaitTCP, Address: address}, nil
+
+	case strings.HasPrefix(spec, "file:"):
+		path := strings.TrimPrefix(strings.TrimPrefix(spec, "file:"), "//")
+		if path == "" {
+			return WaitTarget{}, fmt.Errorf("invalid wait target '%s': expected file:/path", spec)
+		}
+		return WaitTarget{Type: WaitFile, Address: path}, nil
+	}
+	return WaitTarget{}, fmt.Errorf("invalid wait target '%s': expected tcp://host:port or file:/path", spec)
+}
+
+// KeyWaitTarget returns the tcp target for a config value: the host:port of
+// a URL (with its scheme's default port) or a bare host:port. Credentials in
+// the value are never part of the target.
+func KeyWaitTarget(key, value string) (WaitTarget, error) {
+	address, err := hostPort(value)
+	if err != nil {
+		return WaitTarget{}, fmt.Errorf("cannot wait for %s: %v", key, err)
+	}
+	return WaitTarget{Type: WaitTCP, Address: address, Key: key}, nil
+}
+
+// WaitOptions configures Wait
+type WaitOptions struct {
+	Timeout   time.Duration // Overall deadline (0 uses DefaultWaitTimeout)
+	RetryWait time.Duration // Wait before the second attempt (0 uses DefaultRetryWait); doubles up to MaxRetryWait
+}
+
+// WaitResult is the outcome of waiting for one target
+type WaitResult struct {
+	Target   string     `json:"target"`        // The target in --wait-for form
+	Key      string     `json:"key,omitempty"` // Config key for --wait-for-key targets
+	Ready    bool       `json:"ready"`
+	Attempts int        `json:"attempts"`
+	Error    string     `json:"error,omitempty"` // Reason for the last failed attempt
+	Code     codes.Code `json:"code,omitempty"`  // codes.WaitTimeout when the target never became ready
+}
+
+// Wait polls every target in parallel until all are ready or the deadline
+// passes. Results are in the order of targets.
+func Wait(targets []WaitTarget, opts WaitOptions) []WaitResult {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultWaitTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	results := make([]WaitResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target WaitTarget) {
+			defer wg.Done()
+			results[i] = waitTarget(target, deadline, opts.RetryWait)
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+// waitTarget retries one target with capped exponential backoff until it is
+// ready or the deadline passes
+func waitTarget(target WaitTarget, deadline time.Time, wait time.Duration) WaitResult {
+	result := WaitResult{Target: target.String(), Key: target.Key}
+	if wait <= 0 {
+		wait = DefaultRetryWait
+	}
+
+	for {
+		result.Attempts++
+		err := attemptWait(target, time.Until(deadline))
+		if err == nil {
+			result.Ready = true
+			result.Error = ""
+			return result
+		}
+		result.Error = err.Error()
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			result.Code = codes.WaitTimeout
+			return result
+		}
+		if wait > remaining {
+			wait = remaining
+		}
+		time.Sleep(wait)
+		if wait *= 2; wait > MaxRetryWait {
+			wait = MaxRetryWait
+		}
+	}
+}
+
+// attemptWait checks a target once. A connection attempt is limited to the
+// time remaining, but the last attempt always gets minDialTimeout.
+func attemptWait(target WaitTarget, remaining time.Duration) error {
+	switch target.Type {
+	case WaitTCP:
+		timeout := DefaultTimeout
+		if remaining < timeout {
+			timeout = remaining
+		}
+		if timeout < minDialTimeout {
+			timeout = minDialTimeout
+		}
+		conn, err := net.DialTimeout("tcp", target.Address, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case WaitFile:
+		if _, err := os.Stat(target.Address); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("%s does not exist", target.Address)
+			}
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown wait target type '%s'", target.Type)
+}
+
+// WaitReady returns true if every target became ready
+func WaitReady(results []WaitResult) bool {
+	for _, r := range results {
+		if !r.Ready {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package probe
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"admit/internal/codes"
+)
+
+func TestParseWaitTarget(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    WaitTarget
+		wantErr string
+	}{
+		{spec: "tcp://db:5432", want: WaitTarget{Type: WaitTCP, Address: "db:5432"}},
+		{spec: "tcp://[::1]:6379/", want: WaitTarget{Type: WaitTCP, Address: "[::1]:6379"}},
+		{spec: "file:/tmp/ready", want: WaitTarget{Type: WaitFile, Address: "/tmp/ready"}},
+		{spec: "file:///tmp/ready", want: WaitTarget{Type: WaitFile, Address: "/tmp/ready"}},
+		{spec: "tcp://db", wantErr: "expected tcp://host:port"},
+		{spec: "tcp://:5432", wantErr: "expected tcp://host:port"},
+		{spec: "file:", wantErr: "expected file:/path"},
+		{spec: "http://api", wantErr: "expected tcp://host:port or file:/path"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseWaitTarget(tt.spec)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseWaitTarget(%q) error = %v, want containing %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseWaitTarget(%q) = %+v, %v; want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestKeyWaitTarget(t *testing.T) {
+	target, err := KeyWaitTarget("db.url", "postgres://app:s3cret@db/app")
+	if err != nil || target != (WaitTarget{Type: WaitTCP, Address: "db:5432", Key: "db.url"}) {
+		t.Errorf("KeyWaitTarget() = %+v, %v", target, err)
+	}
+	if target.String() != "tcp://db:5432" {
+		t.Errorf("String() = %q", target.String())
+	}
+
+	if _, err := KeyWaitTarget("db.url", "s3cret"); err == nil || err.Error() != "cannot wait for db.url: not a URL or host:port" {
+		t.Errorf("error = %v, want an error without the value", err)
+	}
+}
+
+func TestWait_BecomesReady(t *testing.T) {
+	dir := t.TempDir()
+	readyFile := filepath.Join(dir, "ready")
+
+	// Reserve a port, then listen on it only after a delay
+	addr := closedPort(t)
+	listening := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		os.WriteFile(readyFile, nil, 0644)
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			listening <- nil
+			return
+		}
+		listening <- l
+	}()
+
+	targets := []WaitTarget{{Type: WaitTCP, Address: addr}, {Type: WaitFile, Address: readyFile}}
+	results := Wait(targets, WaitOptions{Timeout: 5 * time.Second, RetryWait: 20 * time.Millisecond})
+	if l := <-listening; l != nil {
+		defer l.Close()
+	} else {
+		t.Skip("port was taken before the listener started")
+	}
+
+	if !WaitReady(results) {
+		t.Fatalf("Wait() = %+v, want all ready", results)
+	}
+	for _, r := range results {
+		if r.Attempts < 2 || r.Error != "" || r.Code != "" {
+			t.Errorf("result = %+v, want ready after retrying", r)
+		}
+	}
+	if results[0].Target != "tcp://"+addr || results[1].Target != "file:"+readyFile {
+		t.Errorf("results not in target order: %+v", results)
+	}
+}
+
+func TestWait_Timeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+
+	targets := []WaitTarget{
+		{Type: WaitTCP, Address: l.Addr().String()},
+		{Type: WaitTCP, Address: closedPort(t), Key: "db.url"},
+		{Type: WaitFile, Address: filepath.Join(t.TempDir(), "never")},
+	}
+
+	start := time.Now()
+	results := Wait(targets, WaitOptions{Timeout: 300 * time.Millisecond, RetryWait: 20 * time.Millisecond})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Wait() took %s, want the deadline to be shared", elapsed)
+	}
+
+	if WaitReady(results) {
+		t.Fatal("WaitReady() = true, want a timeout")
+	}
+	if !results[0].Ready || results[0].Attempts != 1 {
+		t.Errorf("listening target = %+v, want ready on the first attempt", results[0])
+	}
+	for _, r := range results[1:] {
+		if r.Ready || r.Code != codes.WaitTimeout || r.Attempts < 2 || r.Error == "" {
+			t.Errorf("result = %+v, want timed out after several attempts", r)
+		}
+	}
+	if results[1].Key != "db.url" || !strings.Contains(results[2].Error, "does not exist") {
+		t.Errorf("results = %+v", results)
+	}
+}
@@ -0,0 +1,54 @@
+package resolver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ConfigDirEntry is a file in a mounted config directory.
+// The file name is the config key, either as a schema path ("db.url")
+// or as an env var name ("DB_URL").
+type ConfigDirEntry struct {
+	Name string // File name
+	Path string // Full path to the file
+}
+
+// ListConfigDir lists the key files in a Kubernetes-style config directory.
+// ConfigMap and Secret volumes expose each key as a symlink into a timestamped
+// directory through "..data"; entries whose names start with "." (including the
+// "..data" machinery) and subdirectories are skipped. Symlinks are followed.
+// File contents are read lazily, so only keys in the schema are subject to the size cap.
+func ListConfigDir(dir string) ([]ConfigDirEntry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ConfigDirEntry
+	for _, de := range dirEntries {
+		name := de.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%s is not a regular file", path)
+		}
+
+		entries = append(entries, ConfigDirEntry{Name: name, Path: path})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
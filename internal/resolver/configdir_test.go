@@ -0,0 +1,183 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"admit/internal/schema"
+)
+
+// mountConfigDir lays out files the way the kubelet does for ConfigMap volumes:
+// the data lives in a timestamped directory, "..data" points at it, and each
+// key is a symlink through "..data".
+func mountConfigDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "..2026_10_18_12_00_00.000000001")
+	if err := os.Mkdir(dataDir, 0755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dataDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.Symlink(filepath.Base(dataDir), filepath.Join(dir, "..data")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	for name := range files {
+		if err := os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)); err != nil {
+			t.Fatalf("failed to link %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestListConfigDir(t *testing.T) {
+	dir := mountConfigDir(t, map[string]string{
+		"db.url":        "postgres://db/app\n",
+		"PAYMENTS_MODE": "test",
+	})
+	if err := os.WriteFile(filepath.Join(dir, ".hidden"), []byte("x"), 0644); err != nil {
+		t.Fatalf("failed to write hidden file: %v", err)
+	}
+
+	entries, err := ListConfigDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []ConfigDirEntry{
+		{Name: "PAYMENTS_MODE", Path: filepath.Join(dir, "PAYMENTS_MODE")},
+		{Name: "db.url", Path: filepath.Join(dir, "db.url")},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries = %+v, want %+v", entries, want)
+	}
+}
+
+func TestListConfigDir_Errors(t *testing.T) {
+	if _, err := ListConfigDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected error for missing directory")
+	}
+
+	dir := t.TempDir()
+	if err := os.Symlink(filepath.Join(dir, "nowhere"), filepath.Join(dir, "db.url")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if _, err := ListConfigDir(dir); err == nil {
+		t.Error("expected error for dangling symlink")
+	}
+}
+
+func TestResolveWithOptions_ConfigDir(t *testing.T) {
+	dir := mountConfigDir(t, map[string]string{
+		"db.url":        "postgres://db/app\n",
+		"PAYMENTS_MODE": "test",
+		"log.level":     "debug",
+		"unrelated.key": strings.Repeat("x", 100),
+	})
+	entries, err := ListConfigDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url":        {Path: "db.url", Type: schema.TypeString},
+			"payments.mode": {Path: "payments.mode", Type: schema.TypeString},
+			"log.level":     {Path: "log.level", Type: schema.TypeString},
+		},
+	}
+	environ := []string{"LOG_LEVEL=info"}
+
+	// Files not in the schema are never read, so the size cap does not apply to them
+	byKey := resolveByKey(t, s, environ, Options{ConfigDir: entries, ConfigDirMax: 50})
+
+	tests := []struct {
+		key   string
+		value string
+		want  Provenance
+	}{
+		{key: "db.url", value: "postgres://db/app", want: Provenance{Kind: SourceConfigDir, Var: "db.url", Path: filepath.Join(dir, "db.url")}},
+		{key: "payments.mode", value: "test", want: Provenance{Kind: SourceConfigDir, Var: "PAYMENTS_MODE", Path: filepath.Join(dir, "PAYMENTS_MODE")}},
+		{key: "log.level", value: "info", want: Provenance{Kind: SourceEnv, Var: "LOG_LEVEL"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			rv := byKey[tt.key]
+			if rv.Value != tt.value {
+				t.Errorf("value = %q, want %q", rv.Value, tt.value)
+			}
+			if !reflect.DeepEqual(rv.Source, tt.want) {
+				t.Errorf("source = %+v, want %+v", rv.Source, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveWithOptions_ConfigDirPrecedence(t *testing.T) {
+	first := mountConfigDir(t, map[string]string{"db.url": "first", "DB_URL": "by-env-name"})
+	second := mountConfigDir(t, map[string]string{"db.url": "second"})
+	secretPath := writeSecret(t, t.TempDir(), "db", "from-secret-file", 0400)
+
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url": {Path: "db.url", Type: schema.TypeString, Aliases: []string{"DATABASE_URL"}},
+		},
+	}
+
+	firstEntries, err := ListConfigDir(first)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secondEntries, err := ListConfigDir(second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		environ []string
+		entries []ConfigDirEntry
+		want    string
+	}{
+		{name: "env var wins", environ: []string{"DB_URL=env", "DB_URL_FILE=" + secretPath}, entries: firstEntries, want: "env"},
+		{name: "secret file beats config dir", environ: []string{"DB_URL_FILE=" + secretPath}, entries: firstEntries, want: "from-secret-file"},
+		{name: "config dir beats alias", environ: []string{"DATABASE_URL=alias"}, entries: firstEntries, want: "first"},
+		{name: "later dir wins", entries: append(append([]ConfigDirEntry{}, firstEntries...), secondEntries...), want: "second"},
+		{name: "env var file name", entries: firstEntries[:1], want: "by-env-name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{ConfigDir: tt.entries, FileSecrets: true}
+			rv := resolveByKey(t, s, tt.environ, opts)["db.url"]
+			if rv.Value != tt.want {
+				t.Errorf("value = %q (%s), want %q", rv.Value, rv.Source, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveWithOptions_ConfigDirSizeCap(t *testing.T) {
+	dir := mountConfigDir(t, map[string]string{"db.url": "0123456789"})
+	entries, err := ListConfigDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url": {Path: "db.url", Type: schema.TypeString},
+		},
+	}
+
+	_, err = ResolveWithOptions(s, nil, Options{ConfigDir: entries, ConfigDirMax: 5})
+	if err == nil || !strings.Contains(err.Error(), "db.url:") || !strings.Contains(err.Error(), "exceeds size limit") {
+		t.Errorf("expected size limit error naming db.url, got %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config file formats understood by ParseConfigFile
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTOML = "toml"
+)
+
+// ConfigFileEntry is a leaf value of a structured config file, flattened to a dot path.
+// e.g., "db: {url: x}" in YAML becomes {Path: "db.url", Value: "x"}
+type ConfigFileEntry struct {
+	Path      string // Dot path matching schema.ConfigKey.Path (e.g., "db.url")
+	Value     string // Scalar value as written (numbers and booleans keep their text form)
+	File      string // The file the entry was read from (empty for in-memory content)
+	Line      int    // The 1-based line number of the value
+	NonScalar string // "list" or "table" when the path holds a non-scalar value
+}
+
+// LoadConfigFile reads a YAML, JSON or TOML config file, choosing the format by extension.
+// Parse errors are prefixed with the file path.
+func LoadConfigFile(path string) ([]ConfigFileEntry, error) {
+	format, err := configFileFormat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ParseConfigFile(content, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for i := range entries {
+		entries[i].File = path
+	}
+	return entries, nil
+}
+
+// ParseConfigFile flattens a structured config document into dot-path entries, in file order.
+// The top level must be a mapping. Null values are treated as unset. Lists and
+// tables are recorded as non-scalar entries so that a schema key pointing at one
+// can be reported. Errors are prefixed with the line number.
+func ParseConfigFile(content []byte, format string) ([]ConfigFileEntry, error) {
+	f := &flattener{index: make(map[string]int)}
+
+	var err error
+	switch format {
+	case FormatYAML:
+		err = flattenYAML(content, f)
+	case FormatJSON:
+		err = flattenJSON(content, f)
+	case FormatTOML:
+		err = flattenTOML(content, f)
+	default:
+		err = fmt.Errorf("unsupported config file format '%s'", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f.entries, nil
+}
+
+// configFileFormat maps a file extension to a config file format
+func configFileFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".toml":
+		return FormatTOML, nil
+	}
+	return "", fmt.Errorf("%s: unsupported config file extension (use .yaml, .yml, .json or .toml)", path)
+}
+
+// flattener collects flattened entries and rejects duplicate paths
+type flattener struct {
+	entries []ConfigFileEntry
+	index   map[string]int
+}
+
+// add records an entry, failing if its path was already defined
+func (f *flattener) add(e ConfigFileEntry) error {
+	if i, dup := f.index[e.Path]; dup {
+		return fmt.Errorf("line %d: duplicate key '%s' (first defined on line %d)", e.Line, e.Path, f.entries[i].Line)
+	}
+	f.index[e.Path] = len(f.entries)
+	f.entries = append(f.entries, e)
+	return nil
+}
+
+// joinPath appends a key to a dot path prefix
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// flattenYAML walks a YAML document using yaml.v3 nodes, which carry line numbers
+func flattenYAML(content []byte, f *flattener) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return err
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		// Empty document
+		return nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: top level must be a mapping", root.Line)
+	}
+	return flattenYAMLMapping(root, "", f)
+}
+
+// flattenYAMLMapping flattens the key/value pairs of a mapping node under prefix
+func flattenYAMLMapping(node *yaml.Node, prefix string, f *flattener) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+		path := joinPath(prefix, key.Value)
+
+		var err error
+		switch value.Kind {
+		case yaml.MappingNode:
+			err = flattenYAMLMapping(value, path, f)
+		case yaml.SequenceNode:
+			err = f.add(ConfigFileEntry{Path: path, Line: value.Line, NonScalar: "list"})
+		case yaml.ScalarNode:
+			if value.Tag == "!!null" {
+				continue
+			}
+			err = f.add(ConfigFileEntry{Path: path, Value: value.Value, Line: value.Line})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flattenJSON walks a JSON document token by token to keep track of line numbers
+func flattenJSON(content []byte, f *flattener) error {
+	j := &jsonFlattener{content: content, dec: json.NewDecoder(bytes.NewReader(content)), out: f}
+	j.dec.UseNumber()
+
+	tok, err := j.dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return j.wrap(err)
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("line %d: top level must be an object", j.lineAt(j.dec.InputOffset()))
+	}
+	if err := j.object(""); err != nil {
+		return err
+	}
+
+	if _, err := j.dec.Token(); err != io.EOF {
+		return fmt.Errorf("line %d: unexpected content after top-level object", j.lineAt(j.dec.InputOffset()))
+	}
+	return nil
+}
+
+// jsonFlattener walks a JSON token stream
+type jsonFlattener struct {
+	content []byte
+	dec     *json.Decoder
+	out     *flattener
+}
+
+// object flattens the members of an object whose opening brace was consumed
+func (j *jsonFlattener) object(prefix string) error {
+	for j.dec.More() {
+		tok, err := j.dec.Token()
+		if err != nil {
+			return j.wrap(err)
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("line %d: expected object key", j.lineAt(j.dec.InputOffset()))
+		}
+		if err := j.value(joinPath(prefix, key)); err != nil {
+			return err
+		}
+	}
+	// Closing brace
+	if _, err := j.dec.Token(); err != nil {
+		return j.wrap(err)
+	}
+	return nil
+}
+
+// value flattens the next value in the stream under path
+func (j *jsonFlattener) value(path string) error {
+	line := j.lineAt(j.nextValueOffset())
+	tok, err := j.dec.Token()
+	if err != nil {
+		return j.wrap(err)
+	}
+
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '{' {
+			return j.object(path)
+		}
+		// Skip the array's elements; only its presence is recorded
+		if err := j.skipArray(); err != nil {
+			return err
+		}
+		return j.out.add(ConfigFileEntry{Path: path, Line: line, NonScalar: "list"})
+	case nil:
+		return nil
+	case string:
+		return j.out.add(ConfigFileEntry{Path: path, Value: v, Line: line})
+	case json.Number:
+		return j.out.add(ConfigFileEntry{Path: path, Value: v.String(), Line: line})
+	case bool:
+		return j.out.add(ConfigFileEntry{Path: path, Value: fmt.Sprintf("%t", v), Line: line})
+	}
+	return nil
+}
+
+// skipArray consumes tokens up to the close of an array whose opening bracket was consumed
+func (j *jsonFlattener) skipArray() error {
+	depth := 1
+	for depth > 0 {
+		tok, err := j.dec.Token()
+		if err != nil {
+			return j.wrap(err)
+		}
+		switch tok {
+		case json.Delim('['), json.Delim('{'):
+			depth++
+		case json.Delim(']'), json.Delim('}'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// nextValueOffset returns the offset of the next value, skipping whitespace and separators
+func (j *jsonFlattener) nextValueOffset() int64 {
+	offset := j.dec.InputOffset()
+	for offset < int64(len(j.content)) && strings.IndexByte(" \t\r\n:,", j.content[offset]) != -1 {
+		offset++
+	}
+	return offset
+}
+
+// lineAt returns the 1-based line number of a byte offset
+func (j *jsonFlattener) lineAt(offset int64) int {
+	if offset > int64(len(j.content)) {
+		offset = int64(len(j.content))
+	}
+	return bytes.Count(j.content[:offset], []byte("\n")) + 1
+}
+
+// wrap adds a line number to a decoding error
+func (j *jsonFlattener) wrap(err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("line %d: %w", j.lineAt(syntaxErr.Offset), err)
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("line %d: unexpected end of JSON input", j.lineAt(int64(len(j.content))))
+	}
+	return fmt.Errorf("line %d: %w", j.lineAt(j.dec.InputOffset()), err)
+}
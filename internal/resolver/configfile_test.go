@@ -0,0 +1,278 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"admit/internal/schema"
+)
+
+func TestParseConfigFile_Formats(t *testing.T) {
+	want := []ConfigFileEntry{
+		{Path: "db.url", Value: "postgres://db/app", Line: 3},
+		{Path: "db.pool", Value: "10", Line: 4},
+		{Path: "payments.mode", Value: "test", Line: 6},
+		{Path: "payments.enabled", Value: "true", Line: 7},
+	}
+
+	tests := []struct {
+		format  string
+		content string
+	}{
+		{
+			format: FormatYAML,
+			content: `# app config
+db:
+  url: postgres://db/app
+  pool: 10
+payments:
+  mode: test
+  enabled: true
+`,
+		},
+		{
+			format: FormatJSON,
+			content: `{
+  "db": {
+    "url": "postgres://db/app",
+    "pool": 10
+  }, "payments": {
+    "mode": "test",
+    "enabled": true
+  }
+}`,
+		},
+		{
+			format: FormatTOML,
+			content: `# app config
+[db]
+url = "postgres://db/app"
+pool = 10
+[payments]
+mode = 'test'
+enabled = true # inline comment
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := ParseConfigFile([]byte(tt.content), tt.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseConfigFile_YAML(t *testing.T) {
+	content := `db.url: postgres://db/app
+cache:
+  hosts: [a, b]
+  ttl: ~
+log:
+  level: &lvl debug
+  fallback: *lvl
+empty: {}
+`
+	got, err := ParseConfigFile([]byte(content), FormatYAML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []ConfigFileEntry{
+		{Path: "db.url", Value: "postgres://db/app", Line: 1},
+		{Path: "cache.hosts", Line: 3, NonScalar: "list"},
+		{Path: "log.level", Value: "debug", Line: 6},
+		{Path: "log.fallback", Value: "debug", Line: 6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseConfigFile_JSON(t *testing.T) {
+	content := `{
+  "db.url": "postgres://db/app",
+  "cache": {"hosts": ["a", {"b": 1}], "ttl": null, "ratio": 0.5}
+}`
+	got, err := ParseConfigFile([]byte(content), FormatJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []ConfigFileEntry{
+		{Path: "db.url", Value: "postgres://db/app", Line: 2},
+		{Path: "cache.hosts", Line: 3, NonScalar: "list"},
+		{Path: "cache.ratio", Value: "0.5", Line: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseConfigFile_TOML(t *testing.T) {
+	content := `title = "legacy"
+db.url = "postgres://db/app"
+"quoted key" = 'C:\path'
+
+[server]
+port = 8_080
+started = 1979-05-27 07:32:00
+bind = { host = "0.0.0.0", tls.enabled = false }
+hosts = [
+  "a", # first
+  "b",
+]
+motd = """
+Hello \
+  world"""
+raw = '''
+line1
+line2'''
+escaped = "tab\there \u00e9"
+
+[[plugins]]
+name = "ignored"
+
+[plugins.options]
+ignored = true
+
+[[plugins]]
+name = "also ignored"
+
+[limits]
+rate = 1.5e3
+mask = 0x1F
+`
+	got, err := ParseConfigFile([]byte(content), FormatTOML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []ConfigFileEntry{
+		{Path: "title", Value: "legacy", Line: 1},
+		{Path: "db.url", Value: "postgres://db/app", Line: 2},
+		{Path: "quoted key", Value: `C:\path`, Line: 3},
+		{Path: "server.port", Value: "8080", Line: 6},
+		{Path: "server.started", Value: "1979-05-27 07:32:00", Line: 7},
+		{Path: "server.bind.host", Value: "0.0.0.0", Line: 8},
+		{Path: "server.bind.tls.enabled", Value: "false", Line: 8},
+		{Path: "server.hosts", Line: 9, NonScalar: "list"},
+		{Path: "server.motd", Value: "Hello world", Line: 13},
+		{Path: "server.raw", Value: "line1\nline2", Line: 16},
+		{Path: "server.escaped", Value: "tab\there \u00e9", Line: 19},
+		{Path: "plugins", Line: 21, NonScalar: "list"},
+		{Path: "limits.rate", Value: "1.5e3", Line: 31},
+		{Path: "limits.mask", Value: "0x1F", Line: 32},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+func TestParseConfigFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		content string
+		wantErr string
+	}{
+		{name: "yaml syntax", format: FormatYAML, content: "db:\n  url: [unclosed\n", wantErr: "line"},
+		{name: "yaml top level list", format: FormatYAML, content: "- a\n- b\n", wantErr: "line 1: top level must be a mapping"},
+		{name: "yaml duplicate flattened path", format: FormatYAML, content: "db.url: a\ndb:\n  url: b\n", wantErr: "line 3: duplicate key 'db.url' (first defined on line 1)"},
+		{name: "json syntax", format: FormatJSON, content: "{\n  \"db\": {\n    \"url\": x\n  }\n}", wantErr: "line 3:"},
+		{name: "json truncated", format: FormatJSON, content: "{\n  \"db\": {", wantErr: "line 2: unexpected end of JSON input"},
+		{name: "json top level array", format: FormatJSON, content: "[1, 2]", wantErr: "line 1: top level must be an object"},
+		{name: "json trailing content", format: FormatJSON, content: "{}\n{}", wantErr: "line 2: unexpected content after top-level object"},
+		{name: "toml missing equals", format: FormatTOML, content: "[db]\nurl \"x\"\n", wantErr: "line 2: expected character ="},
+		{name: "toml unterminated string", format: FormatTOML, content: "a = 1\nb = \"open\n", wantErr: "line 2: basic strings cannot have new lines"},
+		{name: "toml invalid value", format: FormatTOML, content: "a = yes\n", wantErr: "line 1: incomplete number"},
+		{name: "toml trailing junk", format: FormatTOML, content: "a = \"x\" y\n", wantErr: "line 1: expected newline"},
+		{name: "toml redefined table", format: FormatTOML, content: "[db]\nurl = 1\n\n[db]\npool = 2\n", wantErr: "line 4: table db already exists"},
+		{name: "toml redefined key", format: FormatTOML, content: "a = 1\nb = 2\n[a]\nc = 3\n", wantErr: "line 3: key a should be a table, not a value"},
+		{name: "toml duplicate flattened path", format: FormatTOML, content: "\"db.url\" = 1\ndb.url = 2\n", wantErr: "line 2: duplicate key 'db.url' (first defined on line 1)"},
+		{name: "toml unterminated array", format: FormatTOML, content: "a = [1,\n2\n", wantErr: "line 3: expected character ]"},
+		{name: "toml bad header", format: FormatTOML, content: "[db\nurl = 1\n", wantErr: "line 1: expected character ]"},
+		{name: "unknown format", format: "ini", content: "a=1", wantErr: "unsupported config file format 'ini'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfigFile([]byte(tt.content), tt.format)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want containing %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(path, []byte("db:\n  url: x\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	entries, err := LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []ConfigFileEntry{{Path: "db.url", Value: "x", File: path, Line: 2}}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("got %+v, want %+v", entries, want)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte("{\n  oops\n}"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if _, err := LoadConfigFile(bad); err == nil || !strings.HasPrefix(err.Error(), bad+": line 2:") {
+		t.Errorf("expected error prefixed with file and line, got %v", err)
+	}
+
+	if _, err := LoadConfigFile(filepath.Join(dir, "config.ini")); err == nil || !strings.Contains(err.Error(), "unsupported config file extension") {
+		t.Errorf("expected unsupported extension error, got %v", err)
+	}
+}
+
+func TestResolveWithOptions_ConfigFile(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url":      {Path: "db.url", Type: schema.TypeString, Aliases: []string{"DATABASE_URL"}},
+			"db.pool":     {Path: "db.pool", Type: schema.TypeString},
+			"cache.hosts": {Path: "cache.hosts", Type: schema.TypeString},
+		},
+	}
+	entries := []ConfigFileEntry{
+		{Path: "db.url", Value: "from-first", File: "base.yaml", Line: 2},
+		{Path: "db.pool", Value: "5", File: "base.yaml", Line: 3},
+		{Path: "db.url", Value: "from-second", File: "local.yaml", Line: 7},
+	}
+
+	byKey := resolveByKey(t, s, []string{"DB_POOL=20", "DATABASE_URL=alias"}, Options{ConfigFile: entries})
+
+	if rv := byKey["db.url"]; rv.Value != "from-second" ||
+		!reflect.DeepEqual(rv.Source, Provenance{Kind: SourceConfigFile, Var: "db.url", Path: "local.yaml", Line: 7}) {
+		t.Errorf("db.url = %q from %+v, want later config file to win over alias", rv.Value, rv.Source)
+	}
+	if rv := byKey["db.pool"]; rv.Value != "20" || rv.Source.Kind != SourceEnv {
+		t.Errorf("db.pool = %q from %+v, want env to win over config file", rv.Value, rv.Source)
+	}
+
+	// A schema key pointing at a list is an error naming the file and line
+	entries = append(entries, ConfigFileEntry{Path: "cache.hosts", File: "base.yaml", Line: 9, NonScalar: "list"})
+	_, err := ResolveWithOptions(s, nil, Options{ConfigFile: entries})
+	if err == nil || !strings.Contains(err.Error(), "cache.hosts: base.yaml:9: expected a scalar value, got a list") {
+		t.Errorf("expected non-scalar error, got %v", err)
+	}
+}
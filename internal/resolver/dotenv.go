@@ -0,0 +1,295 @@
+package resolver
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DotenvEntry represents a single KEY=VALUE assignment read from a dotenv file
+type DotenvEntry struct {
+	Key       string // The variable name (e.g., "DB_URL")
+	Value     string // The unquoted, unescaped value
+	File      string // The file the entry was read from (empty for in-memory content)
+	Line      int    // The 1-based line number where the assignment starts
+	Encrypted bool   // Value was decrypted from an "enc:v1:" value (see DecryptEntries)
+}
+
+// LoadDotenvFile reads and parses a dotenv file from the given path.
+// Parse errors are prefixed with the file path.
+func LoadDotenvFile(path string) ([]DotenvEntry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ParseDotenv(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for i := range entries {
+		entries[i].File = path
+	}
+	return entries, nil
+}
+
+// ParseDotenv parses dotenv content into entries, in file order.
+// Supported syntax:
+//   - blank lines and lines starting with '#' are ignored
+//   - an optional "export " prefix before the key
+//   - unquoted values, with trailing " # comment" stripped
+//   - single-quoted values, taken literally (may span lines)
+//   - double-quoted values with \n, \r, \t, \", \\ and \$ escapes (may span lines)
+func ParseDotenv(content []byte) ([]DotenvEntry, error) {
+	p := &dotenvParser{input: string(content), line: 1}
+
+	var entries []DotenvEntry
+	for {
+		p.skipBlankAndComments()
+		if p.pos >= len(p.input) {
+			break
+		}
+
+		startLine := p.line
+		entry, err := p.parseAssignment()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", startLine, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// MergeEnviron merges dotenv entries into an environ slice.
+// When override is false, variables already set in environ win and file values
+// only fill in what is missing. When override is true, file values replace
+// existing variables. Among entries, later ones win over earlier ones.
+// The returned slice is a new slice; environ is not modified.
+func MergeEnviron(environ []string, entries []DotenvEntry, override bool) []string {
+	fileValues := make(map[string]string)
+	var order []string
+	for _, e := range entries {
+		if _, seen := fileValues[e.Key]; !seen {
+			order = append(order, e.Key)
+		}
+		fileValues[e.Key] = e.Value
+	}
+
+	result := make([]string, 0, len(environ)+len(order))
+	present := make(map[string]bool)
+	for _, env := range environ {
+		idx := strings.Index(env, "=")
+		if idx == -1 {
+			result = append(result, env)
+			continue
+		}
+		key := env[:idx]
+		present[key] = true
+		if value, ok := fileValues[key]; ok && override {
+			result = append(result, key+"="+value)
+			continue
+		}
+		result = append(result, env)
+	}
+
+	for _, key := range order {
+		if !present[key] {
+			result = append(result, key+"="+fileValues[key])
+		}
+	}
+
+	return result
+}
+
+// dotenvParser is a cursor over dotenv content that tracks line numbers
+type dotenvParser struct {
+	input string
+	pos   int
+	line  int
+}
+
+// peek returns the current character without advancing
+func (p *dotenvParser) peek() byte {
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// next returns the current character and advances, counting newlines
+func (p *dotenvParser) next() byte {
+	ch := p.input[p.pos]
+	p.pos++
+	if ch == '\n' {
+		p.line++
+	}
+	return ch
+}
+
+// skipInlineSpace advances past spaces and tabs on the current line
+func (p *dotenvParser) skipInlineSpace() {
+	for p.pos < len(p.input) && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipLine advances past the rest of the current line, including the newline
+func (p *dotenvParser) skipLine() {
+	for p.pos < len(p.input) {
+		if p.next() == '\n' {
+			return
+		}
+	}
+}
+
+// skipBlankAndComments advances past blank lines and full-line comments
+func (p *dotenvParser) skipBlankAndComments() {
+	for p.pos < len(p.input) {
+		p.skipInlineSpace()
+		switch p.peek() {
+		case '\n':
+			p.next()
+		case '\r':
+			p.pos++
+		case '#':
+			p.skipLine()
+		default:
+			return
+		}
+	}
+}
+
+// parseAssignment parses a single KEY=VALUE line
+func (p *dotenvParser) parseAssignment() (DotenvEntry, error) {
+	startLine := p.line
+
+	key := p.readKey()
+	if key == "export" && (p.peek() == ' ' || p.peek() == '\t') {
+		p.skipInlineSpace()
+		key = p.readKey()
+	}
+	if key == "" {
+		return DotenvEntry{}, fmt.Errorf("expected variable name, got '%c'", p.peek())
+	}
+
+	p.skipInlineSpace()
+	if p.peek() != '=' {
+		return DotenvEntry{}, fmt.Errorf("expected '=' after '%s'", key)
+	}
+	p.pos++
+	p.skipInlineSpace()
+
+	var value string
+	var err error
+	switch p.peek() {
+	case '"':
+		value, err = p.readDoubleQuoted()
+	case '\'':
+		value, err = p.readSingleQuoted()
+	default:
+		value = p.readUnquoted()
+		return DotenvEntry{Key: key, Value: value, Line: startLine}, nil
+	}
+	if err != nil {
+		return DotenvEntry{}, err
+	}
+
+	// Only whitespace or a comment may follow a closing quote
+	p.skipInlineSpace()
+	switch p.peek() {
+	case 0, '\n', '\r':
+		p.skipLine()
+	case '#':
+		p.skipLine()
+	default:
+		return DotenvEntry{}, fmt.Errorf("unexpected '%c' after quoted value for '%s'", p.peek(), key)
+	}
+
+	return DotenvEntry{Key: key, Value: value, Line: startLine}, nil
+}
+
+// readKey reads a variable name: letters, digits, '_' and '.', not starting with a digit
+func (p *dotenvParser) readKey() string {
+	start := p.pos
+	for p.pos < len(p.input) {
+		ch := p.peek()
+		isLetter := (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_'
+		isDigit := ch >= '0' && ch <= '9'
+		if !isLetter && !(p.pos > start && (isDigit || ch == '.')) {
+			break
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+// readUnquoted reads a value up to the end of the line.
+// A '#' preceded by whitespace starts a comment; surrounding whitespace is trimmed.
+func (p *dotenvParser) readUnquoted() string {
+	start := p.pos
+	end := -1
+	for p.pos < len(p.input) && p.peek() != '\n' {
+		if p.peek() == '#' && p.pos > start && (p.input[p.pos-1] == ' ' || p.input[p.pos-1] == '\t') {
+			end = p.pos
+			break
+		}
+		p.pos++
+	}
+	if end == -1 {
+		end = p.pos
+	}
+	value := strings.TrimRight(p.input[start:end], " \t\r")
+	p.skipLine()
+	return value
+}
+
+// readSingleQuoted reads a literal value between single quotes
+func (p *dotenvParser) readSingleQuoted() (string, error) {
+	p.next() // skip opening quote
+	var sb strings.Builder
+	for p.pos < len(p.input) {
+		ch := p.next()
+		if ch == '\'' {
+			return sb.String(), nil
+		}
+		sb.WriteByte(ch)
+	}
+	return "", fmt.Errorf("unterminated single-quoted value")
+}
+
+// readDoubleQuoted reads a value between double quotes, processing escapes
+func (p *dotenvParser) readDoubleQuoted() (string, error) {
+	p.next() // skip opening quote
+	var sb strings.Builder
+	for p.pos < len(p.input) {
+		ch := p.next()
+		switch ch {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			if p.pos >= len(p.input) {
+				return "", fmt.Errorf("unterminated double-quoted value")
+			}
+			esc := p.next()
+			switch esc {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '"', '\\', '$', '\'':
+				sb.WriteByte(esc)
+			default:
+				// Unknown escapes are kept verbatim
+				sb.WriteByte('\\')
+				sb.WriteByte(esc)
+			}
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return "", fmt.Errorf("unterminated double-quoted value")
+}
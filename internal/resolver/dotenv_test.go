@@ -0,0 +1,242 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+)
+
+func TestParseDotenv_Syntax(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name:    "simple assignment",
+			content: "DB_URL=postgres://localhost/app\n",
+			want:    map[string]string{"DB_URL": "postgres://localhost/app"},
+		},
+		{
+			name:    "export prefix",
+			content: "export PAYMENTS_MODE=test\n",
+			want:    map[string]string{"PAYMENTS_MODE": "test"},
+		},
+		{
+			name:    "comments and blank lines",
+			content: "# leading comment\n\nA=1\n   # indented comment\nB=2\n",
+			want:    map[string]string{"A": "1", "B": "2"},
+		},
+		{
+			name:    "inline comment on unquoted value",
+			content: "A=value # trailing comment\n",
+			want:    map[string]string{"A": "value"},
+		},
+		{
+			name:    "hash without preceding space is part of value",
+			content: "A=abc#def\n",
+			want:    map[string]string{"A": "abc#def"},
+		},
+		{
+			name:    "double-quoted value with spaces and escapes",
+			content: `A="hello \"world\"\n\tdone"` + "\n",
+			want:    map[string]string{"A": "hello \"world\"\n\tdone"},
+		},
+		{
+			name:    "single-quoted value is literal",
+			content: `A='no $escapes \n here'` + "\n",
+			want:    map[string]string{"A": `no $escapes \n here`},
+		},
+		{
+			name:    "quoted value with trailing comment",
+			content: `A="x # not a comment" # comment` + "\n",
+			want:    map[string]string{"A": "x # not a comment"},
+		},
+		{
+			name:    "multi-line double-quoted value",
+			content: "A=\"line1\nline2\"\nB=2\n",
+			want:    map[string]string{"A": "line1\nline2", "B": "2"},
+		},
+		{
+			name:    "empty values",
+			content: "A=\nB=\"\"\nC=''\n",
+			want:    map[string]string{"A": "", "B": "", "C": ""},
+		},
+		{
+			name:    "value containing equals",
+			content: "A=postgres://u:p=w@h/db\n",
+			want:    map[string]string{"A": "postgres://u:p=w@h/db"},
+		},
+		{
+			name:    "spaces around equals",
+			content: "A = spaced\n",
+			want:    map[string]string{"A": "spaced"},
+		},
+		{
+			name:    "CRLF line endings",
+			content: "A=1\r\nB=\"2\"\r\n",
+			want:    map[string]string{"A": "1", "B": "2"},
+		},
+		{
+			name:    "later assignment wins",
+			content: "A=1\nA=2\n",
+			want:    map[string]string{"A": "2"},
+		},
+		{
+			name:    "no trailing newline",
+			content: "A=1",
+			want:    map[string]string{"A": "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ParseDotenv([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := make(map[string]string)
+			for _, e := range entries {
+				got[e.Key] = e.Value
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d keys %v, want %d keys %v", len(got), got, len(tt.want), tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDotenv_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantLine string
+	}{
+		{name: "missing equals", content: "A=1\nINVALID\n", wantLine: "line 2"},
+		{name: "unterminated double quote", content: "A=\"open\n", wantLine: "line 1"},
+		{name: "unterminated single quote", content: "\nA='open\n", wantLine: "line 2"},
+		{name: "junk after quoted value", content: "A=\"x\"y\n", wantLine: "line 1"},
+		{name: "invalid key", content: "1A=x\n", wantLine: "line 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDotenv([]byte(tt.content))
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantLine) {
+				t.Errorf("error %q should mention %q", err.Error(), tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestParseDotenv_LineNumbers(t *testing.T) {
+	content := "# comment\nA=1\nB=\"multi\nline\"\n\nC=3\n"
+	entries, err := ParseDotenv([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantLines := map[string]int{"A": 2, "B": 3, "C": 6}
+	for _, e := range entries {
+		if e.Line != wantLines[e.Key] {
+			t.Errorf("%s: Line = %d, want %d", e.Key, e.Line, wantLines[e.Key])
+		}
+	}
+}
+
+func TestLoadDotenvFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, ".env")
+	if err := os.WriteFile(path, []byte("DB_URL=postgres://localhost/app\n"), 0644); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+
+	entries, err := LoadDotenvFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].File != path {
+		t.Errorf("expected one entry with File=%s, got %+v", path, entries)
+	}
+
+	// Parse errors are prefixed with the path
+	badPath := filepath.Join(tmpDir, "bad.env")
+	if err := os.WriteFile(badPath, []byte("NOPE\n"), 0644); err != nil {
+		t.Fatalf("failed to write env file: %v", err)
+	}
+	_, err = LoadDotenvFile(badPath)
+	if err == nil || !strings.Contains(err.Error(), badPath) {
+		t.Errorf("expected error mentioning %s, got %v", badPath, err)
+	}
+
+	// Missing files surface the os error
+	if _, err := LoadDotenvFile(filepath.Join(tmpDir, "missing.env")); !os.IsNotExist(err) {
+		t.Errorf("expected not-exist error, got %v", err)
+	}
+}
+
+func TestMergeEnviron_Precedence(t *testing.T) {
+	environ := []string{"A=env", "B=env"}
+	entries := []DotenvEntry{
+		{Key: "B", Value: "file1"},
+		{Key: "C", Value: "file1"},
+		{Key: "C", Value: "file2"},
+	}
+
+	// Default: process environment wins, files fill in missing keys
+	merged := parseEnviron(MergeEnviron(environ, entries, false))
+	if merged["A"] != "env" || merged["B"] != "env" || merged["C"] != "file2" {
+		t.Errorf("env-wins merge = %v", merged)
+	}
+
+	// Override: files win over the process environment
+	merged = parseEnviron(MergeEnviron(environ, entries, true))
+	if merged["A"] != "env" || merged["B"] != "file1" || merged["C"] != "file2" {
+		t.Errorf("file-wins merge = %v", merged)
+	}
+
+	// Original environ is untouched
+	if environ[1] != "B=env" {
+		t.Errorf("environ was modified: %v", environ)
+	}
+}
+
+// Feature: admit-v8-config-sources, Property 1: Double-Quoted Value Round-Trip
+// For any value, writing it as a double-quoted dotenv value with escapes
+// and parsing it back SHALL yield the original value.
+func TestParseDotenv_DoubleQuotedRoundTrip_Property(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	parameters.MinSuccessfulTests = 100
+
+	properties := gopter.NewProperties(parameters)
+
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "$", `\$`)
+
+	properties.Property("double-quoted values round-trip", prop.ForAll(
+		func(key, value string) bool {
+			content := "export " + key + "=\"" + quote.Replace(value) + "\"\n"
+			entries, err := ParseDotenv([]byte(content))
+			if err != nil || len(entries) != 1 {
+				return false
+			}
+			return entries[0].Key == key && entries[0].Value == value
+		},
+		gen.Identifier(),
+		gen.AnyString(),
+	))
+
+	properties.TestingRun(t)
+}
@@ -0,0 +1,138 @@
+package resolver
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EncryptedPrefix marks an env file value encrypted with a key file
+const EncryptedPrefix = "enc:v1:"
+
+// KeyFileEnv names the environment variable holding the key file path
+const KeyFileEnv = "ADMIT_KEY_FILE"
+
+// keySize is the AES-256 key length in bytes
+const keySize = 32
+
+// IsEncrypted reports whether a value is an encrypted "enc:v1:" value
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, EncryptedPrefix)
+}
+
+// GenerateKey returns a new random key in key file format (base64)
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// LoadKey reads a key file: a base64-encoded 32-byte key, surrounding whitespace ignored.
+// The file must be a regular file that only its owner can access (mode 0600 or stricter).
+func LoadKey(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key file: %w", err)
+	}
+	if info.Mode().IsRegular() && info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("key file %s is accessible by group or others (mode %04o); restrict it with chmod 600", path, info.Mode().Perm())
+	}
+	content, err := ReadSecretFile(path, 0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key file: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(content))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("key file %s: expected a base64-encoded %d-byte key", path, keySize)
+	}
+	return key, nil
+}
+
+// EncryptValue encrypts plaintext with AES-256-GCM under a random nonce, with
+// the variable name as additional data so the value only decrypts for that name.
+// The result is "enc:v1:" followed by the base64url nonce and ciphertext.
+func EncryptValue(key []byte, name, plaintext string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(name))
+	return EncryptedPrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue decrypts a value EncryptValue produced for the variable name
+func DecryptValue(key []byte, name, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("not an encrypted value")
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(value, EncryptedPrefix))
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", errors.New("wrong key, wrong variable or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+// newAEAD creates the AES-256-GCM cipher for a key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// DecryptEntries returns env file entries with encrypted values replaced by
+// their plaintext and marked Encrypted. The key file is named by ADMIT_KEY_FILE
+// in environ and is only read when some entry is encrypted.
+func DecryptEntries(entries []DotenvEntry, environ []string) ([]DotenvEntry, error) {
+	var key []byte
+	result := make([]DotenvEntry, len(entries))
+	for i, entry := range entries {
+		result[i] = entry
+		if !IsEncrypted(entry.Value) {
+			continue
+		}
+
+		if key == nil {
+			keyFile := parseEnviron(environ)[KeyFileEnv]
+			if keyFile == "" {
+				return nil, fmt.Errorf("%s:%d: %s is encrypted but %s is not set", entry.File, entry.Line, entry.Key, KeyFileEnv)
+			}
+			var err error
+			if key, err = LoadKey(keyFile); err != nil {
+				return nil, err
+			}
+		}
+
+		plaintext, err := DecryptValue(key, entry.Key, entry.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: cannot decrypt %s: %w", entry.File, entry.Line, entry.Key, err)
+		}
+		result[i].Value = plaintext
+		result[i].Encrypted = true
+	}
+	return result, nil
+}
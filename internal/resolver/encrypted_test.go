@@ -0,0 +1,205 @@
+package resolver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"admit/internal/schema"
+)
+
+// writeKey generates a key file and returns its path and the decoded key
+func writeKey(t *testing.T) (string, []byte) {
+	t.Helper()
+	encoded, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "admit.key")
+	if err := os.WriteFile(path, []byte(encoded+"\n"), 0600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+	key, err := LoadKey(path)
+	if err != nil {
+		t.Fatalf("LoadKey() error: %v", err)
+	}
+	return path, key
+}
+
+func TestEncryptValue_RoundTrip(t *testing.T) {
+	_, key := writeKey(t)
+
+	for _, plaintext := range []string{"s3cret", "", "p@ss=word #1 ${X}", "multi\nline"} {
+		value, err := EncryptValue(key, "DB_PASSWORD", plaintext)
+		if err != nil {
+			t.Fatalf("EncryptValue(%q) error: %v", plaintext, err)
+		}
+		if !IsEncrypted(value) || strings.ContainsAny(value, " #=\n") {
+			t.Errorf("EncryptValue(%q) = %q, want an env-file-safe enc:v1: value", plaintext, value)
+		}
+		got, err := DecryptValue(key, "DB_PASSWORD", value)
+		if err != nil || got != plaintext {
+			t.Errorf("DecryptValue() = %q, %v; want %q", got, err, plaintext)
+		}
+	}
+
+	// A random nonce makes each encryption different
+	a, _ := EncryptValue(key, "DB_PASSWORD", "same")
+	b, _ := EncryptValue(key, "DB_PASSWORD", "same")
+	if a == b {
+		t.Error("encrypting the same value twice gave identical output")
+	}
+}
+
+func TestDecryptValue_Errors(t *testing.T) {
+	_, key := writeKey(t)
+	_, otherKey := writeKey(t)
+	value, err := EncryptValue(key, "DB_PASSWORD", "s3cret")
+	if err != nil {
+		t.Fatalf("EncryptValue() error: %v", err)
+	}
+	// Change one character of the ciphertext
+	tampered := []byte(value)
+	if i := len(tampered) - 5; tampered[i] == 'A' {
+		tampered[i] = 'B'
+	} else {
+		tampered[i] = 'A'
+	}
+
+	tests := []struct {
+		name    string
+		key     []byte
+		varName string
+		value   string
+		wantErr string
+	}{
+		{name: "wrong key", key: otherKey, value: value, wantErr: "wrong key, wrong variable or corrupted value"},
+		{name: "wrong variable", key: key, varName: "API_TOKEN", value: value, wantErr: "wrong key, wrong variable or corrupted value"},
+		{name: "tampered", key: key, value: string(tampered), wantErr: "wrong key, wrong variable or corrupted value"},
+		{name: "not base64", key: key, value: "enc:v1:!!!", wantErr: "malformed encrypted value"},
+		{name: "too short", key: key, value: "enc:v1:AAAA", wantErr: "malformed encrypted value"},
+		{name: "not encrypted", key: key, value: "plain", wantErr: "not an encrypted value"},
+		{name: "short key", key: key[:16], value: value, wantErr: "key must be 32 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			varName := tt.varName
+			if varName == "" {
+				varName = "DB_PASSWORD"
+			}
+			_, err := DecryptValue(tt.key, varName, tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadKey_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "short.key")
+	if err := os.WriteFile(path, []byte("c2hvcnQ="), 0600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+	if _, err := LoadKey(path); err == nil || !strings.Contains(err.Error(), "expected a base64-encoded 32-byte key") {
+		t.Errorf("error = %v, want invalid key error", err)
+	}
+	if _, err := LoadKey(filepath.Join(dir, "missing.key")); err == nil || !strings.Contains(err.Error(), "cannot read key file") {
+		t.Errorf("error = %v, want read error", err)
+	}
+
+	// Key files must not be accessible by group or others
+	encoded, _ := GenerateKey()
+	for _, perm := range []os.FileMode{0644, 0640, 0604, 0666} {
+		path := writeSecret(t, dir, fmt.Sprintf("key-%04o.key", perm), encoded, perm)
+		want := fmt.Sprintf("is accessible by group or others (mode %04o)", perm)
+		if _, err := LoadKey(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("mode %04o: error = %v, want %q", perm, err, want)
+		}
+	}
+	if _, err := LoadKey(writeSecret(t, dir, "owner.key", encoded, 0400)); err != nil {
+		t.Errorf("mode 0400: unexpected error: %v", err)
+	}
+	if _, err := LoadKey(dir); err == nil || !strings.Contains(err.Error(), "is not a regular file") {
+		t.Errorf("error = %v, want not a regular file error", err)
+	}
+}
+
+func TestDecryptEntries(t *testing.T) {
+	keyFile, key := writeKey(t)
+	encrypted, err := EncryptValue(key, "DB_PASSWORD", "s3cret")
+	if err != nil {
+		t.Fatalf("EncryptValue() error: %v", err)
+	}
+	entries := []DotenvEntry{
+		{Key: "DB_URL", Value: "postgres://db", File: ".env", Line: 1},
+		{Key: "DB_PASSWORD", Value: encrypted, File: ".env", Line: 2},
+	}
+
+	got, err := DecryptEntries(entries, []string{KeyFileEnv + "=" + keyFile})
+	if err != nil {
+		t.Fatalf("DecryptEntries() error: %v", err)
+	}
+	if got[0].Value != "postgres://db" || got[0].Encrypted {
+		t.Errorf("plain entry = %+v, want unchanged", got[0])
+	}
+	if got[1].Value != "s3cret" || !got[1].Encrypted {
+		t.Errorf("encrypted entry = %+v, want decrypted and marked", got[1])
+	}
+	if entries[1].Value != encrypted {
+		t.Error("DecryptEntries() modified its input")
+	}
+
+	// The key file is only needed when something is encrypted
+	if _, err := DecryptEntries(entries[:1], nil); err != nil {
+		t.Errorf("plain entries without a key file: %v", err)
+	}
+	if _, err := DecryptEntries(entries, nil); err == nil || err.Error() != ".env:2: DB_PASSWORD is encrypted but ADMIT_KEY_FILE is not set" {
+		t.Errorf("error = %v, want missing key file error", err)
+	}
+
+	otherFile, _ := writeKey(t)
+	if _, err := DecryptEntries(entries, []string{KeyFileEnv + "=" + otherFile}); err == nil || !strings.Contains(err.Error(), ".env:2: cannot decrypt DB_PASSWORD: wrong key") {
+		t.Errorf("error = %v, want decrypt error with location", err)
+	}
+
+	// A value copied to another variable does not decrypt
+	moved := []DotenvEntry{{Key: "API_TOKEN", Value: encrypted, File: ".env", Line: 3}}
+	if _, err := DecryptEntries(moved, []string{KeyFileEnv + "=" + keyFile}); err == nil || !strings.Contains(err.Error(), ".env:3: cannot decrypt API_TOKEN: wrong key, wrong variable") {
+		t.Errorf("error = %v, want decrypt error for the moved value", err)
+	}
+}
+
+func TestResolveWithOptions_Encrypted(t *testing.T) {
+	entries := []DotenvEntry{
+		{Key: "DB_PASSWORD", Value: "p$ss${word}", File: ".env", Line: 2, Encrypted: true},
+		{Key: "API_TOKEN", Value: "t0ken", File: ".env", Line: 3, Encrypted: true},
+		{Key: "DB_HOST", Value: "db", File: ".env", Line: 4},
+	}
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.password": {Path: "db.password", Type: schema.TypeString, Sensitive: boolPtr(false)},
+			"db.dsn":      {Path: "db.dsn", Type: schema.TypeString},
+			"db.host":     {Path: "db.host", Type: schema.TypeString},
+		},
+	}
+	opts := Options{EnvFile: entries, Interpolate: true}
+
+	byKey := resolveByKey(t, s, []string{"DB_DSN=postgres://${DB_HOST}?token=${API_TOKEN}"}, opts)
+
+	if rv := byKey["db.password"]; rv.Value != "p$ss${word}" || !rv.Sensitive || !rv.Source.Encrypted {
+		t.Errorf("db.password = %+v, want literal sensitive decrypted value", rv)
+	}
+	if got := byKey["db.password"].Source.String(); got != "env file .env:2 (DB_PASSWORD) [encrypted]" {
+		t.Errorf("provenance = %q", got)
+	}
+	if rv := byKey["db.dsn"]; rv.Value != "postgres://db?token=t0ken" || !rv.Sensitive {
+		t.Errorf("db.dsn = %+v, want sensitive after embedding a decrypted variable", rv)
+	}
+	if rv := byKey["db.host"]; rv.Sensitive {
+		t.Errorf("db.host = %+v, want plain env file values to stay non-sensitive", rv)
+	}
+}
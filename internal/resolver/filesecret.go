@@ -0,0 +1,59 @@
+package resolver
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// FileSuffix is appended to an env var name to find its secret file variable.
+// e.g., "DB_PASSWORD" -> "DB_PASSWORD_FILE"
+const FileSuffix = "_FILE"
+
+// DefaultMaxFileSize is the default size limit for secret files (64 KiB)
+const DefaultMaxFileSize int64 = 64 * 1024
+
+// ReadSecretFile reads a mounted secret file (Docker/Kubernetes _FILE convention).
+// The file must be a regular file, must not be writable by others, and must not
+// exceed maxSize bytes. Trailing newlines are trimmed from the content.
+func ReadSecretFile(path string, maxSize int64) (string, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFileSize
+	}
+
+	// Stat follows symlinks, which secret mounts commonly use
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file", path)
+	}
+
+	if info.Mode().Perm()&0002 != 0 {
+		return "", fmt.Errorf("%s is world-writable (mode %04o)", path, info.Mode().Perm())
+	}
+
+	if info.Size() > maxSize {
+		return "", fmt.Errorf("%s exceeds size limit (%d > %d bytes)", path, info.Size(), maxSize)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// Read at most maxSize+1 bytes in case the file grew after stat
+	content, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(content)) > maxSize {
+		return "", fmt.Errorf("%s exceeds size limit (%d bytes)", path, maxSize)
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
@@ -0,0 +1,153 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"admit/internal/schema"
+)
+
+func writeSecret(t *testing.T, dir, name, content string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+	// Chmod explicitly so the umask doesn't interfere
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatalf("failed to chmod secret: %v", err)
+	}
+	return path
+}
+
+func TestReadSecretFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		mode    os.FileMode
+		maxSize int64
+		want    string
+		wantErr string
+	}{
+		{name: "trailing newline trimmed", content: "s3cret\n", mode: 0400, want: "s3cret"},
+		{name: "CRLF trimmed", content: "s3cret\r\n", mode: 0400, want: "s3cret"},
+		{name: "inner newlines preserved", content: "line1\nline2\n", mode: 0444, want: "line1\nline2"},
+		{name: "no newline", content: "s3cret", mode: 0644, want: "s3cret"},
+		{name: "world-writable rejected", content: "s3cret", mode: 0666, wantErr: "world-writable"},
+		{name: "size limit enforced", content: "0123456789", mode: 0400, maxSize: 5, wantErr: "exceeds size limit"},
+		{name: "exactly at size limit", content: "01234", mode: 0400, maxSize: 5, want: "01234"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeSecret(t, dir, "secret"+string(rune('a'+i)), tt.content, tt.mode)
+			got, err := ReadSecretFile(path, tt.maxSize)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSecretFile_NotRegular(t *testing.T) {
+	dir := t.TempDir()
+	_, err := ReadSecretFile(dir, 0)
+	if err == nil || !strings.Contains(err.Error(), "not a regular file") {
+		t.Errorf("expected not-a-regular-file error, got %v", err)
+	}
+}
+
+func TestReadSecretFile_Symlink(t *testing.T) {
+	// Kubernetes secret mounts expose files through symlinks
+	dir := t.TempDir()
+	target := writeSecret(t, dir, "target", "linked\n", 0400)
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	got, err := ReadSecretFile(link, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "linked" {
+		t.Errorf("got %q, want %q", got, "linked")
+	}
+}
+
+func TestResolveWithOptions_FileSecrets(t *testing.T) {
+	dir := t.TempDir()
+	secretPath := writeSecret(t, dir, "db", "from-file\n", 0400)
+
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.password": {Path: "db.password", Type: schema.TypeString, Required: true},
+			"db.user":     {Path: "db.user", Type: schema.TypeString, Required: true},
+		},
+	}
+
+	environ := []string{
+		"DB_PASSWORD_FILE=" + secretPath,
+		"DB_USER=app",
+		"DB_USER_FILE=" + filepath.Join(dir, "ignored"),
+	}
+
+	// Disabled: _FILE variables are ignored
+	results, err := ResolveWithOptions(s, environ, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, r := range results {
+		if r.Key == "db.password" && r.Present {
+			t.Error("expected db.password to be unset when file secrets are disabled")
+		}
+	}
+
+	// Enabled: unset vars are read from _FILE, set vars win
+	results, err = ResolveWithOptions(s, environ, Options{FileSecrets: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, r := range results {
+		switch r.Key {
+		case "db.password":
+			if !r.Present || r.Value != "from-file" || r.Source.Kind != SourceFile || r.Source.Path != secretPath {
+				t.Errorf("db.password = %+v, want value from %s", r, secretPath)
+			}
+			if !r.Sensitive {
+				t.Error("db.password from a secret file is not sensitive")
+			}
+		case "db.user":
+			if r.Value != "app" || r.Source.Kind != SourceEnv || r.Sensitive {
+				t.Errorf("db.user = %+v, want env value to win over _FILE", r)
+			}
+		}
+	}
+}
+
+func TestResolveWithOptions_FileSecretErrors(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.password": {Path: "db.password", Type: schema.TypeString, Required: true},
+		},
+	}
+	environ := []string{"DB_PASSWORD_FILE=/nonexistent/secret"}
+
+	_, err := ResolveWithOptions(s, environ, Options{FileSecrets: true})
+	if err == nil || !strings.Contains(err.Error(), "DB_PASSWORD_FILE") {
+		t.Errorf("expected error naming DB_PASSWORD_FILE, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"admit/internal/codes"
+	"admit/internal/execjson"
+)
+
+// DefaultHelperTimeout is how long a helper may run when no timeout is configured
+const DefaultHelperTimeout = 10 * time.Second
+
+// Helper exit status policies
+const (
+	HelperOnErrorFail   = "fail"   // A non-zero exit blocks resolution (default)
+	HelperOnErrorIgnore = "ignore" // A non-zero exit is treated as "no values"
+)
+
+// HelperConfig configures the helper source: an executable that is given the
+// schema's key paths as a JSON array on stdin and prints a JSON object mapping
+// key paths to string values on stdout.
+type HelperConfig struct {
+	Command string        // Executable path (or name looked up in PATH)
+	Args    []string      // Arguments passed to the executable
+	Timeout time.Duration // Time limit for the helper (0 uses DefaultHelperTimeout)
+	OnError string        // Non-zero exit policy: "fail" (default) or "ignore"
+}
+
+// Identity returns a string identifying the helper for execution identity:
+// its resolved executable path followed by its arguments
+func (c HelperConfig) Identity() string {
+	path := c.Command
+	if resolved, err := exec.LookPath(path); err == nil {
+		path = resolved
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return strings.Join(append([]string{SourceNameHelper + ":" + path}, c.Args...), "\x00")
+}
+
+// validate checks the helper settings
+func (c HelperConfig) validate() error {
+	switch c.OnError {
+	case "", HelperOnErrorFail, HelperOnErrorIgnore:
+		return nil
+	}
+	return fmt.Errorf("helper on_error must be '%s' or '%s', got '%s'", HelperOnErrorFail, HelperOnErrorIgnore, c.OnError)
+}
+
+// helperSource runs the helper once, on the first lookup, for all keys
+type helperSource struct {
+	config  HelperConfig
+	keys    []string
+	environ []string
+
+	ran    bool
+	values map[string]string
+	err    error
+}
+
+// NewHelperSource creates a source backed by a helper executable.
+// keys are the schema paths requested from the helper; environ is its environment.
+func NewHelperSource(config HelperConfig, keys []string, environ []string) Source {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	return &helperSource{config: config, keys: sorted, environ: environ}
+}
+
+func (s *helperSource) Name() string { return SourceNameHelper }
+
+// Lookup matches on the schema path. The helper is not run until a key reaches it,
+// so it never runs when earlier sources supply every key.
+func (s *helperSource) Lookup(path, name string) (string, Provenance, bool, error) {
+	if !s.ran {
+		s.values, s.err = runHelper(s.config, s.keys, s.environ)
+		if s.err != nil {
+			s.err = &SourceError{Code: codes.HelperFailed, Err: s.err}
+		}
+		s.ran = true
+	}
+	if s.err != nil {
+		return "", Provenance{}, false, s.err
+	}
+	value, ok := s.values[path]
+	if !ok {
+		return "", Provenance{}, false, nil
+	}
+	return value, Provenance{Kind: SourceHelper, Var: path, Path: s.config.Command}, true, nil
+}
+
+// runHelper executes the helper with keys on stdin and parses its output.
+// Timeouts, start failures and malformed output are always errors; a non-zero
+// exit is an error unless the policy is "ignore". Error messages include the
+// helper's stderr.
+func runHelper(config HelperConfig, keys []string, environ []string) (map[string]string, error) {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHelperTimeout
+	}
+
+	var raw map[string]interface{}
+	err := execjson.Run(execjson.Command{Path: config.Command, Args: config.Args, Env: environ, Timeout: timeout}, keys, &raw)
+	var exitErr *execjson.ExitError
+	var outputErr *execjson.OutputError
+	switch {
+	case errors.As(err, &exitErr):
+		if config.OnError == HelperOnErrorIgnore {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("helper %s %v", config.Command, exitErr)
+	case errors.As(err, &outputErr):
+		return nil, fmt.Errorf("helper %s: invalid output: expected a JSON object of key/value strings: %v", config.Command, outputErr.Err)
+	case err != nil:
+		return nil, fmt.Errorf("helper %s: %w", config.Command, err)
+	}
+
+	return parseHelperOutput(config.Command, raw)
+}
+
+// parseHelperOutput converts the helper's {key: value} object.
+// Null values are treated as unset; other non-string values are errors.
+func parseHelperOutput(command string, raw map[string]interface{}) (map[string]string, error) {
+	values := make(map[string]string, len(raw))
+	for key, v := range raw {
+		switch value := v.(type) {
+		case nil:
+			continue
+		case string:
+			values[key] = value
+		default:
+			return nil, fmt.Errorf("helper %s: invalid output: value for '%s' must be a string", command, key)
+		}
+	}
+	return values, nil
+}
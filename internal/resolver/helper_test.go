@@ -0,0 +1,242 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"admit/internal/execjson"
+	"admit/internal/schema"
+)
+
+// writeHelper writes an executable shell script helper and returns its path
+func writeHelper(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "helper.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("failed to write helper: %v", err)
+	}
+	return path
+}
+
+func TestRunHelper(t *testing.T) {
+	// The helper echoes its stdin back through a value so the request can be checked
+	helper := writeHelper(t, `keys=$(cat)
+printf '{"db.password": "s3cret", "keys": "%s", "cache.ttl": null}' "$(echo "$keys" | sed 's/"/\\"/g')"
+`)
+
+	values, err := runHelper(HelperConfig{Command: helper}, []string{"cache.ttl", "db.password"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"db.password": "s3cret",
+		"keys":        `["cache.ttl","db.password"]`,
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %v, want %v", values, want)
+	}
+}
+
+func TestRunHelper_ArgsAndEnv(t *testing.T) {
+	helper := writeHelper(t, `printf '{"a": "%s %s"}' "$1" "$PROFILE"`)
+
+	values, err := runHelper(HelperConfig{Command: helper, Args: []string{"--prod"}}, nil, []string{"PROFILE=team"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values["a"] != "--prod team" {
+		t.Errorf("a = %q, want args and environment passed to the helper", values["a"])
+	}
+}
+
+func TestRunHelper_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		config  HelperConfig
+		wantErr string
+	}{
+		{
+			name:    "non-zero exit includes stderr",
+			script:  "echo 'vault sealed' >&2\nexit 3\n",
+			wantErr: "exited with status 3: vault sealed",
+		},
+		{
+			name:    "timeout",
+			script:  "sleep 5\n",
+			config:  HelperConfig{Timeout: 100 * time.Millisecond},
+			wantErr: "timed out after 100ms",
+		},
+		{
+			name:    "invalid JSON",
+			script:  "echo not json\n",
+			wantErr: "invalid output: expected a JSON object",
+		},
+		{
+			name:    "non-string value",
+			script:  `echo '{"db.pool": 10}'`,
+			wantErr: "value for 'db.pool' must be a string",
+		},
+		{
+			name:    "invalid output even with ignore policy",
+			script:  "echo '[]'\n",
+			config:  HelperConfig{OnError: HelperOnErrorIgnore},
+			wantErr: "invalid output",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Command = writeHelper(t, tt.script)
+			_, err := runHelper(config, []string{"db.url"}, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := runHelper(HelperConfig{Command: filepath.Join(t.TempDir(), "missing")}, nil, nil); err == nil {
+		t.Error("expected error for missing helper, got nil")
+	}
+}
+
+func TestRunHelper_IgnorePolicy(t *testing.T) {
+	helper := writeHelper(t, "echo '{\"a\": \"partial\"}'\nexit 1\n")
+
+	values, err := runHelper(HelperConfig{Command: helper, OnError: HelperOnErrorIgnore}, nil, nil)
+	if err != nil || values != nil {
+		t.Errorf("got %v, %v; want no values and no error", values, err)
+	}
+}
+
+func TestRunHelper_StderrBounded(t *testing.T) {
+	helper := writeHelper(t, "head -c 10000 /dev/zero | tr '\\0' x >&2\nexit 1\n")
+
+	_, err := runHelper(HelperConfig{Command: helper}, nil, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if len(err.Error()) > execjson.MaxStderr+200 || !strings.HasSuffix(err.Error(), " ...") {
+		t.Errorf("stderr not truncated: %d bytes", len(err.Error()))
+	}
+}
+
+func TestResolveWithOptions_Helper(t *testing.T) {
+	// Counts its runs in a file to check the helper runs once for all keys
+	runs := filepath.Join(t.TempDir(), "runs")
+	helper := writeHelper(t, `echo run >> `+runs+`
+echo '{"db.password": "s3cret", "db.user": "app", "db.url": "from-helper"}'
+`)
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url":      {Path: "db.url", Type: schema.TypeString},
+			"db.password": {Path: "db.password", Type: schema.TypeString},
+			"db.user":     {Path: "db.user", Type: schema.TypeString, Sensitive: boolPtr(false)},
+			"db.host":     {Path: "db.host", Type: schema.TypeString, Sensitive: boolPtr(true)},
+		},
+	}
+
+	byKey := resolveByKey(t, s, []string{"DB_URL=from-env", "DB_HOST=localhost"}, Options{Helper: HelperConfig{Command: helper}})
+
+	if rv := byKey["db.url"]; rv.Value != "from-env" || rv.Sensitive {
+		t.Errorf("db.url = %q (sensitive %v), want env to win over the helper", rv.Value, rv.Sensitive)
+	}
+	want := Provenance{Kind: SourceHelper, Var: "db.password", Path: helper}
+	if rv := byKey["db.password"]; rv.Value != "s3cret" || !rv.Sensitive || !reflect.DeepEqual(rv.Source, want) {
+		t.Errorf("db.password = %+v, want sensitive helper value", rv)
+	}
+	if rv := byKey["db.user"]; rv.Value != "app" || rv.Sensitive {
+		t.Errorf("db.user = %+v, want sensitive: false to override the helper default", rv)
+	}
+	if rv := byKey["db.host"]; !rv.Sensitive || rv.DisplayValue() != RedactedValue {
+		t.Errorf("db.host = %+v, want sensitive: true to apply to env values", rv)
+	}
+
+	content, err := os.ReadFile(runs)
+	if err != nil {
+		t.Fatalf("failed to read run count: %v", err)
+	}
+	if n := strings.Count(string(content), "run"); n != 1 {
+		t.Errorf("helper ran %d times, want 1", n)
+	}
+}
+
+func TestResolveWithOptions_HelperNotRunWhenUnneeded(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url": {Path: "db.url", Type: schema.TypeString},
+		},
+	}
+	opts := Options{Helper: HelperConfig{Command: filepath.Join(t.TempDir(), "missing")}}
+
+	if _, err := ResolveWithOptions(s, []string{"DB_URL=x"}, opts); err != nil {
+		t.Errorf("helper ran although every key was set: %v", err)
+	}
+}
+
+func TestResolveWithOptions_HelperErrorReportedOnce(t *testing.T) {
+	helper := writeHelper(t, "exit 2\n")
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"a": {Path: "a", Type: schema.TypeString},
+			"b": {Path: "b", Type: schema.TypeString},
+		},
+	}
+
+	_, err := ResolveWithOptions(s, nil, Options{Helper: HelperConfig{Command: helper}})
+	if err == nil || strings.Count(err.Error(), "exited with status 2") != 1 {
+		t.Errorf("error = %v, want the helper failure reported once", err)
+	}
+}
+
+func TestResolveWithOptions_InterpolatedSensitive(t *testing.T) {
+	helper := writeHelper(t, `echo '{"db.password": "${NOT_EXPANDED}"}'`)
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.password": {Path: "db.password", Type: schema.TypeString},
+			"db.dsn":      {Path: "db.dsn", Type: schema.TypeString},
+		},
+	}
+	opts := Options{Helper: HelperConfig{Command: helper}, Interpolate: true}
+
+	byKey := resolveByKey(t, s, []string{"DB_DSN=postgres://app:${DB_PASSWORD}@db"}, opts)
+
+	if rv := byKey["db.password"]; rv.Value != "${NOT_EXPANDED}" {
+		t.Errorf("db.password = %q, want helper output taken literally", rv.Value)
+	}
+	if rv := byKey["db.dsn"]; !rv.Sensitive {
+		t.Errorf("db.dsn = %+v, want sensitive after embedding a sensitive value", rv)
+	}
+}
+
+func TestApplyFetched(t *testing.T) {
+	resolved := []ResolvedValue{
+		{Key: "db.password", EnvVar: "DB_PASSWORD", Value: "s3cret", Present: true, Source: Provenance{Kind: SourceHelper}},
+		{Key: "db.user", EnvVar: "DB_USER", Value: "app", Present: true, Source: Provenance{Kind: SourceHelper}},
+		{Key: "db.url", EnvVar: "DB_URL", Value: "x", Present: true, Source: Provenance{Kind: SourceEnv, Var: "DB_URL"}},
+	}
+
+	got := ApplyFetched([]string{"PATH=/bin", "DB_USER=old", "DB_URL=x"}, resolved)
+	want := []string{"PATH=/bin", "DB_USER=app", "DB_URL=x", "DB_PASSWORD=s3cret"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ApplyFetched() = %v, want %v", got, want)
+	}
+}
+
+func TestHelperConfig_Identity(t *testing.T) {
+	helper := writeHelper(t, "")
+	a := HelperConfig{Command: helper}.Identity()
+	b := HelperConfig{Command: helper, Args: []string{"--prod"}}.Identity()
+	if !strings.HasPrefix(a, "helper:"+helper) || a == b {
+		t.Errorf("identities %q and %q, want path-based and argument-sensitive", a, b)
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,235 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// rawVar is a variable's value before interpolation
+type rawVar struct {
+	value   string
+	literal bool // Value is used as-is (interpolate: false or secret file content)
+}
+
+// expander expands ${VAR} and ${VAR:-default} references with cycle detection.
+// Variables are looked up through a caller-provided function so that references
+// to schema keys see the key's fully resolved value (aliases, defaults, _FILE).
+type expander struct {
+	lookup func(name string) (rawVar, bool)
+	cache  map[string]string
+	errs   map[string]error
+	stack  []string
+}
+
+// newExpander creates an expander over the given lookup function
+func newExpander(lookup func(name string) (rawVar, bool)) *expander {
+	return &expander{
+		lookup: lookup,
+		cache:  make(map[string]string),
+		errs:   make(map[string]error),
+	}
+}
+
+// expandVar returns the expanded value of a variable and whether it is set.
+// Unset variables expand to the empty string, as in the shell.
+func (e *expander) expandVar(name string) (string, bool, error) {
+	for i, active := range e.stack {
+		if active == name {
+			cycle := append(append([]string{}, e.stack[i:]...), name)
+			return "", false, fmt.Errorf("interpolation cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	raw, ok := e.lookup(name)
+	if !ok {
+		return "", false, nil
+	}
+	if raw.literal {
+		return raw.value, true, nil
+	}
+	if value, cached := e.cache[name]; cached {
+		return value, true, nil
+	}
+	if err, failed := e.errs[name]; failed {
+		return "", false, err
+	}
+
+	e.stack = append(e.stack, name)
+	value, _, err := e.expand(raw.value)
+	e.stack = e.stack[:len(e.stack)-1]
+
+	if err != nil {
+		e.errs[name] = err
+		return "", false, err
+	}
+	e.cache[name] = value
+	return value, true, nil
+}
+
+// expandKey expands a config key's own raw value, registered under name for cycle detection
+func (e *expander) expandKey(name string, raw string) (string, []string, error) {
+	e.stack = append(e.stack, name)
+	defer func() { e.stack = e.stack[:len(e.stack)-1] }()
+	return e.expand(raw)
+}
+
+// expand expands all references in s and returns the variables it referenced directly.
+// "$$" is an escaped literal "$"; a "$" not followed by "{" is kept as-is.
+func (e *expander) expand(s string) (string, []string, error) {
+	var sb strings.Builder
+	var refs []string
+	seen := make(map[string]bool)
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if s[i+1] == '$' {
+			sb.WriteByte('$')
+			i++
+			continue
+		}
+		if s[i+1] != '{' {
+			sb.WriteByte('$')
+			continue
+		}
+
+		end := matchingBrace(s, i+2)
+		if end == -1 {
+			return "", nil, fmt.Errorf("unterminated '${' in %q", s)
+		}
+		body := s[i+2 : end]
+		i = end
+
+		name, fallback, hasFallback := strings.Cut(body, ":-")
+		if !isVarName(name) {
+			return "", nil, fmt.Errorf("invalid variable reference '${%s}'", body)
+		}
+		if !seen[name] {
+			seen[name] = true
+			refs = append(refs, name)
+		}
+
+		value, set, err := e.expandVar(name)
+		if err != nil {
+			return "", nil, err
+		}
+		if hasFallback && (!set || value == "") {
+			value, _, err = e.expand(fallback)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+		sb.WriteString(value)
+	}
+
+	return sb.String(), refs, nil
+}
+
+// matchingBrace returns the index of the '}' closing a "${" whose body starts at start,
+// accounting for nested "${...}" in defaults. Returns -1 if there is none.
+func matchingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch {
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isVarName returns true if name is a valid variable name for interpolation
+func isVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		isLetter := (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_'
+		isDigit := ch >= '0' && ch <= '9'
+		if !isLetter && !(i > 0 && isDigit) {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyInterpolated updates environ so the executed command sees interpolated values.
+// Each value whose expanded form differs from the raw value in environ (by
+// references or $$ escapes) replaces the variable it was read from.
+// The returned slice is a new slice; environ is not modified.
+func ApplyInterpolated(environ []string, resolved []ResolvedValue) []string {
+	raw := parseEnviron(environ)
+	updates := make(map[string]string)
+	for _, rv := range resolved {
+		if !rv.Present || (rv.Source.Kind != SourceEnv && rv.Source.Kind != SourceEnvFile) {
+			continue
+		}
+		if value, ok := raw[rv.Source.Var]; !ok || value != rv.Value {
+			updates[rv.Source.Var] = rv.Value
+		}
+	}
+	return setEnviron(environ, updates)
+}
+
+// fetchedKinds are the sources whose values the executed command cannot read itself
+var fetchedKinds = map[SourceKind]bool{
+	SourceVault:  true,
+	SourceHelper: true,
+}
+
+// ApplyFetched updates environ so the executed command sees values fetched from
+// Vault and the helper, under each key's env var (replacing any existing entry).
+// The returned slice is a new slice; environ is not modified.
+func ApplyFetched(environ []string, resolved []ResolvedValue) []string {
+	updates := make(map[string]string)
+	for _, rv := range resolved {
+		if rv.Present && fetchedKinds[rv.Source.Kind] {
+			updates[rv.EnvVar] = rv.Value
+		}
+	}
+	return setEnviron(environ, updates)
+}
+
+// setEnviron returns a copy of environ with updates applied; names not already
+// present are appended in sorted order
+func setEnviron(environ []string, updates map[string]string) []string {
+	if len(updates) == 0 {
+		return environ
+	}
+
+	applied := make(map[string]bool)
+	result := make([]string, 0, len(environ)+len(updates))
+	for _, env := range environ {
+		idx := strings.Index(env, "=")
+		if idx != -1 {
+			if value, ok := updates[env[:idx]]; ok {
+				result = append(result, env[:idx]+"="+value)
+				applied[env[:idx]] = true
+				continue
+			}
+		}
+		result = append(result, env)
+	}
+
+	var added []string
+	for name := range updates {
+		if !applied[name] {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+	for _, name := range added {
+		result = append(result, name+"="+updates[name])
+	}
+	return result
+}
@@ -0,0 +1,216 @@
+package resolver
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"admit/internal/schema"
+)
+
+func TestResolveWithOptions_Interpolate(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url":  {Path: "db.url", Type: schema.TypeString},
+			"db.user": {Path: "db.user", Type: schema.TypeString, Default: strPtr("app")},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		environ  []string
+		want     string
+		wantRefs []string
+	}{
+		{
+			name:     "schema key and plain env var",
+			environ:  []string{"DB_URL=postgres://${DB_USER}@${DB_HOST}/app", "DB_HOST=db.internal"},
+			want:     "postgres://app@db.internal/app",
+			wantRefs: []string{"DB_USER", "DB_HOST"},
+		},
+		{
+			name:     "default when unset",
+			environ:  []string{"DB_URL=${DB_HOST:-localhost}"},
+			want:     "localhost",
+			wantRefs: []string{"DB_HOST"},
+		},
+		{
+			name:     "default when empty",
+			environ:  []string{"DB_URL=${DB_HOST:-localhost}", "DB_HOST="},
+			want:     "localhost",
+			wantRefs: []string{"DB_HOST"},
+		},
+		{
+			name:     "nested default",
+			environ:  []string{"DB_URL=${DB_HOST:-${FALLBACK_HOST:-localhost}}", "FALLBACK_HOST=replica"},
+			want:     "replica",
+			wantRefs: []string{"DB_HOST"},
+		},
+		{
+			name:     "transitive references",
+			environ:  []string{"DB_URL=${DB_HOST}", "DB_HOST=${REGION}.db", "REGION=eu"},
+			want:     "eu.db",
+			wantRefs: []string{"DB_HOST"},
+		},
+		{
+			name:    "escaped dollar",
+			environ: []string{"DB_URL=p$$ss$word"},
+			want:    "p$ss$word",
+		},
+		{
+			name:     "unset expands to empty",
+			environ:  []string{"DB_URL=[${MISSING}]"},
+			want:     "[]",
+			wantRefs: []string{"MISSING"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rv := resolveByKey(t, s, tt.environ, Options{Interpolate: true})["db.url"]
+			if rv.Value != tt.want {
+				t.Errorf("value = %q, want %q", rv.Value, tt.want)
+			}
+			if !reflect.DeepEqual(rv.Source.References, tt.wantRefs) {
+				t.Errorf("references = %v, want %v", rv.Source.References, tt.wantRefs)
+			}
+		})
+	}
+}
+
+func TestResolveWithOptions_InterpolateDisabled(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url": {Path: "db.url", Type: schema.TypeString},
+		},
+	}
+	rv := resolveByKey(t, s, []string{"DB_URL=${DB_HOST}", "DB_HOST=db"}, Options{})["db.url"]
+	if rv.Value != "${DB_HOST}" {
+		t.Errorf("value = %q, want value untouched without Interpolate", rv.Value)
+	}
+}
+
+func TestResolveWithOptions_InterpolateLiteral(t *testing.T) {
+	dir := t.TempDir()
+	secretPath := writeSecret(t, dir, "db", "pa${ss}\n", 0400)
+
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url":      {Path: "db.url", Type: schema.TypeString},
+			"db.password": {Path: "db.password", Type: schema.TypeString},
+			"app.banner":  {Path: "app.banner", Type: schema.TypeString, NoInterpolate: true},
+		},
+	}
+	environ := []string{
+		"DB_URL=postgres://u:${DB_PASSWORD}@h/app",
+		"DB_PASSWORD_FILE=" + secretPath,
+		"APP_BANNER=costs ${PRICE}",
+		"PRICE=5",
+	}
+
+	byKey := resolveByKey(t, s, environ, Options{Interpolate: true, FileSecrets: true})
+
+	if got := byKey["db.password"].Value; got != "pa${ss}" {
+		t.Errorf("db.password = %q, want secret file content taken literally", got)
+	}
+	if got := byKey["db.url"].Value; got != "postgres://u:pa${ss}@h/app" {
+		t.Errorf("db.url = %q, want literal secret substituted", got)
+	}
+	if got := byKey["app.banner"]; got.Value != "costs ${PRICE}" || got.Source.References != nil {
+		t.Errorf("app.banner = %+v, want interpolate: false to keep value as-is", got)
+	}
+}
+
+func TestResolveWithOptions_InterpolateErrors(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url":  {Path: "db.url", Type: schema.TypeString},
+			"db.host": {Path: "db.host", Type: schema.TypeString},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		environ []string
+		wantErr []string
+	}{
+		{
+			name:    "self reference",
+			environ: []string{"DB_URL=${DB_URL}"},
+			wantErr: []string{"db.url: interpolation cycle: DB_URL -> DB_URL"},
+		},
+		{
+			name:    "cycle between keys",
+			environ: []string{"DB_URL=${DB_HOST}", "DB_HOST=${DB_URL}"},
+			wantErr: []string{
+				"db.host: interpolation cycle: DB_HOST -> DB_URL -> DB_HOST",
+				"db.url: interpolation cycle: DB_URL -> DB_HOST -> DB_URL",
+			},
+		},
+		{
+			name:    "cycle through plain env vars",
+			environ: []string{"DB_URL=${A}", "A=${B}", "B=${A}"},
+			wantErr: []string{"db.url: interpolation cycle: A -> B -> A"},
+		},
+		{
+			name:    "unterminated reference",
+			environ: []string{"DB_URL=${DB_HOST"},
+			wantErr: []string{"db.url: unterminated '${'"},
+		},
+		{
+			name:    "invalid name",
+			environ: []string{"DB_URL=${1BAD}"},
+			wantErr: []string{"db.url: invalid variable reference '${1BAD}'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ResolveWithOptions(s, tt.environ, Options{Interpolate: true})
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error = %q, want containing %q", err.Error(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyInterpolated(t *testing.T) {
+	environ := []string{"PATH=/bin", "DB_URL=${DB_HOST}/app", "DB_HOST=db"}
+	resolved := []ResolvedValue{
+		{Key: "db.url", EnvVar: "DB_URL", Value: "db/app", Present: true,
+			Source: Provenance{Kind: SourceEnv, Var: "DB_URL", References: []string{"DB_HOST"}}},
+		{Key: "db.host", EnvVar: "DB_HOST", Value: "db", Present: true,
+			Source: Provenance{Kind: SourceEnv, Var: "DB_HOST"}},
+	}
+
+	got := ApplyInterpolated(environ, resolved)
+	want := []string{"PATH=/bin", "DB_URL=db/app", "DB_HOST=db"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if environ[1] != "DB_URL=${DB_HOST}/app" {
+		t.Error("ApplyInterpolated modified its input")
+	}
+}
+
+func TestApplyInterpolated_EscapesOnly(t *testing.T) {
+	environ := []string{"DB_PASS=pa$$word", "DB_USER=app"}
+	resolved := []ResolvedValue{
+		{Key: "db.pass", EnvVar: "DB_PASS", Value: "pa$word", Present: true,
+			Source: Provenance{Kind: SourceEnv, Var: "DB_PASS"}},
+		{Key: "db.user", EnvVar: "DB_USER", Value: "app", Present: true,
+			Source: Provenance{Kind: SourceEnv, Var: "DB_USER"}},
+	}
+
+	// A value with no references but an escaped $ still differs from its raw form
+	got := ApplyInterpolated(environ, resolved)
+	want := []string{"DB_PASS=pa$word", "DB_USER=app"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
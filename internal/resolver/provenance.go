@@ -0,0 +1,81 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SourceKind identifies where a resolved value came from
+type SourceKind string
+
+const (
+	SourceEnv        SourceKind = "env"         // Process environment variable
+	SourceEnvFile    SourceKind = "env-file"    // --env-file entry
+	SourceFile       SourceKind = "file"        // Secret file named by <ENV_VAR>_FILE
+	SourceConfigDir  SourceKind = "config-dir"  // File in a --config-dir directory
+	SourceConfigFile SourceKind = "config-file" // Value in a --config-file document
+	SourceVault      SourceKind = "vault"       // Field of a Vault KV v2 secret
+	SourceHelper     SourceKind = "helper"      // Value printed by a helper executable
+	SourceDefault    SourceKind = "default"     // Schema default value
+)
+
+// Provenance records where a resolved value came from
+type Provenance struct {
+	Kind  SourceKind `json:"kind"`            // Where the value was found
+	Var   string     `json:"var,omitempty"`   // Variable or file key consulted (e.g., "DB_URL" or "DB_PASSWORD_FILE")
+	Path  string     `json:"path,omitempty"`  // Env file, secret file, config file, Vault secret or helper path
+	Line  int        `json:"line,omitempty"`  // Line in the env file or config file
+	Alias string     `json:"alias,omitempty"` // Alias name, when resolved through an alias
+
+	// Encrypted is set when the value was decrypted from an env file
+	Encrypted bool `json:"encrypted,omitempty"`
+
+	// References lists the variables an interpolated value referenced, in order
+	References []string `json:"references,omitempty"`
+}
+
+// String returns a human-readable description of the provenance
+func (p Provenance) String() string {
+	var desc string
+	switch p.Kind {
+	case SourceEnv:
+		desc = fmt.Sprintf("environment variable %s", p.Var)
+	case SourceEnvFile:
+		desc = fmt.Sprintf("env file %s:%d (%s)", p.Path, p.Line, p.Var)
+	case SourceFile:
+		desc = fmt.Sprintf("secret file %s (via %s)", p.Path, p.Var)
+	case SourceConfigDir:
+		desc = fmt.Sprintf("config dir file %s", p.Path)
+	case SourceConfigFile:
+		desc = fmt.Sprintf("config file %s:%d (%s)", p.Path, p.Line, p.Var)
+	case SourceVault:
+		desc = fmt.Sprintf("vault %s (%s)", p.Path, p.Var)
+	case SourceHelper:
+		desc = fmt.Sprintf("helper %s (%s)", p.Path, p.Var)
+	case SourceDefault:
+		desc = "schema default"
+	default:
+		return "(not set)"
+	}
+	if p.Alias != "" {
+		desc += fmt.Sprintf(" [alias %s]", p.Alias)
+	}
+	if p.Encrypted {
+		desc += " [encrypted]"
+	}
+	if len(p.References) > 0 {
+		desc += fmt.Sprintf(" [references %s]", strings.Join(p.References, ", "))
+	}
+	return desc
+}
+
+// ProvenanceMap returns the provenance of every present value, keyed by config path
+func ProvenanceMap(resolved []ResolvedValue) map[string]Provenance {
+	result := make(map[string]Provenance)
+	for _, rv := range resolved {
+		if rv.Present {
+			result[rv.Key] = rv.Source
+		}
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+
+	"admit/internal/schema"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func resolveByKey(t *testing.T, s schema.Schema, environ []string, opts Options) map[string]ResolvedValue {
+	t.Helper()
+	results, err := ResolveWithOptions(s, environ, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	byKey := make(map[string]ResolvedValue)
+	for _, r := range results {
+		byKey[r.Key] = r
+	}
+	return byKey
+}
+
+func TestResolveWithOptions_Provenance(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url":        {Path: "db.url", Type: schema.TypeString},
+			"db.user":       {Path: "db.user", Type: schema.TypeString},
+			"payments.mode": {Path: "payments.mode", Type: schema.TypeString, Aliases: []string{"PAY_MODE", "LEGACY_PAY_MODE"}},
+			"log.level":     {Path: "log.level", Type: schema.TypeString, Default: strPtr("info")},
+			"unset.key":     {Path: "unset.key", Type: schema.TypeString},
+		},
+	}
+
+	environ := []string{
+		"DB_URL=from-env",
+		"LEGACY_PAY_MODE=test",
+	}
+	opts := Options{
+		EnvFile: []DotenvEntry{
+			{Key: "DB_URL", Value: "from-file", File: ".env", Line: 1},
+			{Key: "DB_USER", Value: "app", File: ".env", Line: 2},
+		},
+	}
+
+	byKey := resolveByKey(t, s, environ, opts)
+
+	tests := []struct {
+		key   string
+		value string
+		want  Provenance
+	}{
+		{key: "db.url", value: "from-env", want: Provenance{Kind: SourceEnv, Var: "DB_URL"}},
+		{key: "db.user", value: "app", want: Provenance{Kind: SourceEnvFile, Var: "DB_USER", Path: ".env", Line: 2}},
+		{key: "payments.mode", value: "test", want: Provenance{Kind: SourceEnv, Var: "LEGACY_PAY_MODE", Alias: "LEGACY_PAY_MODE"}},
+		{key: "log.level", value: "info", want: Provenance{Kind: SourceDefault}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			rv := byKey[tt.key]
+			if !rv.Present || rv.Value != tt.value {
+				t.Errorf("value = %q (present=%v), want %q", rv.Value, rv.Present, tt.value)
+			}
+			if !reflect.DeepEqual(rv.Source, tt.want) {
+				t.Errorf("source = %+v, want %+v", rv.Source, tt.want)
+			}
+		})
+	}
+
+	if rv := byKey["unset.key"]; rv.Present || !reflect.DeepEqual(rv.Source, Provenance{}) {
+		t.Errorf("unset.key = %+v, want not present with zero provenance", rv)
+	}
+}
+
+func TestResolveWithOptions_EnvFileOverride(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url": {Path: "db.url", Type: schema.TypeString},
+		},
+	}
+	environ := []string{"DB_URL=from-env"}
+	opts := Options{
+		EnvFile: []DotenvEntry{
+			{Key: "DB_URL", Value: "first", File: ".env", Line: 1},
+			{Key: "DB_URL", Value: "second", File: ".env.local", Line: 4},
+		},
+		EnvFileOverride: true,
+	}
+
+	rv := resolveByKey(t, s, environ, opts)["db.url"]
+	want := Provenance{Kind: SourceEnvFile, Var: "DB_URL", Path: ".env.local", Line: 4}
+	if rv.Value != "second" || !reflect.DeepEqual(rv.Source, want) {
+		t.Errorf("db.url = %q from %+v, want %q from %+v", rv.Value, rv.Source, "second", want)
+	}
+}
+
+func TestResolveWithOptions_Precedence(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url": {Path: "db.url", Type: schema.TypeString, Aliases: []string{"DATABASE_URL"}, Default: strPtr("default")},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		environ  []string
+		wantKind SourceKind
+		want     string
+	}{
+		{name: "own var beats alias", environ: []string{"DB_URL=own", "DATABASE_URL=alias"}, wantKind: SourceEnv, want: "own"},
+		{name: "alias beats default", environ: []string{"DATABASE_URL=alias"}, wantKind: SourceEnv, want: "alias"},
+		{name: "default when nothing set", environ: nil, wantKind: SourceDefault, want: "default"},
+		{name: "empty value is still set", environ: []string{"DB_URL="}, wantKind: SourceEnv, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rv := resolveByKey(t, s, tt.environ, Options{})["db.url"]
+			if rv.Value != tt.want || rv.Source.Kind != tt.wantKind {
+				t.Errorf("got %q from %s, want %q from %s", rv.Value, rv.Source.Kind, tt.want, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestProvenance_String(t *testing.T) {
+	tests := []struct {
+		p    Provenance
+		want string
+	}{
+		{p: Provenance{Kind: SourceEnv, Var: "DB_URL"}, want: "environment variable DB_URL"},
+		{p: Provenance{Kind: SourceEnvFile, Var: "DB_URL", Path: ".env", Line: 3}, want: "env file .env:3 (DB_URL)"},
+		{p: Provenance{Kind: SourceFile, Var: "DB_PASSWORD_FILE", Path: "/run/secrets/db"}, want: "secret file /run/secrets/db (via DB_PASSWORD_FILE)"},
+		{p: Provenance{Kind: SourceConfigDir, Var: "db.url", Path: "/etc/config/db.url"}, want: "config dir file /etc/config/db.url"},
+		{p: Provenance{Kind: SourceConfigFile, Var: "db.url", Path: "config.yaml", Line: 7}, want: "config file config.yaml:7 (db.url)"},
+		{p: Provenance{Kind: SourceDefault}, want: "schema default"},
+		{p: Provenance{Kind: SourceEnv, Var: "DATABASE_URL", Alias: "DATABASE_URL"}, want: "environment variable DATABASE_URL [alias DATABASE_URL]"},
+		{p: Provenance{}, want: "(not set)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.p.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
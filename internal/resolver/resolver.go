@@ -1,6 +1,9 @@
 package resolver
 
 import (
+	"errors"
+	"fmt"
+	"sort"
 	"strings"
 
 	"admit/internal/schema"
@@ -8,33 +11,239 @@ import (
 
 // ResolvedValue represents a resolved config value
 type ResolvedValue struct {
-	Key     string // The config key path (e.g., "db.url")
-	EnvVar  string // The environment variable name (e.g., "DB_URL")
-	Value   string // The resolved value (empty if not set)
-	Present bool   // Whether the env var was set
+	Key     string     // The config key path (e.g., "db.url")
+	EnvVar  string     // The environment variable name (e.g., "DB_URL")
+	Value   string     // The resolved value (empty if not set)
+	Present bool       // Whether the env var was set
+	Source  Provenance // Where the value came from (zero if not present)
+
+	// Sensitive values are redacted in reports and never stored in plaintext
+	Sensitive bool
+}
+
+// Options controls optional resolution behavior
+type Options struct {
+	EnvFile         []DotenvEntry     // Entries from --env-file, in load order (later wins)
+	EnvFileOverride bool              // Env file entries win over the process environment
+	FileSecrets     bool              // Read <ENV_VAR>_FILE when <ENV_VAR> is unset
+	MaxFileSize     int64             // Size limit for secret files (0 uses DefaultMaxFileSize)
+	ConfigDir       []ConfigDirEntry  // Files from --config-dir, in load order (later wins)
+	ConfigDirMax    int64             // Size limit for config dir files (0 uses DefaultMaxFileSize)
+	ConfigFile      []ConfigFileEntry // Entries from --config-file, in load order (later wins)
+	Interpolate     bool              // Expand ${VAR} and ${VAR:-default} references in values
+	Vault           VaultConfig       // Vault KV v2 server (enabled when Address is set)
+	Helper          HelperConfig      // Helper executable (enabled when Command is set)
+
+	Order   []string // Source names in precedence order (empty uses DefaultSourceOrder)
+	Sources []Source // Explicit source chain; when set, Order and the loaded data above are not used
+}
+
+// EnvFileWins reports whether env file entries take precedence over the process
+// environment, either through EnvFileOverride or the position in Order
+func (o Options) EnvFileWins() bool {
+	if len(o.Order) == 0 {
+		return o.EnvFileOverride
+	}
+	for _, name := range o.Order {
+		switch name {
+		case SourceNameEnvFile:
+			return true
+		case SourceNameEnv:
+			return false
+		}
+	}
+	return false
+}
+
+// IdentitySources returns identifiers for the executables configured to supply
+// values (the helper), to be folded into the execution identity
+func (o Options) IdentitySources() []string {
+	if o.Helper.Command == "" {
+		return nil
+	}
+	return []string{o.Helper.Identity()}
+}
+
+// Offline returns the options without the sources that reach outside the
+// machine (Vault and the helper), so resolution does not depend on live services
+func (o Options) Offline() Options {
+	o.Vault = VaultConfig{}
+	o.Helper = HelperConfig{}
+	if len(o.Order) > 0 {
+		order := make([]string, 0, len(o.Order))
+		for _, name := range o.Order {
+			if name != SourceNameVault && name != SourceNameHelper {
+				order = append(order, name)
+			}
+		}
+		o.Order = order
+	}
+	return o
 }
 
 // Resolve looks up all config values from the environment.
 // It takes a schema and an environ slice (format: "KEY=VALUE") and returns
 // resolved values for each config key in the schema.
 func Resolve(s schema.Schema, environ []string) []ResolvedValue {
-	// Build a map from environ slice for O(1) lookups
-	envMap := parseEnviron(environ)
+	// Without optional sources, resolution cannot fail
+	results, _ := ResolveWithOptions(s, environ, Options{})
+	return results
+}
+
+// ResolveWithOptions resolves config values like Resolve, through a chain of sources.
+// For each key, the chain is consulted for the key's env var, then for each of
+// its aliases, and finally the schema default is used. The default chain is:
+//  1. the key's env var (process environment and env files, per EnvFileOverride)
+//  2. <ENV_VAR>_FILE, when FileSecrets is set
+//  3. a config dir file named after the key's path or env var
+//  4. a config file entry at the key's path
+//  5. the key's Vault secret field, when Vault is configured
+//  6. the helper's output for the key's path, when a helper is configured
+//
+// When Interpolate is set, values are then expanded (see interpolateAll).
+// Errors reading files or expanding values are collected for all keys.
+func ResolveWithOptions(s schema.Schema, environ []string, opts Options) ([]ResolvedValue, error) {
+	sources := opts.Sources
+	if sources == nil {
+		var err error
+		sources, err = BuildSources(s, environ, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	var results []ResolvedValue
+	var errs []error
 	for path, configKey := range s.Config {
 		envVar := PathToEnvVar(configKey.Path)
-		value, present := envMap[envVar]
+		rv := ResolvedValue{
+			Key:    path,
+			EnvVar: envVar,
+		}
+
+		value, source, found, err := resolveKey(configKey, envVar, sources)
+		if err != nil {
+			errs = append(errs, err)
+		} else if found {
+			rv.Value = value
+			rv.Present = true
+			rv.Source = source
+		}
+		rv.Sensitive = isSensitive(configKey, rv.Source)
 
-		results = append(results, ResolvedValue{
-			Key:     path,
-			EnvVar:  envVar,
-			Value:   value,
-			Present: present,
-		})
+		results = append(results, rv)
 	}
 
-	return results
+	if opts.Interpolate {
+		errs = append(errs, interpolateAll(s, results, variableSources(environ, opts))...)
+	}
+
+	if len(errs) > 0 {
+		return results, joinErrors(errs)
+	}
+
+	return results, nil
+}
+
+// joinErrors sorts errors for deterministic output and drops repeats,
+// such as a failed helper run reported by every key that reached it
+func joinErrors(errs []error) error {
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	unique := errs[:1]
+	for _, err := range errs[1:] {
+		if err.Error() != unique[len(unique)-1].Error() {
+			unique = append(unique, err)
+		}
+	}
+	return errors.Join(unique...)
+}
+
+// resolveKey consults the source chain for a single config key:
+// first for its env var, then for each alias, then falls back to the schema default
+func resolveKey(configKey schema.ConfigKey, envVar string, sources []Source) (string, Provenance, bool, error) {
+	if value, source, found, err := lookupChain(sources, configKey.Path, envVar); err != nil || found {
+		return value, source, found, err
+	}
+
+	for _, alias := range configKey.Aliases {
+		value, source, found, err := lookupChain(sources, configKey.Path, alias)
+		if err != nil {
+			return "", Provenance{}, false, err
+		}
+		if found {
+			source.Alias = alias
+			return value, source, true, nil
+		}
+	}
+
+	if configKey.Default != nil {
+		return *configKey.Default, Provenance{Kind: SourceDefault}, true, nil
+	}
+
+	return "", Provenance{}, false, nil
+}
+
+// interpolateAll expands ${VAR} references in resolved values in place.
+// References to a schema key's env var see that key's resolved value; other
+// names are looked up in vars (the environment and env files). Keys with
+// interpolate: false, file contents (secret files, config dirs), Vault secrets,
+// helper output and decrypted values are taken literally. Each expanded value records
+// the variables it referenced in its provenance, and becomes sensitive if one of them is.
+func interpolateAll(s schema.Schema, results []ResolvedValue, vars []Source) []error {
+	keyVars := make(map[string]rawVar)
+	sensitiveVars := make(map[string]bool)
+	for _, rv := range results {
+		if rv.Present {
+			keyVars[rv.EnvVar] = rawVar{value: rv.Value, literal: isLiteral(s.Config[rv.Key], rv)}
+			sensitiveVars[rv.EnvVar] = rv.Sensitive
+		}
+	}
+
+	e := newExpander(func(name string) (rawVar, bool) {
+		if raw, ok := keyVars[name]; ok {
+			return raw, true
+		}
+		if value, source, ok, _ := lookupChain(vars, "", name); ok {
+			if source.Encrypted {
+				sensitiveVars[name] = true
+			}
+			return rawVar{value: value, literal: source.Encrypted}, true
+		}
+		return rawVar{}, false
+	})
+
+	var errs []error
+	for i, rv := range results {
+		if !rv.Present || isLiteral(s.Config[rv.Key], rv) {
+			continue
+		}
+		value, refs, err := e.expandKey(rv.EnvVar, rv.Value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rv.Key, err))
+			continue
+		}
+		results[i].Value = value
+		results[i].Source.References = refs
+		// A value that embeds a sensitive key's value is sensitive too
+		for _, ref := range refs {
+			if sensitiveVars[ref] {
+				results[i].Sensitive = true
+			}
+		}
+	}
+	return errs
+}
+
+// isLiteral reports whether a resolved value must not be interpolated
+func isLiteral(configKey schema.ConfigKey, rv ResolvedValue) bool {
+	if rv.Source.Encrypted {
+		return true
+	}
+	switch rv.Source.Kind {
+	case SourceFile, SourceConfigDir, SourceVault, SourceHelper:
+		return true
+	}
+	return configKey.NoInterpolate
 }
 
 // parseEnviron converts an environ slice (["KEY=VALUE", ...]) into a map.
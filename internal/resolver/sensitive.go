@@ -0,0 +1,95 @@
+package resolver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"admit/internal/schema"
+)
+
+// RedactedValue is shown in place of a sensitive value in reports and explanations
+const RedactedValue = "[sensitive]"
+
+// sensitiveKinds are the sources whose values are sensitive unless the schema says otherwise
+var sensitiveKinds = map[SourceKind]bool{
+	SourceFile:   true,
+	SourceVault:  true,
+	SourceHelper: true,
+}
+
+// isSensitive reports whether a resolved value must be kept out of output:
+// always for decrypted values, else the key's "sensitive" setting when present,
+// otherwise the source's default
+func isSensitive(configKey schema.ConfigKey, source Provenance) bool {
+	if source.Encrypted {
+		return true
+	}
+	if configKey.Sensitive != nil {
+		return *configKey.Sensitive
+	}
+	return sensitiveKinds[source.Kind]
+}
+
+// DisplayValue returns the value for display, or RedactedValue if it is sensitive
+func (rv ResolvedValue) DisplayValue() string {
+	if rv.Sensitive {
+		return RedactedValue
+	}
+	return rv.Value
+}
+
+// SensitiveKeys returns the set of config paths whose values are sensitive
+func SensitiveKeys(resolved []ResolvedValue) map[string]bool {
+	keys := make(map[string]bool)
+	for _, rv := range resolved {
+		if rv.Sensitive {
+			keys[rv.Key] = true
+		}
+	}
+	return keys
+}
+
+// maskKeyLabel separates the mask key derived from a key file from the key's use for encryption
+const maskKeyLabel = "admit mask v1"
+
+// MaskKey returns the key MaskValue uses, derived from the key file named by
+// ADMIT_KEY_FILE in environ, or nil if it is not set
+func MaskKey(environ []string) ([]byte, error) {
+	keyFile := parseEnviron(environ)[KeyFileEnv]
+	if keyFile == "" {
+		return nil, nil
+	}
+	key, err := LoadKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(maskKeyLabel))
+	return mac.Sum(nil), nil
+}
+
+// MaskValue replaces a sensitive value for stored records. With a key it is a
+// keyed digest, so records can still be compared for changes but the digest
+// cannot be checked against guessed values without the key; with no key only
+// the value's presence is recorded, as RedactedValue
+func MaskValue(key []byte, value string) string {
+	if key == nil {
+		return RedactedValue
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// MaskValues returns a copy of values with the sensitive keys masked
+func MaskValues(values map[string]string, sensitive map[string]bool, key []byte) map[string]string {
+	masked := make(map[string]string, len(values))
+	for k, value := range values {
+		if sensitive[k] {
+			value = MaskValue(key, value)
+		}
+		masked[k] = value
+	}
+	return masked
+}
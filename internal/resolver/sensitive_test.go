@@ -0,0 +1,66 @@
+package resolver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestMaskValue(t *testing.T) {
+	// Without a key only presence is recorded
+	if got := MaskValue(nil, "s3cret"); got != RedactedValue {
+		t.Errorf("MaskValue(nil) = %q, want %q", got, RedactedValue)
+	}
+
+	keyFile, _ := writeKey(t)
+	key, err := MaskKey([]string{KeyFileEnv + "=" + keyFile})
+	if err != nil {
+		t.Fatalf("MaskKey() error: %v", err)
+	}
+	masked := MaskValue(key, "s3cret")
+	if !strings.HasPrefix(masked, "hmac-sha256:") || masked != MaskValue(key, "s3cret") {
+		t.Errorf("MaskValue() = %q, want a stable hmac-sha256: digest", masked)
+	}
+	if MaskValue(key, "other") == masked {
+		t.Error("MaskValue() gave different values the same digest")
+	}
+	plain := sha256.Sum256([]byte("s3cret"))
+	if strings.Contains(masked, hex.EncodeToString(plain[:])) {
+		t.Error("MaskValue() holds the unkeyed digest of the value")
+	}
+
+	otherFile, _ := writeKey(t)
+	otherKey, _ := MaskKey([]string{KeyFileEnv + "=" + otherFile})
+	if MaskValue(otherKey, "s3cret") == masked {
+		t.Error("MaskValue() gave the same digest under different keys")
+	}
+}
+
+func TestMaskKey(t *testing.T) {
+	if key, err := MaskKey(nil); key != nil || err != nil {
+		t.Errorf("MaskKey() without a key file = %v, %v, want nil, nil", key, err)
+	}
+
+	// The mask key is derived, not the encryption key itself
+	keyFile, encKey := writeKey(t)
+	key, err := MaskKey([]string{KeyFileEnv + "=" + keyFile})
+	if err != nil || len(key) != sha256.Size || string(key) == string(encKey) {
+		t.Errorf("MaskKey() = %x, %v, want a key derived from %x", key, err, encKey)
+	}
+
+	if _, err := MaskKey([]string{KeyFileEnv + "=/nonexistent/admit.key"}); err == nil {
+		t.Error("MaskKey() with a missing key file succeeded")
+	}
+}
+
+func TestMaskValues(t *testing.T) {
+	values := map[string]string{"db.url": "postgres://db", "db.password": "s3cret"}
+	masked := MaskValues(values, map[string]bool{"db.password": true}, nil)
+	if masked["db.url"] != "postgres://db" || masked["db.password"] != RedactedValue {
+		t.Errorf("MaskValues() = %v", masked)
+	}
+	if values["db.password"] != "s3cret" {
+		t.Error("MaskValues() modified its input")
+	}
+}
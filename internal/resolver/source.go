@@ -0,0 +1,339 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"admit/internal/codes"
+	"admit/internal/schema"
+)
+
+// Source names, as used in admit.yaml "sources:" and --sources
+const (
+	SourceNameEnv         = "env"
+	SourceNameEnvFile     = "env-file"
+	SourceNameSecretFiles = "secret-files"
+	SourceNameConfigDir   = "config-dir"
+	SourceNameConfigFile  = "config-file"
+	SourceNameVault       = "vault"
+	SourceNameHelper      = "helper"
+)
+
+// sourceNames lists the known source names, in default precedence order
+var sourceNames = []string{
+	SourceNameEnv,
+	SourceNameEnvFile,
+	SourceNameSecretFiles,
+	SourceNameConfigDir,
+	SourceNameConfigFile,
+	SourceNameVault,
+	SourceNameHelper,
+}
+
+// Source supplies config values from one place (environment, files, directories, ...).
+// Sources are consulted in chain order; the first one that finds a value wins.
+type Source interface {
+	// Name returns the source name used in the chain and in error messages
+	Name() string
+
+	// Lookup finds the value for a config key. name is the variable being
+	// consulted (the key's env var or one of its aliases) and path is the key's
+	// schema path; each source matches on whichever it is keyed by.
+	Lookup(path, name string) (string, Provenance, bool, error)
+}
+
+// SourceError reports a value a source could not read, with the code for the kind of failure
+type SourceError struct {
+	Code codes.Code
+	Err  error
+}
+
+func (e *SourceError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the error code for a single resolution error, or "" if it has none
+func ErrorCode(err error) codes.Code {
+	var sourceErr *SourceError
+	var vaultErr *VaultError
+	switch {
+	case errors.As(err, &sourceErr):
+		return sourceErr.Code
+	case errors.As(err, &vaultErr):
+		return codes.VaultFailed
+	}
+	return ""
+}
+
+// DefaultSourceOrder returns the source chain used when none is configured:
+// the environment and env files (env files first with EnvFileOverride), then
+// secret files when FileSecrets is set, config directories, config files, and
+// Vault and the helper when they are configured.
+func DefaultSourceOrder(opts Options) []string {
+	order := []string{SourceNameEnv, SourceNameEnvFile}
+	if opts.EnvFileOverride {
+		order = []string{SourceNameEnvFile, SourceNameEnv}
+	}
+	if opts.FileSecrets {
+		order = append(order, SourceNameSecretFiles)
+	}
+	order = append(order, SourceNameConfigDir, SourceNameConfigFile)
+	if opts.Vault.Address != "" {
+		order = append(order, SourceNameVault)
+	}
+	if opts.Helper.Command != "" {
+		order = append(order, SourceNameHelper)
+	}
+	return order
+}
+
+// BuildSources creates the source chain for opts.Order (or the default order).
+// Each source reads the data loaded into opts for it; Vault reads the vault
+// stanzas of s and the helper is asked for its keys. Unknown or duplicate names
+// are rejected, as is loaded data for a source missing from the chain.
+func BuildSources(s schema.Schema, environ []string, opts Options) ([]Source, error) {
+	order := opts.Order
+	if len(order) == 0 {
+		order = DefaultSourceOrder(opts)
+	} else if opts.EnvFileOverride {
+		return nil, fmt.Errorf("env file override cannot be combined with an explicit source order; list '%s' before '%s' instead", SourceNameEnvFile, SourceNameEnv)
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range order {
+		if !containsName(sourceNames, name) {
+			return nil, fmt.Errorf("unknown source '%s' (known sources: %s)", name, strings.Join(sourceNames, ", "))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate source '%s'", name)
+		}
+		seen[name] = true
+	}
+
+	unused := []struct {
+		name   string
+		loaded bool
+	}{
+		{SourceNameEnvFile, len(opts.EnvFile) > 0},
+		{SourceNameSecretFiles, opts.FileSecrets},
+		{SourceNameConfigDir, len(opts.ConfigDir) > 0},
+		{SourceNameConfigFile, len(opts.ConfigFile) > 0},
+		{SourceNameVault, opts.Vault.Address != ""},
+		{SourceNameHelper, opts.Helper.Command != ""},
+	}
+	for _, u := range unused {
+		if u.loaded && !seen[u.name] {
+			return nil, fmt.Errorf("source '%s' is configured but not in the source order", u.name)
+		}
+	}
+
+	if seen[SourceNameVault] && opts.Vault.Address == "" {
+		return nil, fmt.Errorf("source '%s' requires a Vault address", SourceNameVault)
+	}
+	if seen[SourceNameHelper] {
+		if opts.Helper.Command == "" {
+			return nil, fmt.Errorf("source '%s' requires a helper executable", SourceNameHelper)
+		}
+		if err := opts.Helper.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	vars := variableSources(environ, opts)
+	sources := make([]Source, 0, len(order))
+	for _, name := range order {
+		switch name {
+		case SourceNameEnv:
+			sources = append(sources, NewEnvSource(environ))
+		case SourceNameEnvFile:
+			sources = append(sources, NewEnvFileSource(opts.EnvFile))
+		case SourceNameSecretFiles:
+			sources = append(sources, NewSecretFileSource(vars, opts.MaxFileSize))
+		case SourceNameConfigDir:
+			sources = append(sources, NewConfigDirSource(opts.ConfigDir, opts.ConfigDirMax))
+		case SourceNameConfigFile:
+			sources = append(sources, NewConfigFileSource(opts.ConfigFile))
+		case SourceNameVault:
+			sources = append(sources, NewVaultSource(opts.Vault, s, vars, environ))
+		case SourceNameHelper:
+			sources = append(sources, NewHelperSource(opts.Helper, schemaPaths(s), environ))
+		}
+	}
+	return sources, nil
+}
+
+// variableSources returns the environment and env file sources, in precedence order.
+// They answer plain variable lookups: <VAR>_FILE names and interpolation references.
+func variableSources(environ []string, opts Options) []Source {
+	env := NewEnvSource(environ)
+	envFile := NewEnvFileSource(opts.EnvFile)
+	if opts.EnvFileWins() {
+		return []Source{envFile, env}
+	}
+	return []Source{env, envFile}
+}
+
+// lookupChain consults sources in order and returns the first value found
+func lookupChain(sources []Source, path, name string) (string, Provenance, bool, error) {
+	for _, src := range sources {
+		value, source, found, err := src.Lookup(path, name)
+		if err != nil || found {
+			return value, source, found, err
+		}
+	}
+	return "", Provenance{}, false, nil
+}
+
+// schemaPaths returns the config key paths of a schema
+func schemaPaths(s schema.Schema) []string {
+	paths := make([]string, 0, len(s.Config))
+	for _, configKey := range s.Config {
+		paths = append(paths, configKey.Path)
+	}
+	return paths
+}
+
+// containsName checks if a name is in the list
+func containsName(list []string, name string) bool {
+	for _, v := range list {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// envSource looks up variables in the process environment
+type envSource struct {
+	env map[string]string
+}
+
+// NewEnvSource creates a source over an environ slice ("KEY=VALUE")
+func NewEnvSource(environ []string) Source {
+	return envSource{env: parseEnviron(environ)}
+}
+
+func (s envSource) Name() string { return SourceNameEnv }
+
+func (s envSource) Lookup(path, name string) (string, Provenance, bool, error) {
+	value, ok := s.env[name]
+	if !ok {
+		return "", Provenance{}, false, nil
+	}
+	return value, Provenance{Kind: SourceEnv, Var: name}, true, nil
+}
+
+// envFileSource looks up variables in loaded env file entries
+type envFileSource struct {
+	entries map[string]DotenvEntry
+}
+
+// NewEnvFileSource creates a source over env file entries; later entries win
+func NewEnvFileSource(entries []DotenvEntry) Source {
+	byKey := make(map[string]DotenvEntry)
+	for _, e := range entries {
+		byKey[e.Key] = e
+	}
+	return envFileSource{entries: byKey}
+}
+
+func (s envFileSource) Name() string { return SourceNameEnvFile }
+
+func (s envFileSource) Lookup(path, name string) (string, Provenance, bool, error) {
+	entry, ok := s.entries[name]
+	if !ok {
+		return "", Provenance{}, false, nil
+	}
+	return entry.Value, Provenance{Kind: SourceEnvFile, Var: name, Path: entry.File, Line: entry.Line, Encrypted: entry.Encrypted}, true, nil
+}
+
+// secretFileSource reads the file named by <VAR>_FILE (Docker/Kubernetes secret convention)
+type secretFileSource struct {
+	vars    []Source
+	maxSize int64
+}
+
+// NewSecretFileSource creates a source that finds <VAR>_FILE through vars and reads the file
+func NewSecretFileSource(vars []Source, maxSize int64) Source {
+	return secretFileSource{vars: vars, maxSize: maxSize}
+}
+
+func (s secretFileSource) Name() string { return SourceNameSecretFiles }
+
+func (s secretFileSource) Lookup(path, name string) (string, Provenance, bool, error) {
+	fileVar := name + FileSuffix
+	filePath, _, ok, err := lookupChain(s.vars, "", fileVar)
+	if err != nil || !ok {
+		return "", Provenance{}, false, err
+	}
+	content, err := ReadSecretFile(filePath, s.maxSize)
+	if err != nil {
+		return "", Provenance{}, false, &SourceError{Code: codes.SecretFileFailed, Err: fmt.Errorf("%s: %w", fileVar, err)}
+	}
+	return content, Provenance{Kind: SourceFile, Var: fileVar, Path: filePath}, true, nil
+}
+
+// configDirSource reads key files from mounted config directories
+type configDirSource struct {
+	entries map[string]ConfigDirEntry
+	maxSize int64
+}
+
+// NewConfigDirSource creates a source over config dir entries; later entries win
+func NewConfigDirSource(entries []ConfigDirEntry, maxSize int64) Source {
+	byName := make(map[string]ConfigDirEntry)
+	for _, e := range entries {
+		byName[e.Name] = e
+	}
+	return configDirSource{entries: byName, maxSize: maxSize}
+}
+
+func (s configDirSource) Name() string { return SourceNameConfigDir }
+
+// Lookup prefers a file named after the schema path over one named after the variable
+func (s configDirSource) Lookup(path, name string) (string, Provenance, bool, error) {
+	entry, ok := s.entries[path]
+	if !ok {
+		entry, ok = s.entries[name]
+	}
+	if !ok {
+		return "", Provenance{}, false, nil
+	}
+	content, err := ReadSecretFile(entry.Path, s.maxSize)
+	if err != nil {
+		return "", Provenance{}, false, &SourceError{Code: codes.SecretFileFailed, Err: fmt.Errorf("%s: %w", path, err)}
+	}
+	return content, Provenance{Kind: SourceConfigDir, Var: entry.Name, Path: entry.Path}, true, nil
+}
+
+// configFileSource looks up schema paths in flattened config file entries
+type configFileSource struct {
+	entries map[string]ConfigFileEntry
+}
+
+// NewConfigFileSource creates a source over config file entries; later entries win
+func NewConfigFileSource(entries []ConfigFileEntry) Source {
+	byPath := make(map[string]ConfigFileEntry)
+	for _, e := range entries {
+		byPath[e.Path] = e
+	}
+	return configFileSource{entries: byPath}
+}
+
+func (s configFileSource) Name() string { return SourceNameConfigFile }
+
+func (s configFileSource) Lookup(path, name string) (string, Provenance, bool, error) {
+	entry, ok := s.entries[path]
+	if !ok {
+		return "", Provenance{}, false, nil
+	}
+	if entry.NonScalar != "" {
+		return "", Provenance{}, false, &SourceError{Code: codes.ValueNotScalar, Err: fmt.Errorf("%s: %s:%d: expected a scalar value, got a %s", path, entry.File, entry.Line, entry.NonScalar)}
+	}
+	return entry.Value, Provenance{Kind: SourceConfigFile, Var: entry.Path, Path: entry.File, Line: entry.Line}, true, nil
+}
@@ -0,0 +1,275 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"admit/internal/codes"
+	"admit/internal/schema"
+)
+
+// mapSource is a test Source backed by a map of variable names
+type mapSource struct {
+	name   string
+	values map[string]string
+}
+
+func (s mapSource) Name() string { return s.name }
+
+func (s mapSource) Lookup(path, name string) (string, Provenance, bool, error) {
+	value, ok := s.values[name]
+	if !ok {
+		return "", Provenance{}, false, nil
+	}
+	return value, Provenance{Kind: SourceKind(s.name), Var: name}, true, nil
+}
+
+func sourceNamesOf(sources []Source) []string {
+	names := make([]string, len(sources))
+	for i, src := range sources {
+		names[i] = src.Name()
+	}
+	return names
+}
+
+func TestBuildSources_Order(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "default",
+			opts: Options{},
+			want: []string{"env", "env-file", "config-dir", "config-file"},
+		},
+		{
+			name: "default with override and secret files",
+			opts: Options{EnvFileOverride: true, FileSecrets: true},
+			want: []string{"env-file", "env", "secret-files", "config-dir", "config-file"},
+		},
+		{
+			name: "default with vault and helper",
+			opts: Options{Vault: VaultConfig{Address: "http://vault:8200"}, Helper: HelperConfig{Command: "fetch"}},
+			want: []string{"env", "env-file", "config-dir", "config-file", "vault", "helper"},
+		},
+		{
+			name: "explicit order",
+			opts: Options{Order: []string{"config-file", "env"}, ConfigFile: []ConfigFileEntry{{Path: "a"}}},
+			want: []string{"config-file", "env"},
+		},
+		{
+			name: "explicit order enables secret files",
+			opts: Options{Order: []string{"secret-files", "env"}},
+			want: []string{"secret-files", "env"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources, err := BuildSources(schema.Schema{}, nil, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := sourceNamesOf(sources); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildSources_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{
+			name:    "unknown source",
+			opts:    Options{Order: []string{"env", "consul"}},
+			wantErr: "unknown source 'consul' (known sources: env, env-file, secret-files, config-dir, config-file, vault, helper)",
+		},
+		{
+			name:    "duplicate source",
+			opts:    Options{Order: []string{"env", "env"}},
+			wantErr: "duplicate source 'env'",
+		},
+		{
+			name:    "env file loaded but not in order",
+			opts:    Options{Order: []string{"env"}, EnvFile: []DotenvEntry{{Key: "A"}}},
+			wantErr: "source 'env-file' is configured but not in the source order",
+		},
+		{
+			name:    "secret files requested but not in order",
+			opts:    Options{Order: []string{"env"}, FileSecrets: true},
+			wantErr: "source 'secret-files' is configured but not in the source order",
+		},
+		{
+			name:    "vault in order without address",
+			opts:    Options{Order: []string{"vault", "env"}},
+			wantErr: "source 'vault' requires a Vault address",
+		},
+		{
+			name:    "vault configured but not in order",
+			opts:    Options{Order: []string{"env"}, Vault: VaultConfig{Address: "http://vault:8200"}},
+			wantErr: "source 'vault' is configured but not in the source order",
+		},
+		{
+			name:    "helper in order without executable",
+			opts:    Options{Order: []string{"env", "helper"}},
+			wantErr: "source 'helper' requires a helper executable",
+		},
+		{
+			name:    "helper with unknown exit policy",
+			opts:    Options{Helper: HelperConfig{Command: "fetch", OnError: "retry"}},
+			wantErr: "helper on_error must be 'fail' or 'ignore', got 'retry'",
+		},
+		{
+			name:    "override with explicit order",
+			opts:    Options{Order: []string{"env", "env-file"}, EnvFileOverride: true},
+			wantErr: "env file override cannot be combined with an explicit source order",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BuildSources(schema.Schema{}, nil, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOptions_EnvFileWins(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want bool
+	}{
+		{opts: Options{}, want: false},
+		{opts: Options{EnvFileOverride: true}, want: true},
+		{opts: Options{Order: []string{"env", "env-file"}}, want: false},
+		{opts: Options{Order: []string{"config-file", "env-file", "env"}}, want: true},
+		{opts: Options{Order: []string{"env-file"}}, want: true},
+		{opts: Options{Order: []string{"config-file"}}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.opts.EnvFileWins(); got != tt.want {
+			t.Errorf("%+v: EnvFileWins() = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestOptions_Offline(t *testing.T) {
+	opts := Options{
+		FileSecrets: true,
+		Vault:       VaultConfig{Address: "http://127.0.0.1:8200"},
+		Helper:      HelperConfig{Command: "/usr/local/bin/fetch"},
+		Order:       []string{"helper", "env", "vault", "secret-files"},
+	}
+
+	offline := opts.Offline()
+	if offline.Vault.Address != "" || offline.Helper.Command != "" {
+		t.Errorf("Offline() kept live sources: %+v", offline)
+	}
+	if got := strings.Join(offline.Order, ","); got != "env,secret-files" {
+		t.Errorf("Offline().Order = %s, want env,secret-files", got)
+	}
+	if !offline.FileSecrets {
+		t.Error("Offline() dropped FileSecrets")
+	}
+	if opts.Order[0] != "helper" || opts.Vault.Address == "" {
+		t.Error("Offline() modified the receiver")
+	}
+	if _, err := BuildSources(schema.Schema{}, nil, offline); err != nil {
+		t.Errorf("BuildSources(Offline()) error: %v", err)
+	}
+	if got := (Options{}).Offline().Order; got != nil {
+		t.Errorf("Offline() of the default order = %v, want nil", got)
+	}
+}
+
+func TestResolveWithOptions_SourceOrder(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url": {Path: "db.url", Type: schema.TypeString},
+		},
+	}
+	environ := []string{"DB_URL=from-env"}
+	configFile := []ConfigFileEntry{{Path: "db.url", Value: "from-file", File: "config.yaml", Line: 1}}
+
+	// Default order: env wins
+	rv := resolveByKey(t, s, environ, Options{ConfigFile: configFile})["db.url"]
+	if rv.Value != "from-env" {
+		t.Errorf("default order: value = %q, want from-env", rv.Value)
+	}
+
+	// Explicit order: config file wins
+	rv = resolveByKey(t, s, environ, Options{ConfigFile: configFile, Order: []string{"config-file", "env"}})["db.url"]
+	if rv.Value != "from-file" || rv.Source.Kind != SourceConfigFile {
+		t.Errorf("explicit order: value = %q from %s, want from-file", rv.Value, rv.Source.Kind)
+	}
+
+	// Sources missing from the order are not consulted
+	rv = resolveByKey(t, s, environ, Options{Order: []string{"config-file"}})["db.url"]
+	if rv.Present {
+		t.Errorf("env not in order: value = %q, want not present", rv.Value)
+	}
+}
+
+func TestResolveWithOptions_CustomSources(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url":        {Path: "db.url", Type: schema.TypeString},
+			"payments.mode": {Path: "payments.mode", Type: schema.TypeString, Aliases: []string{"PAY_MODE"}},
+			"log.level":     {Path: "log.level", Type: schema.TypeString, Default: strPtr("info")},
+		},
+	}
+	opts := Options{
+		Sources: []Source{
+			mapSource{name: "primary", values: map[string]string{"DB_URL": "primary-url"}},
+			mapSource{name: "fallback", values: map[string]string{"DB_URL": "fallback-url", "PAY_MODE": "test"}},
+		},
+	}
+
+	// The environment is ignored when an explicit chain is given
+	byKey := resolveByKey(t, s, []string{"DB_URL=env", "LOG_LEVEL=debug"}, opts)
+
+	if rv := byKey["db.url"]; rv.Value != "primary-url" || rv.Source.Kind != "primary" {
+		t.Errorf("db.url = %q from %s, want primary-url from primary", rv.Value, rv.Source.Kind)
+	}
+	if rv := byKey["payments.mode"]; rv.Value != "test" || rv.Source.Alias != "PAY_MODE" {
+		t.Errorf("payments.mode = %q from %+v, want alias lookup through the chain", rv.Value, rv.Source)
+	}
+	if rv := byKey["log.level"]; rv.Value != "info" || rv.Source.Kind != SourceDefault {
+		t.Errorf("log.level = %q from %s, want schema default", rv.Value, rv.Source.Kind)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"source error", &SourceError{Code: codes.SecretFileFailed, Err: errors.New("DB_PASSWORD_FILE: missing")}, codes.SecretFileFailed},
+		{"wrapped source error", fmt.Errorf("db.password: %w", &SourceError{Code: codes.HelperFailed, Err: errors.New("boom")}), codes.HelperFailed},
+		{"vault error", &VaultError{Path: "secret/data/app", Err: errors.New("server returned 500")}, codes.VaultFailed},
+		{"other error", errors.New("db.url: undefined variable"), ""},
+	}
+	for _, tt := range tests {
+		if got := ErrorCode(tt.err); got != tt.want {
+			t.Errorf("%s: ErrorCode() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// The source error's message is that of the error it wraps
+	err := &SourceError{Code: codes.ValueNotScalar, Err: errors.New("db.url: expected a scalar value")}
+	if err.Error() != "db.url: expected a scalar value" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
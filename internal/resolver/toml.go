@@ -0,0 +1,160 @@
+package resolver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// flattenTOML flattens a TOML document. The document is decoded in full so
+// that anything go-toml rejects (syntax, redefined tables or keys) is an
+// error; the flattening walks go-toml's parse tree, which carries the
+// position of each key:
+//   - [table] headers, dotted keys and inline tables are flattened alike
+//   - strings are unescaped; integers and floats keep their text form without
+//     '_' separators; booleans and dates keep their text form
+//   - arrays and [[arrays of tables]] are recorded as non-scalar entries, and
+//     the keys of an array of tables' elements are not recorded
+func flattenTOML(content []byte, f *flattener) error {
+	var doc map[string]interface{}
+	decodeErr := toml.Unmarshal(content, &doc)
+	var positioned *toml.DecodeError
+	if errors.As(decodeErr, &positioned) {
+		line, _ := positioned.Position()
+		return fmt.Errorf("line %d: %s", line, strings.TrimPrefix(positioned.Error(), "toml: "))
+	}
+
+	w := &tomlWalker{out: f, arrays: make(map[string]bool)}
+	w.parser.Reset(content)
+	var walkErr error
+	for w.parser.NextExpression() {
+		node := w.parser.Expression()
+		w.starts = append(w.starts, w.start(node))
+		if walkErr == nil {
+			walkErr = w.expression(node)
+		}
+	}
+	if err := w.parser.Error(); err != nil {
+		return err
+	}
+
+	// go-toml reports redefined tables and keys without a position
+	if decodeErr != nil {
+		return fmt.Errorf("line %d: %s", w.failingLine(content), strings.TrimPrefix(decodeErr.Error(), "toml: "))
+	}
+	return walkErr
+}
+
+// tomlWalker flattens the top-level expressions of a TOML parse tree
+type tomlWalker struct {
+	parser  unstable.Parser
+	out     *flattener
+	table   string          // Current table prefix
+	inArray bool            // Inside an element of an array of tables; its keys are not recorded
+	arrays  map[string]bool // Array-of-tables paths already recorded
+	starts  []int           // Offset of the line each expression starts on
+}
+
+// expression handles a table header or a key/value pair
+func (w *tomlWalker) expression(node *unstable.Node) error {
+	switch node.Kind {
+	case unstable.Table, unstable.ArrayTable:
+		path, line := w.key(node.Key())
+		w.table = path
+		w.inArray = w.underArray(path)
+		if node.Kind != unstable.ArrayTable || w.inArray {
+			return nil
+		}
+		w.inArray = true
+		w.arrays[path] = true
+		return w.out.add(ConfigFileEntry{Path: path, Line: line, NonScalar: "list"})
+	case unstable.KeyValue:
+		if w.inArray {
+			return nil
+		}
+		path, line := w.key(node.Key())
+		return w.value(joinPath(w.table, path), line, node.Value())
+	}
+	return nil
+}
+
+// key joins the segments of a dotted key and returns the line it starts on
+func (w *tomlWalker) key(it unstable.Iterator) (string, int) {
+	var segments []string
+	line := 0
+	for it.Next() {
+		if line == 0 {
+			line = w.parser.Shape(it.Node().Raw).Start.Line
+		}
+		segments = append(segments, string(it.Node().Data))
+	}
+	return strings.Join(segments, "."), line
+}
+
+// value records a value under path; inline tables are flattened
+func (w *tomlWalker) value(path string, line int, node *unstable.Node) error {
+	switch node.Kind {
+	case unstable.InlineTable:
+		children := node.Children()
+		for children.Next() {
+			kv := children.Node()
+			key, _ := w.key(kv.Key())
+			if err := w.value(joinPath(path, key), line, kv.Value()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case unstable.Array:
+		return w.out.add(ConfigFileEntry{Path: path, Line: line, NonScalar: "list"})
+	case unstable.Integer, unstable.Float:
+		return w.out.add(ConfigFileEntry{Path: path, Value: strings.ReplaceAll(string(node.Data), "_", ""), Line: line})
+	default:
+		return w.out.add(ConfigFileEntry{Path: path, Value: string(node.Data), Line: line})
+	}
+}
+
+// underArray reports whether path is, or is inside, an array of tables
+func (w *tomlWalker) underArray(path string) bool {
+	for prefix := path; ; {
+		if w.arrays[prefix] {
+			return true
+		}
+		i := strings.LastIndex(prefix, ".")
+		if i < 0 {
+			return false
+		}
+		prefix = prefix[:i]
+	}
+}
+
+// start returns the offset of the line a top-level expression starts on
+func (w *tomlWalker) start(node *unstable.Node) int {
+	it := node.Key()
+	if !it.Next() {
+		return 0
+	}
+	offset := int(it.Node().Raw.Offset)
+	return bytes.LastIndexByte(w.parser.Data()[:offset], '\n') + 1
+}
+
+// failingLine finds the first expression of content that go-toml rejects:
+// the one ending the shortest prefix of whole expressions that fails to decode
+func (w *tomlWalker) failingLine(content []byte) int {
+	i := sort.Search(len(w.starts), func(i int) bool {
+		end := len(content)
+		if i+1 < len(w.starts) {
+			end = w.starts[i+1]
+		}
+		var doc map[string]interface{}
+		return toml.Unmarshal(content[:end], &doc) != nil
+	})
+	if i == len(w.starts) {
+		return 1
+	}
+	return bytes.Count(content[:w.starts[i]], []byte("\n")) + 1
+}
@@ -0,0 +1,303 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"admit/internal/codes"
+	"admit/internal/schema"
+)
+
+// Vault defaults
+const (
+	DefaultVaultMount     = "secret"               // KV v2 mount used when a key's vault stanza has none
+	DefaultVaultTimeout   = 5 * time.Second        // Per-request time limit
+	DefaultVaultRetries   = 2                      // Retries after a failed request
+	DefaultVaultRetryWait = 200 * time.Millisecond // Wait before the first retry; doubles each time
+)
+
+// VaultConfig configures the Vault KV v2 source
+type VaultConfig struct {
+	Address   string        // Vault server URL (the source is enabled when set)
+	TokenFile string        // Token file used when VAULT_TOKEN is unset (default ~/.vault-token)
+	Timeout   time.Duration // Per-request time limit (0 uses DefaultVaultTimeout)
+	Retries   int           // Retries after a transport error, 429 or 5xx response
+	RetryWait time.Duration // Wait before the first retry (0 uses DefaultVaultRetryWait)
+	Client    *http.Client  // HTTP client (nil uses vaultClient, which does not follow redirects)
+}
+
+// vaultClient is the default Vault HTTP client. It does not follow redirects:
+// the token travels in X-Vault-Token, which Go forwards to any redirect target.
+var vaultClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// VaultError reports a Vault secret that could not be fetched.
+// Unlike other resolution errors it has its own exit code.
+type VaultError struct {
+	Path string // The KV v2 API path (e.g., "secret/data/myapp/db")
+	Err  error
+}
+
+func (e *VaultError) Error() string {
+	return fmt.Sprintf("vault %s: %v", e.Path, e.Err)
+}
+
+func (e *VaultError) Unwrap() error {
+	return e.Err
+}
+
+// UsesVault reports whether any config key in the schema has a vault stanza
+func UsesVault(s schema.Schema) bool {
+	for _, configKey := range s.Config {
+		if configKey.Vault != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// vaultSource reads the keys that have a vault stanza from KV v2 secrets.
+// Each secret is fetched at most once.
+type vaultSource struct {
+	config  VaultConfig
+	refs    map[string]schema.VaultRef
+	vars    []Source
+	environ map[string]string
+
+	token  string
+	secret map[string]vaultResult
+}
+
+// vaultResult is the cached outcome of fetching one secret
+type vaultResult struct {
+	data map[string]interface{}
+	err  error
+}
+
+// NewVaultSource creates a source for the vault stanzas of s. VAULT_TOKEN is
+// looked up through vars; environ supplies VAULT_NAMESPACE and HOME.
+func NewVaultSource(config VaultConfig, s schema.Schema, vars []Source, environ []string) Source {
+	refs := make(map[string]schema.VaultRef)
+	for _, configKey := range s.Config {
+		if configKey.Vault != nil {
+			refs[configKey.Path] = *configKey.Vault
+		}
+	}
+	return &vaultSource{
+		config:  config,
+		refs:    refs,
+		vars:    vars,
+		environ: parseEnviron(environ),
+		secret:  make(map[string]vaultResult),
+	}
+}
+
+func (s *vaultSource) Name() string { return SourceNameVault }
+
+// Lookup matches on the schema path. A field missing from the secret is not
+// found; a secret that cannot be fetched is a *VaultError.
+func (s *vaultSource) Lookup(path, name string) (string, Provenance, bool, error) {
+	ref, ok := s.refs[path]
+	if !ok {
+		return "", Provenance{}, false, nil
+	}
+
+	mount := ref.Mount
+	if mount == "" {
+		mount = DefaultVaultMount
+	}
+	apiPath := mount + "/data/" + strings.Trim(ref.Path, "/")
+
+	data, err := s.fetch(apiPath)
+	if err != nil {
+		return "", Provenance{}, false, err
+	}
+
+	raw, ok := data[ref.Field]
+	if !ok || raw == nil {
+		return "", Provenance{}, false, nil
+	}
+	var value string
+	switch v := raw.(type) {
+	case string:
+		value = v
+	case json.Number:
+		value = v.String()
+	case bool:
+		value = fmt.Sprintf("%t", v)
+	default:
+		return "", Provenance{}, false, &SourceError{Code: codes.ValueNotScalar, Err: fmt.Errorf("%s: vault %s: field '%s' is not a scalar value", path, apiPath, ref.Field)}
+	}
+	return value, Provenance{Kind: SourceVault, Var: ref.Field, Path: apiPath}, true, nil
+}
+
+// fetch returns the data of a secret, reading it on first use
+func (s *vaultSource) fetch(apiPath string) (map[string]interface{}, error) {
+	if result, ok := s.secret[apiPath]; ok {
+		return result.data, result.err
+	}
+
+	data, err := s.read(apiPath)
+	if err != nil {
+		err = &VaultError{Path: apiPath, Err: err}
+	}
+	s.secret[apiPath] = vaultResult{data: data, err: err}
+	return data, err
+}
+
+// read requests a secret, retrying transport errors, 429 and 5xx responses
+// with exponential backoff
+func (s *vaultSource) read(apiPath string) (map[string]interface{}, error) {
+	token, err := s.resolveToken()
+	if err != nil {
+		return nil, err
+	}
+
+	wait := s.config.RetryWait
+	if wait <= 0 {
+		wait = DefaultVaultRetryWait
+	}
+	url := strings.TrimRight(s.config.Address, "/") + "/v1/" + apiPath
+
+	for attempt := 0; ; attempt++ {
+		data, retry, err := s.get(url, token)
+		if err == nil {
+			return data, nil
+		}
+		if !retry || attempt >= s.config.Retries {
+			if attempt > 0 {
+				return nil, fmt.Errorf("%w (after %d attempts)", err, attempt+1)
+			}
+			return nil, err
+		}
+		time.Sleep(wait << attempt)
+	}
+}
+
+// get performs one request. It reports whether a failure is worth retrying.
+func (s *vaultSource) get(url, token string) (map[string]interface{}, bool, error) {
+	timeout := s.config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultVaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := s.environ["VAULT_NAMESPACE"]; namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	client := s.config.Client
+	if client == nil {
+		client = vaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, true, fmt.Errorf("request timed out after %s", timeout)
+		}
+		return nil, true, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, true, fmt.Errorf("reading response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		data, err := parseVaultSecret(body)
+		return data, false, err
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, false, errors.New("secret not found")
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		return nil, false, fmt.Errorf("server returned %d redirect to %q (redirects are not followed; use the address it points to)", resp.StatusCode, resp.Header.Get("Location"))
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, true, fmt.Errorf("server returned %d%s", resp.StatusCode, vaultErrors(body))
+	default:
+		return nil, false, fmt.Errorf("server returned %d%s", resp.StatusCode, vaultErrors(body))
+	}
+}
+
+// resolveToken finds the Vault token: VAULT_TOKEN, then the configured token
+// file, then ~/.vault-token
+func (s *vaultSource) resolveToken() (string, error) {
+	if s.token != "" {
+		return s.token, nil
+	}
+
+	if token, _, ok, _ := lookupChain(s.vars, "", "VAULT_TOKEN"); ok && token != "" {
+		s.token = token
+		return token, nil
+	}
+
+	tokenFile := s.config.TokenFile
+	if tokenFile == "" {
+		home := s.environ["HOME"]
+		if home == "" {
+			return "", errors.New("no token: set VAULT_TOKEN or configure a token file")
+		}
+		tokenFile = filepath.Join(home, ".vault-token")
+		if _, err := os.Stat(tokenFile); os.IsNotExist(err) {
+			return "", errors.New("no token: set VAULT_TOKEN or configure a token file")
+		}
+	}
+
+	content, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("cannot read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", tokenFile)
+	}
+	s.token = token
+	return token, nil
+}
+
+// parseVaultSecret extracts the key/value data from a KV v2 read response
+func parseVaultSecret(body []byte) (map[string]interface{}, error) {
+	var resp struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&resp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if resp.Data.Data == nil {
+		return nil, errors.New("secret has no data (is the version deleted or is this a KV v1 mount?)")
+	}
+	return resp.Data.Data, nil
+}
+
+// vaultErrors formats the "errors" list of a Vault error response, if any
+func vaultErrors(body []byte) string {
+	var resp struct {
+		Errors []string `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || len(resp.Errors) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(resp.Errors, "; ")
+}
@@ -0,0 +1,241 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"admit/internal/schema"
+)
+
+// fakeVault serves KV v2 secrets and counts requests per path
+type fakeVault struct {
+	token    string
+	secrets  map[string]string // API path -> response body for the secret's data
+	failures int32             // Number of initial requests answered with 503
+	requests int32
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := atomic.AddInt32(&f.requests, 1)
+	if n <= atomic.LoadInt32(&f.failures) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"errors":["Vault is sealed"]}`)
+		return
+	}
+	if r.Header.Get("X-Vault-Token") != f.token {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"errors":["permission denied"]}`)
+		return
+	}
+	data, ok := f.secrets[strings.TrimPrefix(r.URL.Path, "/v1/")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errors":[]}`)
+		return
+	}
+	fmt.Fprintf(w, `{"data":{"data":%s,"metadata":{"version":3}}}`, data)
+}
+
+func vaultSchema() schema.Schema {
+	return schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.password": {Path: "db.password", Type: schema.TypeString, Vault: &schema.VaultRef{Path: "myapp/db", Field: "password"}},
+			"db.user":     {Path: "db.user", Type: schema.TypeString, Vault: &schema.VaultRef{Path: "myapp/db", Field: "user"}},
+			"db.pool":     {Path: "db.pool", Type: schema.TypeString, Vault: &schema.VaultRef{Path: "myapp/db", Field: "pool"}},
+			"db.replica":  {Path: "db.replica", Type: schema.TypeString, Vault: &schema.VaultRef{Path: "myapp/db", Field: "replica"}, Default: strPtr("none")},
+			"api.key":     {Path: "api.key", Type: schema.TypeString, Vault: &schema.VaultRef{Mount: "kv", Path: "/myapp/api/", Field: "key"}},
+			"log.level":   {Path: "log.level", Type: schema.TypeString},
+		},
+	}
+}
+
+func TestResolveWithOptions_Vault(t *testing.T) {
+	fake := &fakeVault{
+		token: "root-token",
+		secrets: map[string]string{
+			"secret/data/myapp/db": `{"password":"s3cret","user":"app","pool":10}`,
+			"kv/data/myapp/api":    `{"key":"k-123"}`,
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	opts := Options{Vault: VaultConfig{Address: server.URL + "/"}}
+	byKey := resolveByKey(t, vaultSchema(), []string{"VAULT_TOKEN=root-token", "LOG_LEVEL=info", "DB_USER=from-env"}, opts)
+
+	want := Provenance{Kind: SourceVault, Var: "password", Path: "secret/data/myapp/db"}
+	if rv := byKey["db.password"]; rv.Value != "s3cret" || !rv.Sensitive || !reflect.DeepEqual(rv.Source, want) {
+		t.Errorf("db.password = %+v, want sensitive value from vault", rv)
+	}
+	if rv := byKey["db.user"]; rv.Value != "from-env" || rv.Source.Kind != SourceEnv {
+		t.Errorf("db.user = %+v, want env to win over vault", rv)
+	}
+	if rv := byKey["db.pool"]; rv.Value != "10" {
+		t.Errorf("db.pool = %q, want numbers in their written form", rv.Value)
+	}
+	if rv := byKey["db.replica"]; rv.Value != "none" || rv.Source.Kind != SourceDefault {
+		t.Errorf("db.replica = %+v, want a missing field to fall back to the default", rv)
+	}
+	if rv := byKey["api.key"]; rv.Value != "k-123" || rv.Source.Path != "kv/data/myapp/api" {
+		t.Errorf("api.key = %+v, want value from the kv mount", rv)
+	}
+
+	// One request per secret, however many keys read it
+	if n := atomic.LoadInt32(&fake.requests); n != 2 {
+		t.Errorf("vault received %d requests, want 2", n)
+	}
+}
+
+func TestResolveWithOptions_VaultRetries(t *testing.T) {
+	fake := &fakeVault{
+		token:    "t",
+		secrets:  map[string]string{"secret/data/myapp/db": `{"password":"s3cret"}`},
+		failures: 2,
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	s := schema.Schema{Config: map[string]schema.ConfigKey{"db.password": vaultSchema().Config["db.password"]}}
+	opts := Options{Vault: VaultConfig{Address: server.URL, Retries: 2, RetryWait: time.Millisecond}}
+
+	byKey := resolveByKey(t, s, []string{"VAULT_TOKEN=t"}, opts)
+	if rv := byKey["db.password"]; rv.Value != "s3cret" {
+		t.Errorf("db.password = %q after retries, want s3cret", rv.Value)
+	}
+
+	// Out of retries: the last error is reported with the attempt count
+	atomic.StoreInt32(&fake.requests, 0)
+	atomic.StoreInt32(&fake.failures, 5)
+	_, err := ResolveWithOptions(s, []string{"VAULT_TOKEN=t"}, opts)
+	var vaultErr *VaultError
+	if !errors.As(err, &vaultErr) || !strings.Contains(err.Error(), "server returned 503: Vault is sealed (after 3 attempts)") {
+		t.Errorf("error = %v, want VaultError after 3 attempts", err)
+	}
+}
+
+func TestResolveWithOptions_VaultErrors(t *testing.T) {
+	fake := &fakeVault{token: "good", secrets: map[string]string{"secret/data/other": `{}`}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	s := schema.Schema{Config: map[string]schema.ConfigKey{"db.password": vaultSchema().Config["db.password"]}}
+
+	tests := []struct {
+		name    string
+		environ []string
+		vault   VaultConfig
+		wantErr string
+	}{
+		{name: "not found", environ: []string{"VAULT_TOKEN=good"}, vault: VaultConfig{Address: server.URL}, wantErr: "vault secret/data/myapp/db: secret not found"},
+		{name: "permission denied", environ: []string{"VAULT_TOKEN=bad"}, vault: VaultConfig{Address: server.URL}, wantErr: "server returned 403: permission denied"},
+		{name: "no token", vault: VaultConfig{Address: server.URL}, wantErr: "no token: set VAULT_TOKEN or configure a token file"},
+		{name: "timeout", environ: []string{"VAULT_TOKEN=good"}, vault: VaultConfig{Address: slow.URL, Timeout: 50 * time.Millisecond}, wantErr: "request timed out after 50ms"},
+		{name: "unreachable", environ: []string{"VAULT_TOKEN=good"}, vault: VaultConfig{Address: "http://127.0.0.1:1"}, wantErr: "request failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ResolveWithOptions(s, tt.environ, Options{Vault: tt.vault})
+			var vaultErr *VaultError
+			if !errors.As(err, &vaultErr) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want VaultError containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveWithOptions_VaultRedirect(t *testing.T) {
+	// The redirect target records any token it is sent
+	var leaked int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "" {
+			atomic.AddInt32(&leaked, 1)
+		}
+		fmt.Fprint(w, `{"data":{"data":{"password":"s3cret"}}}`)
+	}))
+	defer target.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL+r.URL.Path, http.StatusTemporaryRedirect)
+	}))
+	defer redirect.Close()
+
+	s := schema.Schema{Config: map[string]schema.ConfigKey{"db.password": vaultSchema().Config["db.password"]}}
+	_, err := ResolveWithOptions(s, []string{"VAULT_TOKEN=t"}, Options{Vault: VaultConfig{Address: redirect.URL}})
+	var vaultErr *VaultError
+	if !errors.As(err, &vaultErr) || !strings.Contains(err.Error(), "server returned 307 redirect to \""+target.URL) {
+		t.Errorf("error = %v, want VaultError for the redirect", err)
+	}
+	if n := atomic.LoadInt32(&leaked); n != 0 {
+		t.Errorf("redirect target received the token %d time(s)", n)
+	}
+}
+
+func TestResolveWithOptions_VaultTokenFile(t *testing.T) {
+	fake := &fakeVault{token: "file-token", secrets: map[string]string{"secret/data/myapp/db": `{"password":"s3cret"}`}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token\n"), 0600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+	s := schema.Schema{Config: map[string]schema.ConfigKey{"db.password": vaultSchema().Config["db.password"]}}
+
+	// Configured token file
+	byKey := resolveByKey(t, s, nil, Options{Vault: VaultConfig{Address: server.URL, TokenFile: tokenFile}})
+	if rv := byKey["db.password"]; rv.Value != "s3cret" {
+		t.Errorf("with token file: db.password = %q, want s3cret", rv.Value)
+	}
+
+	// ~/.vault-token
+	if err := os.WriteFile(filepath.Join(dir, ".vault-token"), []byte("file-token"), 0600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+	byKey = resolveByKey(t, s, []string{"HOME=" + dir}, Options{Vault: VaultConfig{Address: server.URL}})
+	if rv := byKey["db.password"]; rv.Value != "s3cret" {
+		t.Errorf("with ~/.vault-token: db.password = %q, want s3cret", rv.Value)
+	}
+
+	// VAULT_TOKEN wins over the token file
+	_, err := ResolveWithOptions(s, []string{"VAULT_TOKEN=other"}, Options{Vault: VaultConfig{Address: server.URL, TokenFile: tokenFile}})
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("error = %v, want VAULT_TOKEN to be used", err)
+	}
+}
+
+func TestResolveWithOptions_VaultNonScalar(t *testing.T) {
+	fake := &fakeVault{token: "t", secrets: map[string]string{"secret/data/myapp/db": `{"password":{"nested":true}}`}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	s := schema.Schema{Config: map[string]schema.ConfigKey{"db.password": vaultSchema().Config["db.password"]}}
+	_, err := ResolveWithOptions(s, []string{"VAULT_TOKEN=t"}, Options{Vault: VaultConfig{Address: server.URL}})
+	var vaultErr *VaultError
+	if err == nil || errors.As(err, &vaultErr) || !strings.Contains(err.Error(), "field 'password' is not a scalar value") {
+		t.Errorf("error = %v, want non-scalar error that is not a fetch failure", err)
+	}
+}
+
+func TestUsesVault(t *testing.T) {
+	if !UsesVault(vaultSchema()) {
+		t.Error("UsesVault() = false for a schema with vault stanzas")
+	}
+	if UsesVault(schema.Schema{Config: map[string]schema.ConfigKey{"a": {Path: "a"}}}) {
+		t.Error("UsesVault() = true for a schema without vault stanzas")
+	}
+}
@@ -1,14 +1,19 @@
 package schema
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
+	"time"
 
+	"admit/internal/codes"
 	"admit/internal/contract"
 	"admit/internal/invariant"
+	"admit/internal/severity"
 
 	"gopkg.in/yaml.v3"
 )
@@ -18,6 +23,7 @@ type schemaFile struct {
 	Config       map[string]configEntry      `yaml:"config"`
 	Invariants   []invariantEntry            `yaml:"invariants,omitempty"`
 	Environments map[string]environmentEntry `yaml:"environments,omitempty"`
+	Sources      []sourceEntry               `yaml:"sources,omitempty"`
 }
 
 // configEntry represents a single config entry in YAML
@@ -25,12 +31,138 @@ type configEntry struct {
 	Type     string   `yaml:"type"`
 	Required bool     `yaml:"required"`
 	Values   []string `yaml:"values,omitempty"`
+	Default  *string  `yaml:"default,omitempty"`
+	Aliases  []string `yaml:"aliases,omitempty"`
+
+	Interpolate *bool `yaml:"interpolate,omitempty"` // Defaults to true
+	Sensitive   *bool `yaml:"sensitive,omitempty"`   // Defaults to the source's setting
+
+	Vault    *vaultEntry `yaml:"vault,omitempty"`
+	Severity string      `yaml:"severity,omitempty"` // "error" (default) or "warn"
+
+	ValidateWith *validatorEntry `yaml:"validate_with,omitempty"`
+	Probe        *probeEntry     `yaml:"probe,omitempty"`
+}
+
+// vaultEntry represents a config entry's vault stanza in YAML
+type vaultEntry struct {
+	Mount string `yaml:"mount,omitempty"`
+	Path  string `yaml:"path"`
+	Field string `yaml:"field"`
+}
+
+// validatorEntry represents a config entry's external validator in YAML.
+// It can be a bare executable path or a mapping with settings.
+type validatorEntry struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args,omitempty"`
+	Timeout string   `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements custom unmarshaling for validatorEntry to handle
+// both bare paths and mappings
+func (e *validatorEntry) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		e.Command = value.Value
+		return nil
+	}
+
+	// Decode through a distinct type to avoid recursing into this method
+	type plain validatorEntry
+	var p plain
+	if err := value.Decode(&p); err != nil {
+		return fmt.Errorf("validate_with must be an executable path or a mapping with 'command'")
+	}
+	*e = validatorEntry(p)
+	return nil
+}
+
+// MarshalYAML implements custom marshaling for validatorEntry
+// Entries without settings are serialized as bare paths
+func (e validatorEntry) MarshalYAML() (interface{}, error) {
+	if reflect.DeepEqual(e, validatorEntry{Command: e.Command}) {
+		return e.Command, nil
+	}
+	type plain validatorEntry
+	return plain(e), nil
+}
+
+// probeEntry represents a config entry's probe in YAML.
+// It can be a bare probe type or a mapping with settings.
+type probeEntry struct {
+	Type    string `yaml:"type"`
+	Path    string `yaml:"path,omitempty"`
+	Timeout string `yaml:"timeout,omitempty"`
+	Retries int    `yaml:"retries,omitempty"`
+}
+
+// UnmarshalYAML implements custom unmarshaling for probeEntry to handle both
+// bare types and mappings
+func (e *probeEntry) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		e.Type = value.Value
+		return nil
+	}
+
+	// Decode through a distinct type to avoid recursing into this method
+	type plain probeEntry
+	var p plain
+	if err := value.Decode(&p); err != nil {
+		return fmt.Errorf("probe must be a probe type or a mapping with 'type'")
+	}
+	*e = probeEntry(p)
+	return nil
+}
+
+// MarshalYAML implements custom marshaling for probeEntry
+// Entries without settings are serialized as bare types
+func (e probeEntry) MarshalYAML() (interface{}, error) {
+	if e == (probeEntry{Type: e.Type}) {
+		return e.Type, nil
+	}
+	type plain probeEntry
+	return plain(e), nil
 }
 
 // invariantEntry represents a single invariant entry in YAML
 type invariantEntry struct {
-	Name string `yaml:"name"`
-	Rule string `yaml:"rule"`
+	Name     string     `yaml:"name"`
+	Rule     string     `yaml:"rule"`
+	Severity string     `yaml:"severity,omitempty"`
+	When     *whenEntry `yaml:"when,omitempty"`
+}
+
+// whenEntry represents an invariant's when: selector in YAML
+type whenEntry struct {
+	Env     stringList `yaml:"env,omitempty"`
+	Command stringList `yaml:"command,omitempty"`
+}
+
+// stringList is a list of strings that can also be written as a single string
+type stringList []string
+
+// UnmarshalYAML implements custom unmarshaling for stringList to handle
+// single values and arrays
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = stringList{value.Value}
+		return nil
+	}
+	var array []string
+	if err := value.Decode(&array); err != nil {
+		return fmt.Errorf("expected a string or an array of strings")
+	}
+	*l = array
+	return nil
+}
+
+// MarshalYAML implements custom marshaling for stringList
+// Single values are serialized as strings
+func (l stringList) MarshalYAML() (interface{}, error) {
+	if len(l) == 1 {
+		return l[0], nil
+	}
+	return []string(l), nil
 }
 
 // environmentEntry represents a single environment contract in YAML
@@ -39,14 +171,32 @@ type environmentEntry struct {
 	Deny  map[string]ruleEntry `yaml:"deny,omitempty"`
 }
 
-// ruleEntry represents a rule value that can be a single string or array of strings
+// ruleEntry represents a rule value that can be a single string, an array of
+// strings, or a mapping with "values" and a "severity"
 type ruleEntry struct {
-	values []string
+	values   []string
+	severity string
 }
 
-// UnmarshalYAML implements custom unmarshaling for ruleEntry to handle both
-// single values and arrays
+// ruleMapping is the mapping form of a rule
+type ruleMapping struct {
+	Values   ruleEntry `yaml:"values"`
+	Severity string    `yaml:"severity,omitempty"`
+}
+
+// UnmarshalYAML implements custom unmarshaling for ruleEntry to handle
+// single values, arrays and mappings
 func (r *ruleEntry) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.MappingNode {
+		var m ruleMapping
+		if err := value.Decode(&m); err != nil {
+			return err
+		}
+		r.values = m.Values.values
+		r.severity = m.Severity
+		return nil
+	}
+
 	// Try to unmarshal as a single string first
 	var single string
 	if err := value.Decode(&single); err == nil {
@@ -61,26 +211,105 @@ func (r *ruleEntry) UnmarshalYAML(value *yaml.Node) error {
 		return nil
 	}
 
-	return fmt.Errorf("rule value must be a string or array of strings")
+	return fmt.Errorf("rule value must be a string, an array of strings or a mapping with 'values'")
 }
 
 // MarshalYAML implements custom marshaling for ruleEntry
-// Single values are serialized as strings, multiple values as arrays
+// Single values are serialized as strings, multiple values as arrays,
+// and rules with a severity as mappings
 func (r ruleEntry) MarshalYAML() (interface{}, error) {
+	if r.severity != "" {
+		return ruleMapping{Values: ruleEntry{values: r.values}, Severity: r.severity}, nil
+	}
 	if len(r.values) == 1 {
 		return r.values[0], nil
 	}
 	return r.values, nil
 }
 
+// sourceEntry represents a source chain entry in YAML.
+// It can be a bare type name ("env") or a mapping with settings.
+type sourceEntry struct {
+	Type      string   `yaml:"type"`
+	Path      string   `yaml:"path,omitempty"`
+	Args      []string `yaml:"args,omitempty"`
+	Timeout   string   `yaml:"timeout,omitempty"`
+	OnError   string   `yaml:"on_error,omitempty"`
+	Address   string   `yaml:"address,omitempty"`
+	TokenFile string   `yaml:"token_file,omitempty"`
+	Retries   *int     `yaml:"retries,omitempty"`
+}
+
+// UnmarshalYAML implements custom unmarshaling for sourceEntry to handle both
+// bare type names and mappings
+func (e *sourceEntry) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		e.Type = value.Value
+		return nil
+	}
+
+	// Decode through a distinct type to avoid recursing into this method
+	type plain sourceEntry
+	var p plain
+	if err := value.Decode(&p); err != nil {
+		return fmt.Errorf("source must be a type name or a mapping with 'type'")
+	}
+	*e = sourceEntry(p)
+	return nil
+}
+
+// MarshalYAML implements custom marshaling for sourceEntry
+// Entries without settings are serialized as bare type names
+func (e sourceEntry) MarshalYAML() (interface{}, error) {
+	if reflect.DeepEqual(e, sourceEntry{Type: e.Type}) {
+		return e.Type, nil
+	}
+	type plain sourceEntry
+	return plain(e), nil
+}
+
 // invariantNameRegex validates invariant names: alphanumeric, hyphens, underscores
 var invariantNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 
+// ParseError is a schema error with the code of the section it was found in.
+// Its message is that of the wrapped error.
+type ParseError struct {
+	Code codes.Code
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// parseError formats a schema error with its code
+func parseError(code codes.Code, format string, args ...interface{}) error {
+	return &ParseError{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// ErrorCode returns the code for an error from LoadSchemaFromPath or
+// ParseSchema: codes.SchemaNotFound for a missing file, the ParseError's code,
+// or "" for other errors
+func ErrorCode(err error) codes.Code {
+	var parseErr *ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		return parseErr.Code
+	case os.IsNotExist(err):
+		return codes.SchemaNotFound
+	}
+	return ""
+}
+
 // ParseSchema parses YAML content into a Schema
 func ParseSchema(content []byte) (Schema, error) {
 	var sf schemaFile
 	if err := yaml.Unmarshal(content, &sf); err != nil {
-		return Schema{}, fmt.Errorf("invalid YAML: %w", err)
+		return Schema{}, parseError(codes.SchemaInvalidYAML, "invalid YAML: %w", err)
 	}
 
 	schema := Schema{
@@ -93,13 +322,88 @@ func ParseSchema(content []byte) (Schema, error) {
 		configType := ConfigType(entry.Type)
 
 		// Validate type
-		if configType != TypeString && configType != TypeEnum {
-			return Schema{}, fmt.Errorf("unknown type '%s' for config '%s'", entry.Type, path)
+		switch configType {
+		case TypeString, TypeEnum, TypeInt, TypeFloat, TypeDuration:
+		default:
+			return Schema{}, parseError(codes.SchemaInvalidKey, "unknown type '%s' for config '%s'", entry.Type, path)
 		}
 
 		// Validate enum has values
 		if configType == TypeEnum && len(entry.Values) == 0 {
-			return Schema{}, fmt.Errorf("enum type requires 'values' for config '%s'", path)
+			return Schema{}, parseError(codes.SchemaInvalidKey, "enum type requires 'values' for config '%s'", path)
+		}
+
+		// Validate enum default is one of the allowed values
+		if configType == TypeEnum && entry.Default != nil && !containsString(entry.Values, *entry.Default) {
+			return Schema{}, parseError(codes.SchemaInvalidKey, "default '%s' is not one of the allowed values for config '%s'", *entry.Default, path)
+		}
+
+		// Validate a typed default has the type's format
+		if entry.Default != nil {
+			if err := configType.CheckValue(*entry.Default); err != nil {
+				return Schema{}, parseError(codes.SchemaInvalidKey, "default '%s' for config '%s' is %s", *entry.Default, path, err)
+			}
+		}
+
+		// Validate aliases are non-empty env var names
+		for _, alias := range entry.Aliases {
+			if alias == "" {
+				return Schema{}, parseError(codes.SchemaInvalidKey, "empty alias for config '%s'", path)
+			}
+		}
+
+		// Validate the vault stanza names a secret and a field
+		var vaultRef *VaultRef
+		if entry.Vault != nil {
+			if entry.Vault.Path == "" || entry.Vault.Field == "" {
+				return Schema{}, parseError(codes.SchemaInvalidKey, "vault stanza for config '%s' requires 'path' and 'field'", path)
+			}
+			vaultRef = &VaultRef{Mount: entry.Vault.Mount, Path: entry.Vault.Path, Field: entry.Vault.Field}
+		}
+
+		level, err := severity.Parse(entry.Severity)
+		if err != nil {
+			return Schema{}, parseError(codes.SchemaInvalidKey, "config '%s': %w", path, err)
+		}
+
+		// Validate the external validator names an executable
+		var validatorSpec *ValidatorSpec
+		if entry.ValidateWith != nil {
+			if entry.ValidateWith.Command == "" {
+				return Schema{}, parseError(codes.SchemaInvalidKey, "validate_with for config '%s' requires 'command'", path)
+			}
+			validatorSpec = &ValidatorSpec{Command: entry.ValidateWith.Command, Args: entry.ValidateWith.Args}
+			if entry.ValidateWith.Timeout != "" {
+				timeout, err := time.ParseDuration(entry.ValidateWith.Timeout)
+				if err != nil || timeout <= 0 {
+					return Schema{}, parseError(codes.SchemaInvalidKey, "validate_with for config '%s': invalid timeout '%s'", path, entry.ValidateWith.Timeout)
+				}
+				validatorSpec.Timeout = timeout
+			}
+		}
+
+		// Validate the probe type and settings
+		var probeSpec *ProbeSpec
+		if entry.Probe != nil {
+			switch entry.Probe.Type {
+			case ProbeTCP, ProbeUnix, ProbeHTTP:
+			default:
+				return Schema{}, parseError(codes.SchemaInvalidKey, "probe for config '%s': unknown type '%s' (must be '%s', '%s' or '%s')", path, entry.Probe.Type, ProbeTCP, ProbeUnix, ProbeHTTP)
+			}
+			if entry.Probe.Path != "" && entry.Probe.Type != ProbeHTTP {
+				return Schema{}, parseError(codes.SchemaInvalidKey, "probe for config '%s': 'path' is only valid for http probes", path)
+			}
+			if entry.Probe.Retries < 0 {
+				return Schema{}, parseError(codes.SchemaInvalidKey, "probe for config '%s': retries must not be negative", path)
+			}
+			probeSpec = &ProbeSpec{Type: entry.Probe.Type, Path: entry.Probe.Path, Retries: entry.Probe.Retries}
+			if entry.Probe.Timeout != "" {
+				timeout, err := time.ParseDuration(entry.Probe.Timeout)
+				if err != nil || timeout <= 0 {
+					return Schema{}, parseError(codes.SchemaInvalidKey, "probe for config '%s': invalid timeout '%s'", path, entry.Probe.Timeout)
+				}
+				probeSpec.Timeout = timeout
+			}
 		}
 
 		schema.Config[path] = ConfigKey{
@@ -107,15 +411,24 @@ func ParseSchema(content []byte) (Schema, error) {
 			Type:     configType,
 			Required: entry.Required,
 			Values:   entry.Values,
+			Default:  entry.Default,
+			Aliases:  entry.Aliases,
+
+			NoInterpolate: entry.Interpolate != nil && !*entry.Interpolate,
+			Sensitive:     entry.Sensitive,
+			Vault:         vaultRef,
+			Severity:      level,
+			ValidateWith:  validatorSpec,
+			Probe:         probeSpec,
 		}
 	}
 
 	// Parse invariants if present
 	if len(sf.Invariants) > 0 {
-		// Collect config keys for validation
-		configKeys := make([]string, 0, len(schema.Config))
-		for k := range schema.Config {
-			configKeys = append(configKeys, k)
+		// Collect config keys and their types for validation
+		keyTypes := make(map[string]invariant.ValueType, len(schema.Config))
+		for k, configKey := range schema.Config {
+			keyTypes[k] = invariant.ValueType(configKey.Type)
 		}
 
 		// Track names for uniqueness validation
@@ -124,35 +437,47 @@ func ParseSchema(content []byte) (Schema, error) {
 		for i, inv := range sf.Invariants {
 			// Validate name is present
 			if inv.Name == "" {
-				return Schema{}, fmt.Errorf("invariant at index %d: missing required field 'name'", i)
+				return Schema{}, parseError(codes.SchemaInvalidRule, "invariant at index %d: missing required field 'name'", i)
 			}
 
 			// Validate name format
 			if !invariantNameRegex.MatchString(inv.Name) {
-				return Schema{}, fmt.Errorf("invariant name '%s' contains invalid characters", inv.Name)
+				return Schema{}, parseError(codes.SchemaInvalidRule, "invariant name '%s' contains invalid characters", inv.Name)
 			}
 
 			// Validate name uniqueness
 			if seenNames[inv.Name] {
-				return Schema{}, fmt.Errorf("duplicate invariant name: '%s'", inv.Name)
+				return Schema{}, parseError(codes.SchemaInvalidRule, "duplicate invariant name: '%s'", inv.Name)
 			}
 			seenNames[inv.Name] = true
 
 			// Validate rule is present
 			if inv.Rule == "" {
-				return Schema{}, fmt.Errorf("invariant '%s': missing required field 'rule'", inv.Name)
+				return Schema{}, parseError(codes.SchemaInvalidRule, "invariant '%s': missing required field 'rule'", inv.Name)
 			}
 
 			// Parse rule expression
-			expr, err := invariant.ParseRule(inv.Rule, configKeys)
+			expr, err := invariant.ParseTypedRule(inv.Rule, keyTypes)
 			if err != nil {
-				return Schema{}, fmt.Errorf("invariant '%s': invalid rule syntax: %w", inv.Name, err)
+				return Schema{}, parseError(codes.SchemaInvalidRule, "invariant '%s': invalid rule syntax: %w", inv.Name, err)
+			}
+
+			level, err := severity.Parse(inv.Severity)
+			if err != nil {
+				return Schema{}, parseError(codes.SchemaInvalidRule, "invariant '%s': %w", inv.Name, err)
+			}
+
+			when, err := parseWhen(inv.When)
+			if err != nil {
+				return Schema{}, parseError(codes.SchemaInvalidRule, "invariant '%s': %w", inv.Name, err)
 			}
 
 			schema.Invariants = append(schema.Invariants, invariant.Invariant{
-				Name: inv.Name,
-				Rule: inv.Rule,
-				Expr: expr,
+				Name:     inv.Name,
+				Rule:     inv.Rule,
+				Expr:     expr,
+				Severity: level,
+				When:     when,
 			})
 		}
 	}
@@ -162,15 +487,67 @@ func ParseSchema(content []byte) (Schema, error) {
 		for envName, envEntry := range sf.Environments {
 			c, err := parseEnvironmentContract(envName, envEntry)
 			if err != nil {
-				return Schema{}, fmt.Errorf("environment '%s': %w", envName, err)
+				return Schema{}, parseError(codes.SchemaInvalidEnv, "environment '%s': %w", envName, err)
 			}
 			schema.Environments[envName] = c
 		}
 	}
 
+	// Parse the source chain if present
+	// Source types are checked by the resolver, which owns them
+	seenSources := make(map[string]bool)
+	for i, entry := range sf.Sources {
+		if entry.Type == "" {
+			return Schema{}, parseError(codes.SchemaInvalidChain, "source at index %d: missing required field 'type'", i)
+		}
+		if seenSources[entry.Type] {
+			return Schema{}, parseError(codes.SchemaInvalidChain, "duplicate source: '%s'", entry.Type)
+		}
+		seenSources[entry.Type] = true
+
+		if entry.Retries != nil && *entry.Retries < 0 {
+			return Schema{}, parseError(codes.SchemaInvalidChain, "source '%s': retries must not be negative", entry.Type)
+		}
+		spec := SourceSpec{
+			Type:      entry.Type,
+			Path:      entry.Path,
+			Args:      entry.Args,
+			OnError:   entry.OnError,
+			Address:   entry.Address,
+			TokenFile: entry.TokenFile,
+			Retries:   entry.Retries,
+		}
+		if entry.Timeout != "" {
+			timeout, err := time.ParseDuration(entry.Timeout)
+			if err != nil || timeout <= 0 {
+				return Schema{}, parseError(codes.SchemaInvalidChain, "source '%s': invalid timeout '%s'", entry.Type, entry.Timeout)
+			}
+			spec.Timeout = timeout
+		}
+		schema.Sources = append(schema.Sources, spec)
+	}
+
 	return schema, nil
 }
 
+// parseWhen validates an invariant's when: selector (v9)
+func parseWhen(entry *whenEntry) (invariant.When, error) {
+	if entry == nil {
+		return invariant.When{}, nil
+	}
+	if len(entry.Env) == 0 && len(entry.Command) == 0 {
+		return invariant.When{}, fmt.Errorf("'when' must set 'env' or 'command'")
+	}
+	for field, values := range map[string]stringList{"env": entry.Env, "command": entry.Command} {
+		for _, v := range values {
+			if v == "" {
+				return invariant.When{}, fmt.Errorf("empty value in when.%s", field)
+			}
+		}
+	}
+	return invariant.When{Env: entry.Env, Command: entry.Command}, nil
+}
+
 // parseEnvironmentContract converts an environmentEntry to a contract.Contract
 func parseEnvironmentContract(name string, entry environmentEntry) (contract.Contract, error) {
 	c := contract.Contract{
@@ -184,9 +561,14 @@ func parseEnvironmentContract(name string, entry environmentEntry) (contract.Con
 		if len(rule.values) == 0 {
 			return contract.Contract{}, fmt.Errorf("allow rule for '%s' has no values", key)
 		}
+		level, err := severity.Parse(rule.severity)
+		if err != nil {
+			return contract.Contract{}, fmt.Errorf("allow rule for '%s': %w", key, err)
+		}
 		c.Allow[key] = contract.Rule{
-			Values: rule.values,
-			IsGlob: false, // Allow rules don't support glob patterns
+			Values:   rule.values,
+			IsGlob:   false, // Allow rules don't support glob patterns
+			Severity: level,
 		}
 	}
 
@@ -195,6 +577,10 @@ func parseEnvironmentContract(name string, entry environmentEntry) (contract.Con
 		if len(rule.values) == 0 {
 			return contract.Contract{}, fmt.Errorf("deny rule for '%s' has no values", key)
 		}
+		level, err := severity.Parse(rule.severity)
+		if err != nil {
+			return contract.Contract{}, fmt.Errorf("deny rule for '%s': %w", key, err)
+		}
 		// Check if any value contains a glob pattern
 		isGlob := false
 		for _, v := range rule.values {
@@ -204,14 +590,25 @@ func parseEnvironmentContract(name string, entry environmentEntry) (contract.Con
 			}
 		}
 		c.Deny[key] = contract.Rule{
-			Values: rule.values,
-			IsGlob: isGlob,
+			Values:   rule.values,
+			IsGlob:   isGlob,
+			Severity: level,
 		}
 	}
 
 	return c, nil
 }
 
+// containsString checks if a value is in the list
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // ToYAML serializes a Schema back to YAML bytes
 func (s Schema) ToYAML() ([]byte, error) {
 	sf := schemaFile{
@@ -220,19 +617,49 @@ func (s Schema) ToYAML() ([]byte, error) {
 	}
 
 	for path, key := range s.Config {
-		sf.Config[path] = configEntry{
+		entry := configEntry{
 			Type:     string(key.Type),
 			Required: key.Required,
 			Values:   key.Values,
+			Default:  key.Default,
+			Aliases:  key.Aliases,
+
+			Sensitive: key.Sensitive,
+			Severity:  string(key.Severity),
+		}
+		if key.Vault != nil {
+			entry.Vault = &vaultEntry{Mount: key.Vault.Mount, Path: key.Vault.Path, Field: key.Vault.Field}
+		}
+		if key.ValidateWith != nil {
+			entry.ValidateWith = &validatorEntry{Command: key.ValidateWith.Command, Args: key.ValidateWith.Args}
+			if key.ValidateWith.Timeout != 0 {
+				entry.ValidateWith.Timeout = key.ValidateWith.Timeout.String()
+			}
+		}
+		if key.Probe != nil {
+			entry.Probe = &probeEntry{Type: key.Probe.Type, Path: key.Probe.Path, Retries: key.Probe.Retries}
+			if key.Probe.Timeout != 0 {
+				entry.Probe.Timeout = key.Probe.Timeout.String()
+			}
+		}
+		if key.NoInterpolate {
+			interpolate := false
+			entry.Interpolate = &interpolate
 		}
+		sf.Config[path] = entry
 	}
 
 	// Serialize invariants if present
 	for _, inv := range s.Invariants {
-		sf.Invariants = append(sf.Invariants, invariantEntry{
-			Name: inv.Name,
-			Rule: inv.Rule,
-		})
+		entry := invariantEntry{
+			Name:     inv.Name,
+			Rule:     inv.Rule,
+			Severity: string(inv.Severity),
+		}
+		if !inv.When.IsZero() {
+			entry.When = &whenEntry{Env: inv.When.Env, Command: inv.When.Command}
+		}
+		sf.Invariants = append(sf.Invariants, entry)
 	}
 
 	// Serialize environments if present
@@ -242,14 +669,31 @@ func (s Schema) ToYAML() ([]byte, error) {
 			Deny:  make(map[string]ruleEntry),
 		}
 		for key, rule := range c.Allow {
-			envEntry.Allow[key] = ruleEntry{values: rule.Values}
+			envEntry.Allow[key] = ruleEntry{values: rule.Values, severity: string(rule.Severity)}
 		}
 		for key, rule := range c.Deny {
-			envEntry.Deny[key] = ruleEntry{values: rule.Values}
+			envEntry.Deny[key] = ruleEntry{values: rule.Values, severity: string(rule.Severity)}
 		}
 		sf.Environments[envName] = envEntry
 	}
 
+	// Serialize the source chain if present
+	for _, spec := range s.Sources {
+		entry := sourceEntry{
+			Type:      spec.Type,
+			Path:      spec.Path,
+			Args:      spec.Args,
+			OnError:   spec.OnError,
+			Address:   spec.Address,
+			TokenFile: spec.TokenFile,
+			Retries:   spec.Retries,
+		}
+		if spec.Timeout != 0 {
+			entry.Timeout = spec.Timeout.String()
+		}
+		sf.Sources = append(sf.Sources, entry)
+	}
+
 	// Remove empty environments map to avoid serializing empty section
 	if len(sf.Environments) == 0 {
 		sf.Environments = nil
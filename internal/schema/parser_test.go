@@ -1,12 +1,17 @@
 package schema
 
 import (
+	"errors"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"admit/internal/codes"
 	"admit/internal/contract"
 	"admit/internal/invariant"
+	"admit/internal/severity"
 
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
@@ -996,3 +1001,827 @@ environments:
 
 	properties.TestingRun(t)
 }
+
+// TestParseSchema_DefaultsAndAliases tests parsing of v8 default and aliases fields
+func TestParseSchema_DefaultsAndAliases(t *testing.T) {
+	content := `config:
+  log.level:
+    type: enum
+    values: [debug, info]
+    default: info
+  db.url:
+    type: string
+    required: true
+    aliases: [DATABASE_URL, LEGACY_DB_URL]
+`
+	s, err := ParseSchema([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	logLevel := s.Config["log.level"]
+	if logLevel.Default == nil || *logLevel.Default != "info" {
+		t.Errorf("log.level default = %v, want info", logLevel.Default)
+	}
+
+	dbURL := s.Config["db.url"]
+	if dbURL.Default != nil {
+		t.Errorf("db.url default = %v, want nil", *dbURL.Default)
+	}
+	if !reflect.DeepEqual(dbURL.Aliases, []string{"DATABASE_URL", "LEGACY_DB_URL"}) {
+		t.Errorf("db.url aliases = %v", dbURL.Aliases)
+	}
+
+	// Round-trip preserves defaults and aliases
+	yamlBytes, err := s.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	parsed, err := ParseSchema(yamlBytes)
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+	if !reflect.DeepEqual(s, parsed) {
+		t.Errorf("round-trip mismatch:\n%+v\n%+v", s, parsed)
+	}
+}
+
+// TestParseSchema_Interpolate tests the v8 per-key interpolate flag
+func TestParseSchema_Interpolate(t *testing.T) {
+	content := `config:
+  app.banner:
+    type: string
+    interpolate: false
+  db.url:
+    type: string
+    interpolate: true
+  db.host:
+    type: string
+`
+	s, err := ParseSchema([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]bool{"app.banner": true, "db.url": false, "db.host": false}
+	for path, noInterpolate := range want {
+		if s.Config[path].NoInterpolate != noInterpolate {
+			t.Errorf("%s NoInterpolate = %v, want %v", path, s.Config[path].NoInterpolate, noInterpolate)
+		}
+	}
+
+	yamlBytes, err := s.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	parsed, err := ParseSchema(yamlBytes)
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+	if !reflect.DeepEqual(s, parsed) {
+		t.Errorf("round-trip mismatch:\n%+v\n%+v", s, parsed)
+	}
+}
+
+// TestParseSchema_Sources tests parsing of the v8 source chain
+func TestParseSchema_Sources(t *testing.T) {
+	content := `config:
+  db.url:
+    type: string
+sources:
+  - env
+  - type: config-file
+    path: config.yaml
+  - type: env-file
+    path: .env
+  - type: helper
+    path: ./bin/fetch-secrets
+    args: [--profile, prod]
+    timeout: 2s
+    on_error: ignore
+  - type: vault
+    address: https://vault:8200
+    token_file: vault-token
+    retries: 0
+`
+	s, err := ParseSchema([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []SourceSpec{
+		{Type: "env"},
+		{Type: "config-file", Path: "config.yaml"},
+		{Type: "env-file", Path: ".env"},
+		{Type: "helper", Path: "./bin/fetch-secrets", Args: []string{"--profile", "prod"}, Timeout: 2 * time.Second, OnError: "ignore"},
+		{Type: "vault", Address: "https://vault:8200", TokenFile: "vault-token", Retries: intPtr(0)},
+	}
+	if !reflect.DeepEqual(s.Sources, want) {
+		t.Errorf("Sources = %+v, want %+v", s.Sources, want)
+	}
+
+	yamlBytes, err := s.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	parsed, err := ParseSchema(yamlBytes)
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+	if !reflect.DeepEqual(s, parsed) {
+		t.Errorf("round-trip mismatch:\n%+v\n%+v", s, parsed)
+	}
+}
+
+// TestParseSchema_Sensitive tests the v8 per-key sensitive setting
+func TestParseSchema_Sensitive(t *testing.T) {
+	content := `config:
+  db.password:
+    type: string
+    sensitive: true
+  api.tier:
+    type: string
+    sensitive: false
+  db.url:
+    type: string
+`
+	s, err := ParseSchema([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := s.Config["db.password"].Sensitive; v == nil || !*v {
+		t.Errorf("db.password Sensitive = %v, want true", v)
+	}
+	if v := s.Config["api.tier"].Sensitive; v == nil || *v {
+		t.Errorf("api.tier Sensitive = %v, want false", v)
+	}
+	if v := s.Config["db.url"].Sensitive; v != nil {
+		t.Errorf("db.url Sensitive = %v, want unset", *v)
+	}
+
+	yamlBytes, err := s.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	parsed, err := ParseSchema(yamlBytes)
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+	if !reflect.DeepEqual(s, parsed) {
+		t.Errorf("round-trip mismatch:\n%+v\n%+v", s, parsed)
+	}
+}
+
+// TestParseSchema_Vault tests the v8 per-key vault stanza
+func TestParseSchema_Vault(t *testing.T) {
+	content := `config:
+  db.password:
+    type: string
+    vault:
+      path: myapp/db
+      field: password
+  api.key:
+    type: string
+    vault:
+      mount: kv
+      path: myapp/api
+      field: key
+`
+	s, err := ParseSchema([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := s.Config["db.password"].Vault; !reflect.DeepEqual(got, &VaultRef{Path: "myapp/db", Field: "password"}) {
+		t.Errorf("db.password Vault = %+v", got)
+	}
+	if got := s.Config["api.key"].Vault; !reflect.DeepEqual(got, &VaultRef{Mount: "kv", Path: "myapp/api", Field: "key"}) {
+		t.Errorf("api.key Vault = %+v", got)
+	}
+
+	yamlBytes, err := s.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	parsed, err := ParseSchema(yamlBytes)
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v", err)
+	}
+	if !reflect.DeepEqual(s, parsed) {
+		t.Errorf("round-trip mismatch:\n%+v\n%+v", s, parsed)
+	}
+
+	_, err = ParseSchema([]byte("config:\n  db.password:\n    type: string\n    vault:\n      path: myapp/db\n"))
+	if err == nil || !strings.Contains(err.Error(), "vault stanza for config 'db.password' requires 'path' and 'field'") {
+		t.Errorf("expected missing field error, got %v", err)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// TestParseSchema_SourcesErrors tests validation of the v8 source chain
+func TestParseSchema_SourcesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "missing type",
+			content: "config: {}\nsources:\n  - path: config.yaml\n",
+			wantErr: "source at index 0: missing required field 'type'",
+		},
+		{
+			name:    "duplicate type",
+			content: "config: {}\nsources:\n  - env\n  - type: env\n",
+			wantErr: "duplicate source: 'env'",
+		},
+		{
+			name:    "not a name or mapping",
+			content: "config: {}\nsources:\n  - [env]\n",
+			wantErr: "source must be a type name or a mapping with 'type'",
+		},
+		{
+			name:    "negative retries",
+			content: "config: {}\nsources:\n  - type: vault\n    retries: -1\n",
+			wantErr: "source 'vault': retries must not be negative",
+		},
+		{
+			name:    "invalid timeout",
+			content: "config: {}\nsources:\n  - type: helper\n    path: fetch\n    timeout: soon\n",
+			wantErr: "source 'helper': invalid timeout 'soon'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSchema([]byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestParseSchema_DefaultsAndAliasesErrors tests validation of v8 default and aliases fields
+func TestParseSchema_DefaultsAndAliasesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "enum default not in values",
+			content: `config:
+  log.level:
+    type: enum
+    values: [debug, info]
+    default: trace
+`,
+			wantErr: "default 'trace' is not one of the allowed values",
+		},
+		{
+			name: "empty alias",
+			content: `config:
+  db.url:
+    type: string
+    aliases: [""]
+`,
+			wantErr: "empty alias",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSchema([]byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestParseSchema_Severity tests the v9 severity setting on config keys,
+// invariants and contract rules
+func TestParseSchema_Severity(t *testing.T) {
+	content := `config:
+  log.format:
+    type: enum
+    values: [json, text]
+    severity: warn
+  db.url:
+    type: string
+    severity: error
+  cache.url:
+    type: string
+invariants:
+  - name: json-logs
+    rule: log.format == "json"
+    severity: warn
+  - name: db-set
+    rule: db.url != ""
+environments:
+  prod:
+    allow:
+      log.format:
+        values: [json]
+        severity: warn
+    deny:
+      db.url: "*localhost*"
+      cache.url:
+        values: "*localhost*"
+        severity: warn
+`
+	s, err := ParseSchema([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := s.Config["log.format"].Severity; got != severity.Warn {
+		t.Errorf("log.format Severity = %q, want warn", got)
+	}
+	if got := s.Config["db.url"].Severity; got != severity.Error {
+		t.Errorf("db.url Severity = %q, want error", got)
+	}
+	if got := s.Config["cache.url"].Severity; got != "" {
+		t.Errorf("cache.url Severity = %q, want unset", got)
+	}
+	if s.Invariants[0].Severity != severity.Warn || s.Invariants[1].Severity != "" {
+		t.Errorf("invariant severities = %q, %q", s.Invariants[0].Severity, s.Invariants[1].Severity)
+	}
+	prod := s.Environments["prod"]
+	if rule := prod.Allow["log.format"]; rule.Severity != severity.Warn || !reflect.DeepEqual(rule.Values, []string{"json"}) {
+		t.Errorf("allow log.format = %+v", rule)
+	}
+	if rule := prod.Deny["cache.url"]; rule.Severity != severity.Warn || !rule.IsGlob {
+		t.Errorf("deny cache.url = %+v, want glob warn rule from the mapping form", rule)
+	}
+	if rule := prod.Deny["db.url"]; rule.Severity != "" {
+		t.Errorf("deny db.url Severity = %q, want unset", rule.Severity)
+	}
+
+	yamlBytes, err := s.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	parsed, err := ParseSchema(yamlBytes)
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v\n%s", err, yamlBytes)
+	}
+	if !reflect.DeepEqual(s, parsed) {
+		t.Errorf("round-trip mismatch:\n%+v\n%+v", s, parsed)
+	}
+}
+
+func TestParseSchema_InvariantWhen(t *testing.T) {
+	content := `config:
+  db.role:
+    type: string
+invariants:
+  - name: prod-owner
+    rule: db.role == "owner"
+    when:
+      env: [prod, staging]
+  - name: migrate-owner
+    rule: db.role == "owner"
+    when:
+      env: prod
+      command: ["migrate", "db-*"]
+  - name: always
+    rule: db.role != ""
+`
+	s, err := ParseSchema([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []invariant.When{
+		{Env: []string{"prod", "staging"}},
+		{Env: []string{"prod"}, Command: []string{"migrate", "db-*"}},
+		{},
+	}
+	for i, w := range want {
+		if !reflect.DeepEqual(s.Invariants[i].When, w) {
+			t.Errorf("%s When = %+v, want %+v", s.Invariants[i].Name, s.Invariants[i].When, w)
+		}
+	}
+
+	yamlBytes, err := s.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	parsed, err := ParseSchema(yamlBytes)
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v\n%s", err, yamlBytes)
+	}
+	if !reflect.DeepEqual(s, parsed) {
+		t.Errorf("round-trip mismatch:\n%+v\n%+v", s, parsed)
+	}
+
+	base := "config:\n  a:\n    type: string\ninvariants:\n  - name: r\n    rule: a != \"\"\n    when:\n"
+	for when, wantErr := range map[string]string{
+		"      other: x\n":          "invariant 'r': 'when' must set 'env' or 'command'",
+		"      env: [prod, \"\"]\n": "invariant 'r': empty value in when.env",
+		"      command: {a: b}\n":   "expected a string or an array of strings",
+	} {
+		_, err := ParseSchema([]byte(base + when))
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("when %q: error = %v, want containing %q", when, err, wantErr)
+		}
+	}
+}
+
+func TestParseSchema_SeverityErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "config key",
+			content: "config:\n  a:\n    type: string\n    severity: warning\n",
+			wantErr: "config 'a': invalid severity 'warning' (must be 'error' or 'warn')",
+		},
+		{
+			name:    "invariant",
+			content: "config:\n  a:\n    type: string\ninvariants:\n  - name: r\n    rule: a == \"x\"\n    severity: info\n",
+			wantErr: "invariant 'r': invalid severity 'info'",
+		},
+		{
+			name:    "contract rule",
+			content: "config:\n  a:\n    type: string\nenvironments:\n  prod:\n    deny:\n      a:\n        values: [x]\n        severity: fatal\n",
+			wantErr: "environment 'prod': deny rule for 'a': invalid severity 'fatal'",
+		},
+		{
+			name:    "contract rule mapping without values",
+			content: "config:\n  a:\n    type: string\nenvironments:\n  prod:\n    allow:\n      a:\n        severity: warn\n",
+			wantErr: "allow rule for 'a' has no values",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSchema([]byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSchema_ErrorCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    codes.Code
+	}{
+		{name: "yaml", content: "config: [", want: codes.SchemaInvalidYAML},
+		{name: "config key", content: "config:\n  a:\n    type: number\n", want: codes.SchemaInvalidKey},
+		{name: "invariant", content: "config:\n  a:\n    type: string\ninvariants:\n  - name: r\n    rule: b == \"x\"\n", want: codes.SchemaInvalidRule},
+		{name: "environment", content: "config:\n  a:\n    type: string\nenvironments:\n  prod:\n    allow:\n      a: []\n", want: codes.SchemaInvalidEnv},
+		{name: "source", content: "config:\n  a:\n    type: string\nsources:\n  - env\n  - env\n", want: codes.SchemaInvalidChain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSchema([]byte(tt.content))
+			if got := ErrorCode(err); err == nil || got != tt.want {
+				t.Errorf("ErrorCode(%v) = %q, want %q", err, got, tt.want)
+			}
+		})
+	}
+
+	// The code does not change the message
+	_, err := ParseSchema([]byte("config:\n  a:\n    type: number\n"))
+	if err == nil || err.Error() != "unknown type 'number' for config 'a'" {
+		t.Errorf("error = %v, want the plain message", err)
+	}
+
+	_, err = LoadSchemaFromPath(filepath.Join(t.TempDir(), "admit.yaml"))
+	if got := ErrorCode(err); got != codes.SchemaNotFound {
+		t.Errorf("ErrorCode(missing file) = %q, want %q", got, codes.SchemaNotFound)
+	}
+	if got := ErrorCode(errors.New("other")); got != "" {
+		t.Errorf("ErrorCode(other) = %q, want none", got)
+	}
+}
+
+// TestParseSchema_ValidateWith tests the v9 validate_with setting in its
+// bare path and mapping forms
+func TestParseSchema_ValidateWith(t *testing.T) {
+	content := `config:
+  aws.account:
+    type: string
+    validate_with: ./checks/account.sh
+  aws.region:
+    type: string
+    validate_with:
+      command: check-region
+      args: [--strict]
+      timeout: 2s
+`
+	s, err := ParseSchema([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := s.Config["aws.account"].ValidateWith; got == nil || !reflect.DeepEqual(*got, ValidatorSpec{Command: "./checks/account.sh"}) {
+		t.Errorf("aws.account ValidateWith = %+v", got)
+	}
+	want := ValidatorSpec{Command: "check-region", Args: []string{"--strict"}, Timeout: 2 * time.Second}
+	if got := s.Config["aws.region"].ValidateWith; got == nil || !reflect.DeepEqual(*got, want) {
+		t.Errorf("aws.region ValidateWith = %+v, want %+v", got, want)
+	}
+
+	yamlBytes, err := s.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	if !strings.Contains(string(yamlBytes), "validate_with: ./checks/account.sh") {
+		t.Errorf("ToYAML() should write a bare path without settings:\n%s", yamlBytes)
+	}
+	parsed, err := ParseSchema(yamlBytes)
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v\n%s", err, yamlBytes)
+	}
+	if !reflect.DeepEqual(s, parsed) {
+		t.Errorf("round-trip mismatch:\n%+v\n%+v", s, parsed)
+	}
+
+	errorCases := map[string]string{
+		"config:\n  a:\n    type: string\n    validate_with: {args: [x]}\n":                 "validate_with for config 'a' requires 'command'",
+		"config:\n  a:\n    type: string\n    validate_with: {command: c, timeout: soon}\n": "validate_with for config 'a': invalid timeout 'soon'",
+		"config:\n  a:\n    type: string\n    validate_with: [c]\n":                         "validate_with must be an executable path or a mapping",
+	}
+	for content, wantErr := range errorCases {
+		if _, err := ParseSchema([]byte(content)); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("error = %v, want containing %q", err, wantErr)
+		}
+	}
+}
+
+// TestParseSchema_Probe tests the v9 probe setting in its bare type and
+// mapping forms
+func TestParseSchema_Probe(t *testing.T) {
+	content := `config:
+  db.url:
+    type: string
+    probe: tcp
+  api.url:
+    type: string
+    probe:
+      type: http
+      path: /healthz
+      timeout: 500ms
+      retries: 3
+`
+	s, err := ParseSchema([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := s.Config["db.url"].Probe; got == nil || *got != (ProbeSpec{Type: ProbeTCP}) {
+		t.Errorf("db.url Probe = %+v", got)
+	}
+	want := ProbeSpec{Type: ProbeHTTP, Path: "/healthz", Timeout: 500 * time.Millisecond, Retries: 3}
+	if got := s.Config["api.url"].Probe; got == nil || *got != want {
+		t.Errorf("api.url Probe = %+v, want %+v", got, want)
+	}
+
+	yamlBytes, err := s.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	if !strings.Contains(string(yamlBytes), "probe: tcp") {
+		t.Errorf("ToYAML() should write a bare type without settings:\n%s", yamlBytes)
+	}
+	parsed, err := ParseSchema(yamlBytes)
+	if err != nil {
+		t.Fatalf("ParseSchema failed: %v\n%s", err, yamlBytes)
+	}
+	if !reflect.DeepEqual(s, parsed) {
+		t.Errorf("round-trip mismatch:\n%+v\n%+v", s, parsed)
+	}
+
+	errorCases := map[string]string{
+		"config:\n  a:\n    type: string\n    probe: ping\n":                      "probe for config 'a': unknown type 'ping' (must be 'tcp', 'unix' or 'http')",
+		"config:\n  a:\n    type: string\n    probe: {type: tcp, path: /x}\n":     "probe for config 'a': 'path' is only valid for http probes",
+		"config:\n  a:\n    type: string\n    probe: {type: tcp, retries: -1}\n":  "probe for config 'a': retries must not be negative",
+		"config:\n  a:\n    type: string\n    probe: {type: unix, timeout: 0s}\n": "probe for config 'a': invalid timeout '0s'",
+		"config:\n  a:\n    type: string\n    probe: [tcp]\n":                     "probe must be a probe type or a mapping",
+	}
+	for content, wantErr := range errorCases {
+		if _, err := ParseSchema([]byte(content)); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("error = %v, want containing %q", err, wantErr)
+		}
+	}
+}
+
+func TestParseSchema_InvariantPatterns(t *testing.T) {
+	content := `config:
+  db.url:
+    type: string
+  payments.mode:
+    type: string
+invariants:
+  - name: prod-url
+    rule: execution.env == "prod" => db.url =~ "^postgres://.*prod"
+  - name: no-staging
+    rule: db.url like "*-staging*" => payments.mode in ["sandbox", "test"]
+`
+	s, err := ParseSchema([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	impl, ok := s.Invariants[0].Expr.(invariant.Implication)
+	if !ok {
+		t.Fatalf("prod-url Expr = %+v, want an implication", s.Invariants[0].Expr)
+	}
+	if match, ok := impl.Consequent.(invariant.Match); !ok || match.Regexp == nil {
+		t.Errorf("prod-url consequent = %+v, want a Match compiled at parse time", impl.Consequent)
+	}
+
+	// An invalid pattern is rejected with the invariant's name
+	invalid := "config:\n  db.url:\n    type: string\ninvariants:\n  - name: bad-pattern\n    rule: db.url =~ \"(prod\"\n"
+	_, err = ParseSchema([]byte(invalid))
+	if err == nil || !strings.Contains(err.Error(), "invariant 'bad-pattern': invalid rule syntax: invalid regular expression: error parsing regexp: missing closing )") {
+		t.Errorf("error = %v, want invalid pattern error naming the invariant", err)
+	}
+	if code := ErrorCode(err); code != codes.SchemaInvalidRule {
+		t.Errorf("ErrorCode() = %q, want %q", code, codes.SchemaInvalidRule)
+	}
+}
+
+func TestParseSchema_NumericTypes(t *testing.T) {
+	content := `config:
+  pool.min:
+    type: int
+    default: "2"
+  pool.max:
+    type: int
+  cache.ratio:
+    type: float
+  http.timeout:
+    type: duration
+    default: 30s
+  payments.mode:
+    type: enum
+    values: [test, live]
+invariants:
+  - name: pool-bounds
+    rule: pool.max >= pool.min
+  - name: timeout-cap
+    rule: http.timeout <= 2m
+`
+	s, err := ParseSchema([]byte(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Config["cache.ratio"].Type != TypeFloat || s.Config["http.timeout"].Type != TypeDuration {
+		t.Errorf("types = %+v", s.Config)
+	}
+	comp, ok := s.Invariants[0].Expr.(invariant.Comparison)
+	if !ok || comp.Type != invariant.ValueInt {
+		t.Errorf("pool-bounds Expr = %+v, want an int comparison", s.Invariants[0].Expr)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+		want    codes.Code
+	}{
+		{
+			name:    "int default",
+			content: "config:\n  a:\n    type: int\n    default: \"2.5\"\n",
+			wantErr: "default '2.5' for config 'a' is not an integer",
+			want:    codes.SchemaInvalidKey,
+		},
+		{
+			name:    "duration default",
+			content: "config:\n  a:\n    type: duration\n    default: \"30\"\n",
+			wantErr: "default '30' for config 'a' is not a duration",
+			want:    codes.SchemaInvalidKey,
+		},
+		{
+			name:    "enum compared with number",
+			content: content + "  - name: bad\n    rule: payments.mode == 1\n",
+			wantErr: "invariant 'bad': invalid rule syntax: cannot compare enum payments.mode with int 1",
+			want:    codes.SchemaInvalidRule,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSchema([]byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+			if code := ErrorCode(err); code != tt.want {
+				t.Errorf("ErrorCode() = %q, want %q", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSchema_InvariantFunctions(t *testing.T) {
+	base := "config:\n  api.token:\n    type: string\n  tls.key:\n    type: string\ninvariants:\n  - name: check\n    rule: "
+
+	s, err := ParseSchema([]byte(base + "len(api.token) >= 32 || exists(tls.key)\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	or, ok := s.Invariants[0].Expr.(invariant.Or)
+	if !ok {
+		t.Fatalf("Expr = %+v, want an Or", s.Invariants[0].Expr)
+	}
+	if comp, ok := or.Left.(invariant.Comparison); !ok || comp.Type != invariant.ValueInt {
+		t.Errorf("left = %+v, want an int comparison of len()", or.Left)
+	}
+
+	// Unknown functions and wrong argument counts are schema errors
+	for rule, wantErr := range map[string]string{
+		"size(api.token) > 1":        "invariant 'check': invalid rule syntax: unknown function 'size'",
+		"exists(api.token, tls.key)": "invariant 'check': invalid rule syntax: exists() takes 1 argument(s), got 2",
+	} {
+		_, err := ParseSchema([]byte(base + rule + "\n"))
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("rule %q: error = %v, want containing %q", rule, err, wantErr)
+		}
+		if code := ErrorCode(err); code != codes.SchemaInvalidRule {
+			t.Errorf("rule %q: ErrorCode() = %q, want %q", rule, code, codes.SchemaInvalidRule)
+		}
+	}
+}
+
+func TestSchema_WarningsAsErrors(t *testing.T) {
+	s := Schema{
+		Config: map[string]ConfigKey{
+			"a": {Path: "a", Type: TypeString, Severity: severity.Warn},
+			"b": {Path: "b", Type: TypeString},
+		},
+		Invariants: []invariant.Invariant{{Name: "r", Rule: `a == "x"`, Severity: severity.Warn}},
+		Environments: map[string]contract.Contract{
+			"prod": {
+				Name:  "prod",
+				Allow: map[string]contract.Rule{"a": {Values: []string{"x"}, Severity: severity.Warn}},
+				Deny:  map[string]contract.Rule{"b": {Values: []string{"y"}}},
+			},
+		},
+	}
+
+	escalated := s.WarningsAsErrors()
+
+	if escalated.Config["a"].Severity != severity.Error || escalated.Config["b"].Severity != "" {
+		t.Errorf("config severities = %q, %q", escalated.Config["a"].Severity, escalated.Config["b"].Severity)
+	}
+	if escalated.Invariants[0].Severity != severity.Error {
+		t.Errorf("invariant severity = %q, want error", escalated.Invariants[0].Severity)
+	}
+	if escalated.Environments["prod"].Allow["a"].Severity != severity.Error {
+		t.Errorf("allow rule severity = %q, want error", escalated.Environments["prod"].Allow["a"].Severity)
+	}
+
+	// The original schema is not modified
+	if s.Config["a"].Severity != severity.Warn || s.Invariants[0].Severity != severity.Warn || s.Environments["prod"].Allow["a"].Severity != severity.Warn {
+		t.Error("WarningsAsErrors() modified the original schema")
+	}
+}
+
+func TestSchema_AnalysisDomain(t *testing.T) {
+	mode := "sandbox"
+	s := Schema{
+		Config: map[string]ConfigKey{
+			"db.env":        {Path: "db.env", Type: TypeEnum, Values: []string{"dev", "staging", "prod"}},
+			"payments.mode": {Path: "payments.mode", Type: TypeEnum, Values: []string{"sandbox", "live"}, Default: &mode},
+			"region":        {Path: "region", Type: TypeEnum, Values: []string{"us", "eu"}, Required: true},
+			"db.url":        {Path: "db.url", Type: TypeString},
+		},
+		Environments: map[string]contract.Contract{
+			"prod": {
+				Name:  "prod",
+				Allow: map[string]contract.Rule{"db.env": {Values: []string{"staging", "prod"}}},
+				Deny:  map[string]contract.Rule{"db.env": {Values: []string{"stag*"}, IsGlob: true}},
+			},
+			"dev": {
+				Name:  "dev",
+				Allow: map[string]contract.Rule{"region": {Values: []string{"eu"}, Severity: severity.Warn}},
+			},
+		},
+	}
+
+	domain := s.AnalysisDomain()
+
+	if !reflect.DeepEqual(domain.Envs, []string{"dev", "prod"}) {
+		t.Errorf("Envs = %v, want [dev prod]", domain.Envs)
+	}
+	if _, ok := domain.Values["db.url"]; ok || len(domain.Values) != 3 {
+		t.Errorf("Values = %v, want the three enum keys", domain.Values)
+	}
+	if !domain.Nullable["db.env"] || domain.Nullable["payments.mode"] || domain.Nullable["region"] {
+		t.Errorf("Nullable = %v, want only db.env", domain.Nullable)
+	}
+	// Deny takes precedence over allow, and warn rules do not narrow
+	if !reflect.DeepEqual(domain.Allowed, map[string]map[string][]string{"prod": {"db.env": {"prod"}}}) {
+		t.Errorf("Allowed = %v, want db.env narrowed to prod in prod", domain.Allowed)
+	}
+}
@@ -1,24 +1,111 @@
 package schema
 
 import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
 	"admit/internal/contract"
 	"admit/internal/invariant"
+	"admit/internal/severity"
 )
 
 // ConfigType represents the type of a config value
 type ConfigType string
 
 const (
-	TypeString ConfigType = "string"
-	TypeEnum   ConfigType = "enum"
+	TypeString   ConfigType = "string"
+	TypeEnum     ConfigType = "enum"
+	TypeInt      ConfigType = "int"      // v9: a base-10 integer (e.g., "10", "-3")
+	TypeFloat    ConfigType = "float"    // v9: a decimal number (e.g., "0.75", "1e3")
+	TypeDuration ConfigType = "duration" // v9: a Go duration (e.g., "500ms", "1h30m")
 )
 
+// CheckValue reports whether value has the type's format.
+// Enum membership is checked by the validator, not here.
+func (t ConfigType) CheckValue(value string) error {
+	switch t {
+	case TypeInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return errors.New("not an integer")
+		}
+	case TypeFloat:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return errors.New("not a number")
+		}
+	case TypeDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return errors.New("not a duration (e.g., 30s, 500ms, 1h30m)")
+		}
+	}
+	return nil
+}
+
 // ConfigKey represents a single configuration requirement
 type ConfigKey struct {
 	Path     string     // e.g., "db.url"
-	Type     ConfigType // string or enum
+	Type     ConfigType // string, enum, int, float or duration
 	Required bool
 	Values   []string // For enum type only
+	Default  *string  // Value used when no source sets the key (nil if none)
+	Aliases  []string // Alternative env var names, consulted in order
+
+	NoInterpolate bool  // Take the value literally even when interpolation is enabled
+	Sensitive     *bool // Redact the value in output (nil uses the source's default)
+
+	Severity severity.Level // Whether validation failures block (zero value) or only warn
+
+	Vault *VaultRef // Where the value lives in Vault (nil if not in Vault)
+
+	ValidateWith *ValidatorSpec // External validator for the value (nil if none)
+	Probe        *ProbeSpec     // Reachability check of the value before exec (nil if none)
+}
+
+// Probe types
+const (
+	ProbeTCP  = "tcp"  // Dial the host:port of the value
+	ProbeUnix = "unix" // Connect to the socket at the value's path
+	ProbeHTTP = "http" // GET the value's URL (or a health path on its host)
+)
+
+// ProbeSpec configures a reachability check of a config value
+type ProbeSpec struct {
+	Type    string        // ProbeTCP, ProbeUnix or ProbeHTTP
+	Path    string        // Health check path for http probes (empty uses the URL's own path)
+	Timeout time.Duration // Time limit for one attempt (0 uses the default)
+	Retries int           // Retries after a failed attempt
+}
+
+// ValidatorSpec configures an external validator: an executable that is given
+// {"key", "value", "env"} as JSON on stdin and prints {"ok", "message"}
+type ValidatorSpec struct {
+	Command string        // Executable path, relative to the schema file (or name looked up in PATH)
+	Args    []string      // Arguments passed to the executable
+	Timeout time.Duration // Time limit for one run (0 uses the default)
+}
+
+// VaultRef locates a config value in a Vault KV v2 secret
+type VaultRef struct {
+	Mount string // KV v2 mount (empty uses "secret")
+	Path  string // Secret path under the mount (e.g., "myapp/db")
+	Field string // Field of the secret holding the value
+}
+
+// SourceSpec configures one entry of the resolver's source chain
+type SourceSpec struct {
+	Type string // Source type (e.g., "env", "config-file")
+	Path string // File, directory or executable for the source, relative to the schema file
+
+	// Helper settings
+	Args    []string      // Arguments passed to the helper executable
+	Timeout time.Duration // Time limit for the helper or each Vault request (0 uses the default)
+	OnError string        // Non-zero exit policy: "fail" or "ignore"
+
+	// Vault settings
+	Address   string // Vault server URL
+	TokenFile string // Token file used when VAULT_TOKEN is unset, relative to the schema file
+	Retries   *int   // Retries after a failed request (nil uses the default)
 }
 
 // Schema represents the full configuration schema
@@ -26,4 +113,89 @@ type Schema struct {
 	Config       map[string]ConfigKey
 	Invariants   []invariant.Invariant
 	Environments map[string]contract.Contract // Environment contracts
+	Sources      []SourceSpec                 // Source chain in precedence order (empty uses the default)
+}
+
+// WarningsAsErrors returns a copy of the schema in which every config key,
+// invariant and contract rule with severity "warn" blocks like an error
+func (s Schema) WarningsAsErrors() Schema {
+	escalate := func(level severity.Level) severity.Level {
+		if level.IsWarning() {
+			return severity.Error
+		}
+		return level
+	}
+
+	config := make(map[string]ConfigKey, len(s.Config))
+	for path, key := range s.Config {
+		key.Severity = escalate(key.Severity)
+		config[path] = key
+	}
+	s.Config = config
+
+	invariants := make([]invariant.Invariant, len(s.Invariants))
+	for i, inv := range s.Invariants {
+		inv.Severity = escalate(inv.Severity)
+		invariants[i] = inv
+	}
+	s.Invariants = invariants
+
+	environments := make(map[string]contract.Contract, len(s.Environments))
+	for name, c := range s.Environments {
+		escalated := contract.Contract{
+			Name:  c.Name,
+			Allow: make(map[string]contract.Rule, len(c.Allow)),
+			Deny:  make(map[string]contract.Rule, len(c.Deny)),
+		}
+		for key, rule := range c.Allow {
+			rule.Severity = escalate(rule.Severity)
+			escalated.Allow[key] = rule
+		}
+		for key, rule := range c.Deny {
+			rule.Severity = escalate(rule.Severity)
+			escalated.Deny[key] = rule
+		}
+		environments[name] = escalated
+	}
+	s.Environments = environments
+
+	return s
+}
+
+// AnalysisDomain returns the values admit invariants analyze enumerates: the
+// declared environments and each enum key's values, narrowed per environment
+// by the values its contract admits
+func (s Schema) AnalysisDomain() invariant.AnalysisDomain {
+	domain := invariant.AnalysisDomain{
+		Values:   make(map[string][]string),
+		Allowed:  make(map[string]map[string][]string),
+		Nullable: make(map[string]bool),
+	}
+	for path, key := range s.Config {
+		if key.Type != TypeEnum {
+			continue
+		}
+		domain.Values[path] = key.Values
+		domain.Nullable[path] = !key.Required && key.Default == nil
+	}
+
+	for name, c := range s.Environments {
+		domain.Envs = append(domain.Envs, name)
+		for path, values := range domain.Values {
+			var allowed []string
+			for _, value := range values {
+				if contract.Evaluate(c, map[string]string{path: value}).Passed {
+					allowed = append(allowed, value)
+				}
+			}
+			if len(allowed) < len(values) {
+				if domain.Allowed[name] == nil {
+					domain.Allowed[name] = make(map[string][]string)
+				}
+				domain.Allowed[name][path] = allowed
+			}
+		}
+	}
+	sort.Strings(domain.Envs)
+	return domain
 }
@@ -0,0 +1,37 @@
+// Package severity provides v9 severity levels for validation rules,
+// invariants and contract rules.
+package severity
+
+import "fmt"
+
+// Level is how a failed check is treated.
+// The zero Level is Error, so checks without a "severity" setting block.
+type Level string
+
+const (
+	Error Level = "error" // A failure blocks execution
+	Warn  Level = "warn"  // A failure is reported but does not block
+)
+
+// Parse validates a "severity" setting from the schema. An empty setting is
+// kept as the zero Level so schemas round-trip unchanged.
+func Parse(s string) (Level, error) {
+	switch Level(s) {
+	case "", Error, Warn:
+		return Level(s), nil
+	}
+	return "", fmt.Errorf("invalid severity '%s' (must be '%s' or '%s')", s, Error, Warn)
+}
+
+// IsWarning reports whether a failure at this level is non-blocking
+func (l Level) IsWarning() bool {
+	return l == Warn
+}
+
+// String returns the level name, "error" for the zero Level
+func (l Level) String() string {
+	if l == "" {
+		return string(Error)
+	}
+	return string(l)
+}
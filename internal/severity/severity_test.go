@@ -0,0 +1,33 @@
+package severity
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Level
+		wantErr bool
+	}{
+		{input: "", want: ""},
+		{input: "error", want: Error},
+		{input: "warn", want: Warn},
+		{input: "warning", wantErr: true},
+		{input: "ERROR", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Parse(%q) = %q, %v; want %q (error %v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	if Level("").IsWarning() || Error.IsWarning() || !Warn.IsWarning() {
+		t.Error("only Warn should be non-blocking")
+	}
+	if Level("").String() != "error" || Warn.String() != "warn" {
+		t.Errorf("String() = %q, %q", Level("").String(), Warn.String())
+	}
+}
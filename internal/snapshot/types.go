@@ -2,7 +2,11 @@
 // It stores complete execution contexts and enables replaying them later.
 package snapshot
 
-import "time"
+import (
+	"time"
+
+	"admit/internal/resolver"
+)
 
 // ExecutionSnapshot represents a complete execution context for replay.
 // It captures everything needed to reproduce an exact execution.
@@ -14,6 +18,16 @@ type ExecutionSnapshot struct {
 	Environment   map[string]string `json:"environment"`   // Schema-referenced env vars
 	SchemaPath    string            `json:"schemaPath"`    // Path to schema used
 	Timestamp     time.Time         `json:"timestamp"`     // When snapshot was created
+
+	// Provenance records where each config value came from (v8, optional)
+	Provenance map[string]resolver.Provenance `json:"provenance,omitempty"`
+
+	// Sources identifies executables that supplied values (v8, part of the execution ID)
+	Sources []string `json:"sources,omitempty"`
+
+	// Redacted lists env vars with sensitive values that were not stored (v8).
+	// Replay takes them from the current environment.
+	Redacted []string `json:"redacted,omitempty"`
 }
 
 // SnapshotSummary is a lightweight view for listing snapshots.
@@ -26,12 +26,13 @@ func Verify(snap ExecutionSnapshot, schemaKeys []string) VerifyResult {
 	}
 
 	// Recompute execution ID from snapshot contents
-	computed := execid.ComputeExecutionID(
+	computed := execid.ComputeExecutionIDWithSources(
 		snap.ConfigVersion,
 		snap.Command,
 		snap.Args,
 		environ,
 		schemaKeys,
+		snap.Sources,
 	)
 
 	// Check if execution ID matches
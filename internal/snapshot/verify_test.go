@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"admit/internal/execid"
+
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
 	"github.com/leanovate/gopter/prop"
@@ -69,6 +71,30 @@ func TestVerify_ValidSnapshot(t *testing.T) {
 	_ = result
 }
 
+func TestVerify_Sources(t *testing.T) {
+	environ := []string{"DB_URL=postgres://localhost"}
+	sources := []string{"helper:/usr/local/bin/fetch-secrets"}
+	id := execid.ComputeExecutionIDWithSources("sha256:config123", "node", []string{"server.js"}, environ, []string{"db.url"}, sources)
+
+	snap := ExecutionSnapshot{
+		ExecutionID:   id.ExecutionID,
+		ConfigVersion: "sha256:config123",
+		Command:       "node",
+		Args:          []string{"server.js"},
+		Environment:   map[string]string{"DB_URL": "postgres://localhost"},
+		Sources:       sources,
+	}
+
+	if result := Verify(snap, []string{"db.url"}); result.IDMismatch {
+		t.Error("Verify() reported an ID mismatch for a snapshot with sources")
+	}
+
+	snap.Sources = nil
+	if result := Verify(snap, []string{"db.url"}); !result.IDMismatch {
+		t.Error("Verify() did not detect a dropped source")
+	}
+}
+
 func TestVerify_SchemaNotFound(t *testing.T) {
 	snap := ExecutionSnapshot{
 		ExecutionID:   "sha256:abc123",
@@ -3,28 +3,51 @@ package validator
 import (
 	"fmt"
 	"strings"
+
+	"admit/internal/codes"
 )
 
 // FormatError formats a ValidationError into a human-readable error message.
 // Requirements: 6.1, 6.2
 func FormatError(err ValidationError) string {
-	// Check if this is a missing required error (no value, no allowed list)
-	if err.Value == "" && len(err.Allowed) == 0 {
+	switch err.Code {
+	case codes.RequiredMissing:
 		// Requirement 6.1: Missing required error format
 		// Format: "{key}: required but {ENV_VAR} is not set"
 		return fmt.Sprintf("%s: required but %s is not set", err.Key, err.EnvVar)
-	}
 
-	// Check if this is an invalid enum error (has value and allowed list)
-	if len(err.Allowed) > 0 {
+	case codes.InvalidEnum:
 		// Requirement 6.2: Invalid enum error format
 		// Format: "{key}: '{value}' is not valid, must be one of: {allowed}"
-		return fmt.Sprintf("%s: '%s' is not valid, must be one of: %s",
-			err.Key, err.Value, strings.Join(err.Allowed, ", "))
+		return fmt.Sprintf("%s: '%s' is not valid, must be one of: %s%s",
+			err.Key, err.Value, strings.Join(err.Allowed, ", "), formatLocation(err))
+
+	case codes.InvalidType:
+		// Format: "{key}: '{value}' is not an integer"
+		return fmt.Sprintf("%s: '%s' is %s%s", err.Key, err.Value, err.Message, formatLocation(err))
+
+	case codes.PluginRejected:
+		// Format: "{key}: rejected by {validator}: {message}"
+		if err.Message == "" {
+			return fmt.Sprintf("%s: rejected by %s%s", err.Key, err.Validator, formatLocation(err))
+		}
+		return fmt.Sprintf("%s: rejected by %s: %s%s", err.Key, err.Validator, err.Message, formatLocation(err))
+
+	case codes.PluginFailed:
+		// Format: "{key}: validator {validator} failed: {reason}"
+		return fmt.Sprintf("%s: validator %s failed: %s", err.Key, err.Validator, err.Message)
 	}
 
 	// Fallback to generic message
-	return fmt.Sprintf("%s: %s", err.Key, err.Message)
+	return fmt.Sprintf("%s: %s%s", err.Key, err.Message, formatLocation(err))
+}
+
+// formatLocation formats where an invalid value was read from, e.g. " (at config.yaml:3)"
+func formatLocation(err ValidationError) string {
+	if err.File == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (at %s:%d)", err.File, err.Line)
 }
 
 // FormatErrors formats all validation errors into a slice of human-readable messages.
@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 
+	"admit/internal/codes"
+
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
 	"github.com/leanovate/gopter/prop"
@@ -27,6 +29,7 @@ func TestFormatError_MissingRequired_Property(t *testing.T) {
 			}
 
 			err := ValidationError{
+				Code:    codes.RequiredMissing,
 				Key:     key,
 				EnvVar:  envVar,
 				Message: "required but not set",
@@ -55,6 +58,7 @@ func TestFormatError_MissingRequired_Property(t *testing.T) {
 			}
 
 			err := ValidationError{
+				Code:    codes.RequiredMissing,
 				Key:     key,
 				EnvVar:  envVar,
 				Message: "required but not set",
@@ -93,6 +97,7 @@ func TestFormatError_InvalidEnum_Property(t *testing.T) {
 			}
 
 			err := ValidationError{
+				Code:    codes.InvalidEnum,
 				Key:     key,
 				EnvVar:  envVar,
 				Message: "invalid enum value",
@@ -138,6 +143,7 @@ func TestFormatError_InvalidEnum_Property(t *testing.T) {
 			}
 
 			err := ValidationError{
+				Code:    codes.InvalidEnum,
 				Key:     key,
 				EnvVar:  "TEST_ENV",
 				Message: "invalid enum value",
@@ -165,3 +171,26 @@ func TestFormatError_InvalidEnum_Property(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+// TestFormatError_Location tests that errors for file-sourced values name the file and line
+func TestFormatError_Location(t *testing.T) {
+	err := ValidationError{
+		Code:    codes.InvalidEnum,
+		Key:     "payments.mode",
+		EnvVar:  "PAYMENTS_MODE",
+		Message: "invalid enum value",
+		Value:   "prod",
+		Allowed: []string{"test", "live"},
+		File:    "config.yaml",
+		Line:    7,
+	}
+	want := "payments.mode: 'prod' is not valid, must be one of: test, live (at config.yaml:7)"
+	if got := FormatError(err); got != want {
+		t.Errorf("FormatError() = %q, want %q", got, want)
+	}
+
+	err.File = ""
+	if got := FormatError(err); strings.Contains(got, "(at ") {
+		t.Errorf("FormatError() = %q, want no location without a file", got)
+	}
+}
@@ -0,0 +1,151 @@
+package validator
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"admit/internal/codes"
+	"admit/internal/execjson"
+	"admit/internal/resolver"
+	"admit/internal/schema"
+)
+
+// DefaultPluginTimeout is how long an external validator may run when its
+// validate_with has no timeout
+const DefaultPluginTimeout = 5 * time.Second
+
+// maxParallelPlugins bounds how many external validators run at once
+const maxParallelPlugins = 8
+
+// PluginOptions configures how external validators are run
+type PluginOptions struct {
+	Dir     string   // Directory relative validator paths are resolved against (the schema file's)
+	Env     string   // Execution environment sent to validators as "env" (e.g., "prod")
+	Environ []string // Environment of the validator processes
+}
+
+// pluginRequest is written to a validator's stdin
+type pluginRequest struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Env   string `json:"env"`
+}
+
+// pluginResponse is read from a validator's stdout
+type pluginResponse struct {
+	OK      *bool  `json:"ok"`
+	Message string `json:"message"`
+}
+
+// ValidateWithPlugins validates like Validate, then runs the validate_with
+// executables of the keys that are present and passed the built-in checks.
+// Validators run in parallel; their failures are merged into the result, sorted
+// by key, as errors or, for keys with severity "warn", warnings.
+func ValidateWithPlugins(s schema.Schema, resolved []resolver.ResolvedValue, opts PluginOptions) ValidationResult {
+	result := Validate(s, resolved)
+
+	failed := make(map[string]bool)
+	for _, err := range append(append([]ValidationError(nil), result.Errors...), result.Warnings...) {
+		failed[err.Key] = true
+	}
+
+	var checked []resolver.ResolvedValue
+	for _, rv := range resolved {
+		if configKey, ok := s.Config[rv.Key]; ok && configKey.ValidateWith != nil && rv.Present && !failed[rv.Key] {
+			checked = append(checked, rv)
+		}
+	}
+	if len(checked) == 0 {
+		return result
+	}
+
+	outcomes := make([]*ValidationError, len(checked))
+	slots := make(chan struct{}, maxParallelPlugins)
+	var wg sync.WaitGroup
+	for i, rv := range checked {
+		wg.Add(1)
+		go func(i int, rv resolver.ResolvedValue) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			outcomes[i] = runPlugin(s.Config[rv.Key], rv, opts)
+		}(i, rv)
+	}
+	wg.Wait()
+
+	for _, err := range outcomes {
+		if err == nil {
+			continue
+		}
+		if err.Severity.IsWarning() {
+			result.Warnings = append(result.Warnings, *err)
+		} else {
+			result.Errors = append(result.Errors, *err)
+		}
+	}
+	sortByKey(result.Errors)
+	sortByKey(result.Warnings)
+	result.Valid = len(result.Errors) == 0
+	return result
+}
+
+// runPlugin runs a key's validator on its value. It returns nil when the
+// validator accepts the value.
+func runPlugin(configKey schema.ConfigKey, rv resolver.ResolvedValue, opts PluginOptions) *ValidationError {
+	spec := configKey.ValidateWith
+	verr := &ValidationError{
+		Code:      codes.PluginRejected,
+		Key:       rv.Key,
+		EnvVar:    rv.EnvVar,
+		Value:     rv.DisplayValue(),
+		File:      sourceFile(rv),
+		Line:      rv.Source.Line,
+		Validator: spec.Command,
+		Severity:  configKey.Severity,
+	}
+
+	response, err := execPlugin(*spec, pluginRequest{Key: rv.Key, Value: rv.Value, Env: opts.Env}, opts)
+	if err != nil {
+		verr.Code = codes.PluginFailed
+		verr.Message = err.Error()
+		return verr
+	}
+	if *response.OK {
+		return nil
+	}
+	verr.Message = response.Message
+	return verr
+}
+
+// execPlugin executes a validator with the request on stdin and decodes its answer.
+// Timeouts, start failures, non-zero exits and malformed output are errors;
+// error messages include the validator's stderr.
+func execPlugin(spec schema.ValidatorSpec, request pluginRequest, opts PluginOptions) (pluginResponse, error) {
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPluginTimeout
+	}
+
+	var response pluginResponse
+	err := execjson.Run(execjson.Command{Path: pluginPath(spec.Command, opts.Dir), Args: spec.Args, Env: opts.Environ, Timeout: timeout}, request, &response)
+	var outputErr *execjson.OutputError
+	if errors.As(err, &outputErr) || (err == nil && response.OK == nil) {
+		return pluginResponse{}, errors.New(`invalid output: expected a JSON object with a boolean "ok"`)
+	}
+	if err != nil {
+		return pluginResponse{}, err
+	}
+	return response, nil
+}
+
+// pluginPath resolves a validator path relative to dir.
+// Bare names (no path separator) are left for PATH lookup.
+func pluginPath(path, dir string) string {
+	if dir == "" || filepath.IsAbs(path) || !strings.ContainsRune(path, filepath.Separator) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,165 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"admit/internal/codes"
+	"admit/internal/resolver"
+	"admit/internal/schema"
+	"admit/internal/severity"
+)
+
+// writePlugin writes an executable shell script validator into dir and returns its name
+func writePlugin(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("failed to write validator: %v", err)
+	}
+	return "./" + name
+}
+
+func TestValidateWithPlugins(t *testing.T) {
+	dir := t.TempDir()
+	// Accepts account IDs starting with 1 and echoes the request in its message otherwise
+	account := writePlugin(t, dir, "account.sh", `input=$(cat)
+case "$input" in
+  *'"value":"1'*) echo '{"ok": true}' ;;
+  *) printf '{"ok": false, "message": "not in org: %s"}' "$(echo "$input" | sed 's/"/\\"/g')" ;;
+esac
+`)
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"aws.account":  {Path: "aws.account", Type: schema.TypeString, ValidateWith: &schema.ValidatorSpec{Command: account}},
+			"aws.backup":   {Path: "aws.backup", Type: schema.TypeString, ValidateWith: &schema.ValidatorSpec{Command: account}, Severity: severity.Warn},
+			"aws.audit":    {Path: "aws.audit", Type: schema.TypeString, ValidateWith: &schema.ValidatorSpec{Command: account}},
+			"aws.optional": {Path: "aws.optional", Type: schema.TypeString, ValidateWith: &schema.ValidatorSpec{Command: "./missing.sh"}},
+		},
+	}
+	resolved := []resolver.ResolvedValue{
+		{Key: "aws.account", EnvVar: "AWS_ACCOUNT", Value: "999", Present: true},
+		{Key: "aws.backup", EnvVar: "AWS_BACKUP", Value: "998", Present: true},
+		{Key: "aws.audit", EnvVar: "AWS_AUDIT", Value: "123", Present: true},
+		{Key: "aws.optional", EnvVar: "AWS_OPTIONAL"},
+	}
+
+	result := ValidateWithPlugins(s, resolved, PluginOptions{Dir: dir, Env: "prod"})
+	if result.Valid || len(result.Errors) != 1 || len(result.Warnings) != 1 {
+		t.Fatalf("result = %+v, want one error and one warning", result)
+	}
+	err := result.Errors[0]
+	if err.Key != "aws.account" || err.Code != codes.PluginRejected || err.Validator != account {
+		t.Errorf("error = %+v, want aws.account rejected", err)
+	}
+	want := `aws.account: rejected by ./account.sh: not in org: {"key":"aws.account","value":"999","env":"prod"}`
+	if got := FormatError(err); got != want {
+		t.Errorf("FormatError() = %q, want %q", got, want)
+	}
+	if w := result.Warnings[0]; w.Key != "aws.backup" || w.Code != codes.PluginRejected {
+		t.Errorf("warning = %+v, want aws.backup rejected", w)
+	}
+}
+
+func TestValidateWithPlugins_SortedByKey(t *testing.T) {
+	dir := t.TempDir()
+	reject := writePlugin(t, dir, "reject.sh", `echo '{"ok": false, "message": "no"}'`+"\n")
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"a.key": {Path: "a.key", Type: schema.TypeString, ValidateWith: &schema.ValidatorSpec{Command: reject}},
+			"m.key": {Path: "m.key", Type: schema.TypeString, Required: true},
+			"z.key": {Path: "z.key", Type: schema.TypeString, ValidateWith: &schema.ValidatorSpec{Command: reject}},
+		},
+	}
+	resolved := []resolver.ResolvedValue{
+		{Key: "z.key", Value: "x", Present: true},
+		{Key: "m.key"},
+		{Key: "a.key", Value: "x", Present: true},
+	}
+
+	// Validator failures are merged with the built-in errors in key order
+	result := ValidateWithPlugins(s, resolved, PluginOptions{Dir: dir})
+	var keys []string
+	for _, err := range result.Errors {
+		keys = append(keys, err.Key)
+	}
+	if strings.Join(keys, ",") != "a.key,m.key,z.key" {
+		t.Errorf("error keys = %v, want sorted", keys)
+	}
+}
+
+func TestValidateWithPlugins_SkipsFailedKeys(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	plugin := writePlugin(t, dir, "check.sh", "touch "+marker+"\necho '{\"ok\": true}'\n")
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"mode": {Path: "mode", Type: schema.TypeEnum, Values: []string{"a"}, ValidateWith: &schema.ValidatorSpec{Command: plugin}},
+		},
+	}
+
+	result := ValidateWithPlugins(s, []resolver.ResolvedValue{{Key: "mode", Value: "b", Present: true}}, PluginOptions{Dir: dir})
+	if len(result.Errors) != 1 || result.Errors[0].Code != codes.InvalidEnum {
+		t.Errorf("errors = %+v, want only the enum error", result.Errors)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("validator ran for a value that failed the built-in checks")
+	}
+}
+
+func TestValidateWithPlugins_Failures(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		script  string
+		spec    schema.ValidatorSpec
+		wantErr string
+	}{
+		{name: "non-zero exit", script: "echo 'db unreachable' >&2\nexit 3\n", wantErr: "exited with status 3: db unreachable"},
+		{name: "invalid output", script: "echo 'yes'\n", wantErr: `invalid output: expected a JSON object with a boolean "ok"`},
+		{name: "missing ok", script: `echo '{"message": "?"}'` + "\n", wantErr: "invalid output"},
+		{name: "output too large", script: "head -c 2000000 /dev/zero | tr '\\0' ' '\n", wantErr: "invalid output"},
+		{name: "timeout", script: "sleep 5\n", spec: schema.ValidatorSpec{Timeout: 100 * time.Millisecond}, wantErr: "timed out after 100ms"},
+		{name: "not found", spec: schema.ValidatorSpec{Command: "./does-not-exist.sh"}, wantErr: "no such file"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := tt.spec
+			if spec.Command == "" {
+				spec.Command = writePlugin(t, dir, strings.Repeat("p", i+1)+".sh", tt.script)
+			}
+			s := schema.Schema{Config: map[string]schema.ConfigKey{"a": {Path: "a", Type: schema.TypeString, ValidateWith: &spec}}}
+
+			result := ValidateWithPlugins(s, []resolver.ResolvedValue{{Key: "a", Value: "x", Present: true}}, PluginOptions{Dir: dir})
+			if len(result.Errors) != 1 || result.Errors[0].Code != codes.PluginFailed {
+				t.Fatalf("errors = %+v, want one validator failure", result.Errors)
+			}
+			if got := FormatError(result.Errors[0]); !strings.HasPrefix(got, "a: validator "+spec.Command+" failed: ") || !strings.Contains(got, tt.wantErr) {
+				t.Errorf("FormatError() = %q, want containing %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateWithPlugins_Parallel(t *testing.T) {
+	dir := t.TempDir()
+	plugin := writePlugin(t, dir, "slow.sh", "sleep 0.3\necho '{\"ok\": true}'\n")
+	s := schema.Schema{Config: map[string]schema.ConfigKey{}}
+	var resolved []resolver.ResolvedValue
+	for _, key := range []string{"a", "b", "c", "d"} {
+		s.Config[key] = schema.ConfigKey{Path: key, Type: schema.TypeString, ValidateWith: &schema.ValidatorSpec{Command: plugin}}
+		resolved = append(resolved, resolver.ResolvedValue{Key: key, Value: "x", Present: true})
+	}
+
+	start := time.Now()
+	result := ValidateWithPlugins(s, resolved, PluginOptions{Dir: dir})
+	if !result.Valid {
+		t.Fatalf("errors = %+v, want all accepted", result.Errors)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("4 validators sleeping 0.3s took %s, want them run in parallel", elapsed)
+	}
+}
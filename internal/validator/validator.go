@@ -1,30 +1,50 @@
 package validator
 
 import (
+	"sort"
+
+	"admit/internal/codes"
 	"admit/internal/resolver"
 	"admit/internal/schema"
+	"admit/internal/severity"
 )
 
 // ValidationError represents a single validation failure
 type ValidationError struct {
-	Key     string   // The config key path (e.g., "db.url")
-	EnvVar  string   // The environment variable name (e.g., "DB_URL")
-	Message string   // Human-readable error message
-	Value   string   // The invalid value (if present)
-	Allowed []string // For enum errors, the allowed values
+	Code    codes.Code // The kind of failure (e.g., codes.RequiredMissing)
+	Key     string     // The config key path (e.g., "db.url")
+	EnvVar  string     // The environment variable name (e.g., "DB_URL")
+	Message string     // Human-readable error message
+	Value   string     // The invalid value (if present)
+	Allowed []string   // For enum errors, the allowed values
+	File    string     // File the value was read from (env file or config file), if any
+	Line    int        // Line of the value in File
+
+	Validator string // The validate_with executable, for codes.PluginRejected and codes.PluginFailed
+
+	Severity severity.Level // The config key's severity
 }
 
 // ValidationResult contains all validation outcomes
 type ValidationResult struct {
-	Valid  bool
-	Errors []ValidationError
+	Valid    bool              // Whether there are no blocking errors
+	Errors   []ValidationError // Failures that block execution
+	Warnings []ValidationError // Failures of keys with severity "warn"
 }
 
 // Validate checks all resolved values against schema constraints.
-// It collects all errors rather than stopping at the first one.
+// It collects all errors rather than stopping at the first one, sorted by key.
+// Failures of keys with severity "warn" are collected as warnings.
 // Requirements: 4.1, 4.2, 4.3, 4.4, 4.5
 func Validate(s schema.Schema, resolved []resolver.ResolvedValue) ValidationResult {
-	var errors []ValidationError
+	var errors, warnings []ValidationError
+	report := func(err ValidationError) {
+		if err.Severity.IsWarning() {
+			warnings = append(warnings, err)
+		} else {
+			errors = append(errors, err)
+		}
+	}
 
 	for _, rv := range resolved {
 		configKey, exists := s.Config[rv.Key]
@@ -35,10 +55,12 @@ func Validate(s schema.Schema, resolved []resolver.ResolvedValue) ValidationResu
 
 		// Check required fields (Requirement 4.1)
 		if configKey.Required && !rv.Present {
-			errors = append(errors, ValidationError{
-				Key:     rv.Key,
-				EnvVar:  rv.EnvVar,
-				Message: "required but not set",
+			report(ValidationError{
+				Code:     codes.RequiredMissing,
+				Key:      rv.Key,
+				EnvVar:   rv.EnvVar,
+				Message:  "required but not set",
+				Severity: configKey.Severity,
 			})
 			continue
 		}
@@ -55,24 +77,60 @@ func Validate(s schema.Schema, resolved []resolver.ResolvedValue) ValidationResu
 			// A present value is valid for string type
 			// (empty string is technically present but empty)
 
+		case schema.TypeInt, schema.TypeFloat, schema.TypeDuration:
+			// v9: typed values must parse, so invariants can compare them
+			if err := configKey.Type.CheckValue(rv.Value); err != nil {
+				report(ValidationError{
+					Code:     codes.InvalidType,
+					Key:      rv.Key,
+					EnvVar:   rv.EnvVar,
+					Message:  err.Error(),
+					Value:    rv.DisplayValue(),
+					File:     sourceFile(rv),
+					Line:     rv.Source.Line,
+					Severity: configKey.Severity,
+				})
+			}
+
 		case schema.TypeEnum:
 			// Requirements 4.3, 4.4: Validate enum values
 			if !isValidEnumValue(rv.Value, configKey.Values) {
-				errors = append(errors, ValidationError{
-					Key:     rv.Key,
-					EnvVar:  rv.EnvVar,
-					Message: "invalid enum value",
-					Value:   rv.Value,
-					Allowed: configKey.Values,
+				report(ValidationError{
+					Code:     codes.InvalidEnum,
+					Key:      rv.Key,
+					EnvVar:   rv.EnvVar,
+					Message:  "invalid enum value",
+					Value:    rv.DisplayValue(),
+					Allowed:  configKey.Values,
+					File:     sourceFile(rv),
+					Line:     rv.Source.Line,
+					Severity: configKey.Severity,
 				})
 			}
 		}
 	}
 
+	sortByKey(errors)
+	sortByKey(warnings)
 	return ValidationResult{
-		Valid:  len(errors) == 0,
-		Errors: errors,
+		Valid:    len(errors) == 0,
+		Errors:   errors,
+		Warnings: warnings,
+	}
+}
+
+// sortByKey orders errors by config key, so output does not depend on the
+// order values were resolved in
+func sortByKey(errs []ValidationError) {
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Key < errs[j].Key })
+}
+
+// sourceFile returns the file a value was read from, if it has a line position
+func sourceFile(rv resolver.ResolvedValue) string {
+	if rv.Source.Line == 0 {
+		return ""
 	}
+	return rv.Source.Path
 }
 
 // isValidEnumValue checks if a value is in the allowed list
@@ -1,10 +1,13 @@
 package validator
 
 import (
+	"strings"
 	"testing"
 
+	"admit/internal/codes"
 	"admit/internal/resolver"
 	"admit/internal/schema"
+	"admit/internal/severity"
 
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
@@ -460,3 +463,164 @@ func TestValidate_ErrorCollectionCompleteness_Property(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+// TestValidate_SourceLocation tests that enum errors carry the file and line of file-sourced values
+func TestValidate_SourceLocation(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"payments.mode": {Path: "payments.mode", Type: schema.TypeEnum, Values: []string{"test", "live"}},
+			"log.level":     {Path: "log.level", Type: schema.TypeEnum, Values: []string{"info"}},
+		},
+	}
+	resolved := []resolver.ResolvedValue{
+		{Key: "payments.mode", EnvVar: "PAYMENTS_MODE", Value: "prod", Present: true,
+			Source: resolver.Provenance{Kind: resolver.SourceConfigFile, Var: "payments.mode", Path: "config.toml", Line: 4}},
+		{Key: "log.level", EnvVar: "LOG_LEVEL", Value: "debug", Present: true,
+			Source: resolver.Provenance{Kind: resolver.SourceEnv, Var: "LOG_LEVEL"}},
+	}
+
+	result := Validate(s, resolved)
+	if len(result.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got %d", len(result.Errors))
+	}
+	for _, err := range result.Errors {
+		switch err.Key {
+		case "payments.mode":
+			if err.File != "config.toml" || err.Line != 4 {
+				t.Errorf("payments.mode location = %s:%d, want config.toml:4", err.File, err.Line)
+			}
+		case "log.level":
+			if err.File != "" || err.Line != 0 {
+				t.Errorf("log.level location = %s:%d, want none for env values", err.File, err.Line)
+			}
+		}
+	}
+}
+
+// TestValidate_SensitiveValue tests that enum errors do not reveal sensitive values
+func TestValidate_SensitiveValue(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"api.tier": {Path: "api.tier", Type: schema.TypeEnum, Values: []string{"free", "pro"}},
+		},
+	}
+	resolved := []resolver.ResolvedValue{
+		{Key: "api.tier", EnvVar: "API_TIER", Value: "s3cret", Present: true, Sensitive: true},
+	}
+
+	result := Validate(s, resolved)
+	if len(result.Errors) != 1 {
+		t.Fatalf("expected 1 error, got %d", len(result.Errors))
+	}
+	if msg := FormatError(result.Errors[0]); strings.Contains(msg, "s3cret") || !strings.Contains(msg, resolver.RedactedValue) {
+		t.Errorf("FormatError() = %q, want value redacted", msg)
+	}
+}
+
+func TestValidate_WarnSeverity(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"log.format": {Path: "log.format", Type: schema.TypeEnum, Values: []string{"json", "text"}, Severity: severity.Warn},
+			"cache.url":  {Path: "cache.url", Type: schema.TypeString, Required: true, Severity: severity.Warn},
+			"db.url":     {Path: "db.url", Type: schema.TypeString, Required: true},
+		},
+	}
+	resolved := []resolver.ResolvedValue{
+		{Key: "log.format", EnvVar: "LOG_FORMAT", Value: "xml", Present: true},
+		{Key: "cache.url", EnvVar: "CACHE_URL"},
+		{Key: "db.url", EnvVar: "DB_URL", Value: "postgres://db", Present: true},
+	}
+
+	result := Validate(s, resolved)
+	if !result.Valid || len(result.Errors) != 0 {
+		t.Errorf("Valid = %v, Errors = %v; want warnings not to invalidate", result.Valid, result.Errors)
+	}
+	if len(result.Warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", result.Warnings)
+	}
+	for _, w := range result.Warnings {
+		if !w.Severity.IsWarning() {
+			t.Errorf("warning %+v has severity %q", w, w.Severity)
+		}
+	}
+
+	// A blocking error alongside warnings still invalidates
+	resolved[2] = resolver.ResolvedValue{Key: "db.url", EnvVar: "DB_URL"}
+	result = Validate(s, resolved)
+	if result.Valid || len(result.Errors) != 1 || len(result.Warnings) != 2 {
+		t.Errorf("got Valid = %v, %d errors, %d warnings; want invalid with 1 error and 2 warnings", result.Valid, len(result.Errors), len(result.Warnings))
+	}
+}
+
+func TestValidate_Codes(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"db.url":        {Path: "db.url", Type: schema.TypeString, Required: true},
+			"payments.mode": {Path: "payments.mode", Type: schema.TypeEnum, Values: []string{"test", "live"}},
+		},
+	}
+	resolved := []resolver.ResolvedValue{
+		{Key: "db.url", EnvVar: "DB_URL"},
+		{Key: "payments.mode", EnvVar: "PAYMENTS_MODE", Value: "prod", Present: true},
+	}
+
+	result := Validate(s, resolved)
+	if len(result.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got %v", result.Errors)
+	}
+	if result.Errors[0].Code != codes.RequiredMissing || result.Errors[1].Code != codes.InvalidEnum {
+		t.Errorf("codes = %s, %s; want %s, %s", result.Errors[0].Code, result.Errors[1].Code, codes.RequiredMissing, codes.InvalidEnum)
+	}
+
+	// The code, not the fields that happen to be set, decides the message
+	err := ValidationError{Code: codes.RequiredMissing, Key: "db.url", EnvVar: "DB_URL", Value: "x", Allowed: []string{"a"}}
+	if got := FormatError(err); got != "db.url: required but DB_URL is not set" {
+		t.Errorf("FormatError() = %q", got)
+	}
+	err = ValidationError{Key: "db.url", Message: "custom failure"}
+	if got := FormatError(err); got != "db.url: custom failure" {
+		t.Errorf("FormatError() without a code = %q, want the message", got)
+	}
+}
+
+func TestValidate_NumericTypes(t *testing.T) {
+	s := schema.Schema{
+		Config: map[string]schema.ConfigKey{
+			"pool.max":     {Path: "pool.max", Type: schema.TypeInt},
+			"cache.ratio":  {Path: "cache.ratio", Type: schema.TypeFloat},
+			"http.timeout": {Path: "http.timeout", Type: schema.TypeDuration},
+		},
+	}
+
+	valid := []resolver.ResolvedValue{
+		{Key: "pool.max", EnvVar: "POOL_MAX", Value: "-20", Present: true},
+		{Key: "cache.ratio", EnvVar: "CACHE_RATIO", Value: "0.5", Present: true},
+		{Key: "http.timeout", EnvVar: "HTTP_TIMEOUT", Value: "1h30m", Present: true},
+	}
+	if result := Validate(s, valid); !result.Valid {
+		t.Errorf("Validate() = %+v, want valid", result.Errors)
+	}
+
+	invalid := []resolver.ResolvedValue{
+		{Key: "pool.max", EnvVar: "POOL_MAX", Value: "20.5", Present: true},
+		{Key: "cache.ratio", EnvVar: "CACHE_RATIO", Value: "half", Present: true},
+		{Key: "http.timeout", EnvVar: "HTTP_TIMEOUT", Value: "30", Present: true},
+	}
+	result := Validate(s, invalid)
+	if len(result.Errors) != 3 {
+		t.Fatalf("expected 3 errors, got %v", result.Errors)
+	}
+	want := map[string]string{
+		"pool.max":     "pool.max: '20.5' is not an integer",
+		"cache.ratio":  "cache.ratio: 'half' is not a number",
+		"http.timeout": "http.timeout: '30' is not a duration (e.g., 30s, 500ms, 1h30m)",
+	}
+	for _, err := range result.Errors {
+		if err.Code != codes.InvalidType {
+			t.Errorf("%s: code = %q, want %q", err.Key, err.Code, codes.InvalidType)
+		}
+		if got := FormatError(err); got != want[err.Key] {
+			t.Errorf("FormatError() = %q, want %q", got, want[err.Key])
+		}
+	}
+}